@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/a2y-d5l/multiproc/config"
+	"github.com/a2y-d5l/multiproc/engine"
+)
+
+// commandsFlag collects repeated -p name=command flags.
+type commandsFlag []config.Command
+
+func (f *commandsFlag) String() string {
+	parts := make([]string, len(*f))
+	for i, c := range *f {
+		parts[i] = c.Name + "=" + c.Command
+	}
+	return strings.Join(parts, " ")
+}
+
+func (f *commandsFlag) Set(s string) error {
+	c, err := config.ParseNamedCommand(s)
+	if err != nil {
+		return err
+	}
+	*f = append(*f, c)
+	return nil
+}
+
+// listFlag is a comma-separated list of values. Repeating the flag adds
+// to the list.
+type listFlag []string
+
+func (f *listFlag) String() string { return strings.Join(*f, ",") }
+
+func (f *listFlag) Set(s string) error {
+	*f = append(*f, strings.Split(s, ",")...)
+	return nil
+}
+
+// intListFlag is a comma-separated list of integers, one per process in
+// order.
+type intListFlag []int
+
+func (f *intListFlag) String() string {
+	parts := make([]string, len(*f))
+	for i, n := range *f {
+		parts[i] = strconv.Itoa(n)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (f *intListFlag) Set(s string) error {
+	var list []int
+	for _, part := range strings.Split(s, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return fmt.Errorf("invalid number %q", part)
+		}
+		list = append(list, n)
+	}
+	*f = list
+	return nil
+}
+
+// applyPerProcess applies the per-process flag lists to specs in order:
+// -max-lines with several values, -max-bytes and -colors. A list with a
+// single value applies to every process; processes past the end of a list
+// keep their settings.
+func applyPerProcess(specs []engine.ProcessSpec, maxLines, maxBytes intListFlag, colors listFlag) error {
+	lists := []struct {
+		flag string
+		n    int
+	}{{"max-lines", len(maxLines)}, {"max-bytes", len(maxBytes)}, {"colors", len(colors)}}
+	for _, l := range lists {
+		if l.n > 1 && l.n > len(specs) {
+			return fmt.Errorf("-%s has %d values for %d processes", l.flag, l.n, len(specs))
+		}
+	}
+	for i := range specs {
+		if len(maxLines) > 1 && i < len(maxLines) {
+			specs[i].MaxLines = maxLines[i]
+		}
+		if v, ok := perProcess(maxBytes, i); ok {
+			specs[i].MaxBytes = v
+		}
+		if v, ok := perProcess(colors, i); ok && v != "" {
+			specs[i].Color = strings.TrimSpace(v)
+		}
+	}
+	return nil
+}
+
+// applyWatch restarts every process without watch patterns of its own
+// when files matching the -watch globs change, ignoring the files matching
+// the -watch-ignore globs.
+func applyWatch(specs []engine.ProcessSpec, watch, ignore listFlag) {
+	if len(watch) == 0 {
+		return
+	}
+	for i := range specs {
+		if len(specs[i].Watch) == 0 {
+			specs[i].Watch, specs[i].WatchIgnore = watch, ignore
+		}
+	}
+}
+
+// perProcess returns the value of list for process i: the only value of a
+// single-value list, or the i-th value.
+func perProcess[T any](list []T, i int) (T, bool) {
+	switch {
+	case len(list) == 1:
+		return list[0], true
+	case i < len(list):
+		return list[i], true
+	default:
+		var zero T
+		return zero, false
+	}
+}
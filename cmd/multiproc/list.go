@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/a2y-d5l/multiproc/engine"
+	"github.com/a2y-d5l/multiproc/runner"
+)
+
+// listColumnGap is the space between the columns of the list subcommand.
+const listColumnGap = 2
+
+// printList writes the processes that would run as a table, for the list
+// subcommand:
+//
+//	NAME    GROUP    TAGS     COMMAND
+//	api     backend  go       go run ./cmd/api
+//	web     -        node     sh -c 'npm run dev'
+func printList(w io.Writer, specs []engine.ProcessSpec) error {
+	tw := tabwriter.NewWriter(w, 0, 0, listColumnGap, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tGROUP\tTAGS\tCOMMAND")
+	for _, spec := range specs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", spec.Name, orDash(spec.Group), orDash(strings.Join(spec.Tags, ",")), spec.CommandLine())
+	}
+	return tw.Flush()
+}
+
+// printPlan writes the plan of a run of cfg (see runner.Plan) for
+// -dry-run, as text or, if asJSON is set, as an indented JSON object.
+func printPlan(w io.Writer, cfg runner.Config, asJSON bool) error {
+	plan, err := runner.NewPlan(cfg)
+	if err != nil {
+		return err
+	}
+	if !asJSON {
+		return plan.WriteText(w)
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(plan)
+}
+
+// invalidPlan is the JSON object -dry-run -format=json prints instead of the
+// plan when the configuration is invalid.
+type invalidPlan struct {
+	// Errors lists the problems found by runner.Config.Validate.
+	Errors []string `json:"errors"`
+}
+
+// dryRunPlan prints the plan of cfg for -dry-run and returns the exit code. An
+// invalid cfg has no plan: its problems are printed instead, to errOut or,
+// if asJSON is set, to out as an invalidPlan, and dryRunPlan returns exitUsage.
+func dryRunPlan(out, errOut io.Writer, cfg runner.Config, asJSON bool) int {
+	if err := cfg.Validate(); err != nil {
+		if !asJSON {
+			printErrors(errOut, err)
+			return exitUsage
+		}
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		if err = enc.Encode(invalidPlan{Errors: strings.Split(err.Error(), "\n")}); err != nil {
+			fmt.Fprintf(errOut, "multiproc: %v\n", err)
+		}
+		return exitUsage
+	}
+	if err := printPlan(out, cfg, asJSON); err != nil {
+		fmt.Fprintf(errOut, "multiproc: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// validate checks cfg for the validate subcommand (see
+// runner.Config.Validate), and returns the exit code: 0 if it is valid,
+// else exitUsage after printing every problem to errOut.
+func validate(out, errOut io.Writer, cfg runner.Config) int {
+	if err := cfg.Validate(); err != nil {
+		printErrors(errOut, err)
+		return exitUsage
+	}
+	noun := "processes"
+	if len(cfg.Specs) == 1 {
+		noun = "process"
+	}
+	fmt.Fprintf(out, "OK: %d %s\n", len(cfg.Specs), noun)
+	return 0
+}
+
+// printErrors writes the lines of err, one problem per line for joined
+// errors, each prefixed with "multiproc: ".
+func printErrors(w io.Writer, err error) {
+	for _, line := range strings.Split(err.Error(), "\n") {
+		fmt.Fprintf(w, "multiproc: %s\n", line)
+	}
+}
+
+// orDash returns s, or "-" if s is empty.
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/a2y-d5l/multiproc/engine"
+	"github.com/a2y-d5l/multiproc/runner"
+)
+
+// TestDryRunPlan verifies that -dry-run validates the configuration and
+// prints its problems instead of a plan, as text or JSON.
+func TestDryRunPlan(t *testing.T) {
+	cfg := runner.DefaultConfig()
+	cfg.Specs = []engine.ProcessSpec{{Name: "a", Command: "true", DependsOn: []string{"missing"}}}
+	wantErr := `process "a": depends on unknown process "missing"`
+
+	var out, errOut bytes.Buffer
+	if code := dryRunPlan(&out, &errOut, cfg, false); code != exitUsage {
+		t.Errorf("text: exit code = %d, want %d", code, exitUsage)
+	}
+	if out.Len() != 0 || errOut.String() != "multiproc: "+wantErr+"\n" {
+		t.Errorf("text: got stdout %q, stderr %q", out.String(), errOut.String())
+	}
+
+	out.Reset()
+	errOut.Reset()
+	if code := dryRunPlan(&out, &errOut, cfg, true); code != exitUsage {
+		t.Errorf("json: exit code = %d, want %d", code, exitUsage)
+	}
+	var got invalidPlan
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatalf("json: %v in %q", err, out.String())
+	}
+	if len(got.Errors) != 1 || got.Errors[0] != wantErr {
+		t.Errorf("json: errors = %q, want [%q]", got.Errors, wantErr)
+	}
+
+	cfg.Specs[0].DependsOn = nil
+	out.Reset()
+	if code := dryRunPlan(&out, &errOut, cfg, false); code != 0 {
+		t.Errorf("valid: exit code = %d, want 0", code)
+	}
+	if !strings.Contains(out.String(), "true") {
+		t.Errorf("valid: expected the plan, got %q", out.String())
+	}
+}
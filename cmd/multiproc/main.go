@@ -1,18 +1,46 @@
 package main
 
 import (
+	"bufio"
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"os/signal"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/a2y-d5l/multiproc/config"
 	"github.com/a2y-d5l/multiproc/engine"
+	"github.com/a2y-d5l/multiproc/renderer"
 	"github.com/a2y-d5l/multiproc/runner"
 )
 
+const (
+	// formatText selects the built-in terminal/log renderers.
+	formatText = "text"
+
+	// formatJSON selects the JSON Lines renderer.
+	formatJSON = "json"
+
+	// exitUsage is the exit code for invalid command-line usage.
+	exitUsage = 2
+
+	// cmdList shows the selected processes instead of running them.
+	cmdList = "list"
+
+	// cmdValidate checks the configuration instead of running it.
+	cmdValidate = "validate"
+
+	// cmdStress runs one process many times to find flaky failures.
+	cmdStress = "stress"
+)
+
 func printHelp() {
 	fmt.Fprintf(os.Stderr, `multiproc - Concurrent Process Runner
 
@@ -22,22 +50,115 @@ DESCRIPTION:
   mode with full-screen rendering and non-TTY mode for CI/log environments.
 
 USAGE:
-  multiproc [OPTIONS]
+  multiproc [OPTIONS] [COMMAND...]
+  multiproc list [OPTIONS] [COMMAND...]
+  multiproc validate [OPTIONS] [COMMAND...]
+  multiproc stress [OPTIONS] [COMMAND]
+
+  Each COMMAND is a process, run with "$SHELL -c" and named after the
+  command ("go test ./..." is "go test"), unless -names names it. Options
+  must come before the commands. -p 'name=command' adds a named process.
+
+  Without commands, the processes are read from a configuration file (see
+  CONFIGURATION below) or a Procfile (see PROCFILE below).
+
+  -only, -skip and -group select some of the processes: -only and -skip
+  take name globs ("api-*") or tags, -group the groups of the configuration
+  file. A selected process brings in the processes it depends on
+  ("depends_on"), unless -skip matches them. "list" shows the selected
+  processes instead of running them, and -dry-run shows how each would run:
+  command, environment, limits, timeouts and the waves they start in (as
+  JSON with -format=json). An invalid configuration has no plan: -dry-run
+  prints its problems instead (as {"errors": [...]} with -format=json) and
+  exits with status 2.
+
+  A process with "depends_on" starts once those processes have exited
+  successfully, and is not started at all if one of them fails.
+
+  Before starting anything, multiproc checks that process names are unique
+  and not empty, that commands are found in PATH, that working directories
+  exist, that limits are not negative and that -prefix is a valid format,
+  and reports every problem. "validate" only runs these checks.
+
+  "stress" hunts flaky failures: it runs one process -count times (100 by
+  default), -parallel at a time, or with -until-failure until the first
+  failure (-count=0 for no limit). The output of failed runs is kept in
+  -failures-dir (a temporary directory by default) and that of passed runs
+  is discarded. It then prints the pass and fail counts, the failure rate
+  and the distribution of the run durations.
+
+  While running, multiproc reloads its processes on SIGHUP and when the
+  configuration file, its local override, the Procfile or the .env files
+  change: processes are matched by name, changed ones are restarted, new
+  ones started and removed ones stopped; the others keep running. Use
+  -reload=false to disable it (SIGHUP then stops the run).
+
+  A process with watch patterns ("watch" in the configuration file, or
+  -watch for every process) is stopped gracefully and started again when
+  matching files change, once they have settled ("watch_debounce", 200ms by
+  default), like entr. -watch-ignore and "watch_ignore" skip files, and
+  "watch_clear" clears the output of the previous run. The run then lasts
+  until it is interrupted.
+
+  A process with a "schedule" is run periodically: "@every 5m" (or "5m"),
+  a cron expression like "*/15 * * * *", or @hourly, @daily, @weekly,
+  @monthly and @yearly. A tick that finds the previous run still going is
+  skipped, or queued with "schedule_overlap": "queue". The header shows the
+  next run and the outcome of the last "run_history" runs (10 by default).
 
 OPTIONS:
 `)
 	flag.PrintDefaults()
 	fmt.Fprintf(os.Stderr, `
 EXAMPLES:
-  # Run with default settings (full-screen mode in TTY)
+  # Run the processes of ./multiproc.json (full-screen mode in TTY)
   multiproc
 
+  # One-off parallel run, without a configuration file
+  multiproc "go vet ./..." "go test ./..."
+
+  # Named processes, with per-process colors and line limits
+  multiproc -names=vet,test -colors=cyan,magenta -max-lines=200,5000 "go vet ./..." "go test ./..."
+  multiproc -p 'api=go run ./cmd/api' -p 'web=npm run dev'
+
+  # Only the backend group and the processes tagged "db", except slow ones
+  multiproc -group=backend -only=db -skip=slow
+
+  # Show which processes match, without running them
+  multiproc list -only='test-*'
+
+  # Check the configuration, e.g., in a pre-commit hook
+  multiproc validate
+
+  # Rerun a flaky test 500 times, 8 at a time, keeping the failing output
+  multiproc stress -count=500 -parallel=8 "go test -count=1 -run TestFlaky ./engine"
+  multiproc stress -until-failure -count=0 -failures-dir=flakes "go test -race ./..."
+
+  # Show the resolved commands, environment and limits, as text or JSON
+  multiproc -dry-run
+  multiproc -dry-run -format=json | jq '.processes[].argv'
+
+  # Apply an edited configuration to the running processes now
+  kill -HUP $(pgrep multiproc)
+
+  # Rerun the tests whenever a Go file changes
+  multiproc -watch='**/*.go' -watch-ignore='vendor' "go test ./..."
+
+  # Run the processes of another configuration file
+  multiproc -config=ci/multiproc.toml
+
+  # Run a Procfile like foreman, with the variables of .env and .env.local
+  multiproc -procfile=Procfile -env=.env,.env.local -port=3000
+
   # Enable timestamps for debugging timing issues
   multiproc -timestamps
 
   # Use custom log prefix format
   multiproc -prefix="%%s:"
 
+  # Keep colored prefixes when piping into a pager
+  multiproc -fullscreen=false -color=always | less -R
+
   # Limit output history to 500 lines per process
   multiproc -max-lines=500
 
@@ -47,13 +168,85 @@ EXAMPLES:
   # Disable full-screen mode (useful for logging)
   multiproc -fullscreen=false
 
+  # One contiguous block per process in CI logs, failures at the end
+  multiproc -output=grouped -failed-last
+
+  # JUnit XML report for the CI test results view
+  multiproc -junit=report.xml
+
+  # Browsable HTML report with the full logs
+  multiproc -html=report.html
+
+  # Summary with failures first and their last 20 lines
+  multiproc -summary-sort=status -summary-tail=20
+
+  # Interleaved output in CI, without log folding
+  multiproc -ci=none
+
+  # Full-screen view without keyboard controls
+  multiproc -interactive=false
+
+  # Emit JSON Lines (one object per event) for log shippers
+  multiproc -format=json | my-log-shipper
+
+  # Full-screen view plus a JSON Lines log file for later analysis
+  multiproc -log-file=run.jsonl -log-format=json
+
+KEYS (interactive full-screen mode):
+  ↑/k ↓/j  select a process       Enter  expand to full height
+  PgUp/PgDn, g/G  scroll          /      search (n/N: older/newer match)
+  r  restart   s  stop   c  collapse finished   ?  help   q  quit
+
 ENVIRONMENT:
-  The process specifications are currently hardcoded in main.go.
-  Future versions may support configuration files or command-line arguments.
+  NO_COLOR     Disable colors when -color=auto (https://no-color.org)
+  FORCE_COLOR  Enable colors when -color=auto, even without a TTY
+  GITHUB_ACTIONS, GITLAB_CI
+               Select the CI log folding when -ci=auto
+  GITHUB_STEP_SUMMARY
+               File that receives a Markdown results table on GitHub
+  MULTIPROC_CONFIG
+               Configuration file when -config is not given
+  PORT         PORT of the first Procfile process when -port is not given
+  SHELL        Shell that runs the commands given on the command line
+
+CONFIGURATION:
+  The configuration file is the first of: -config, $MULTIPROC_CONFIG,
+  ./multiproc.json, ./multiproc.toml. It is JSON, or TOML if its name ends
+  in .toml, and lists the processes and optional runner settings:
+
+    {
+      "shutdown_timeout": "10s",
+      "processes": [
+        {"name": "build", "command": "go", "args": ["build", "./..."]},
+        {"name": "test", "command": "go", "args": ["test", "./..."], "max_lines": 5000,
+         "group": "check", "tags": ["go", "slow"], "depends_on": ["build"],
+         "watch": ["**/*.go"], "watch_ignore": ["testdata"]},
+        {"name": "health", "command": "curl", "args": ["-fsS", "localhost:8080/healthz"],
+         "schedule": "@every 30s"}
+      ]
+    }
+
+  A local override next to it (multiproc.local.json for multiproc.json) is
+  merged on top: its settings win, and its processes are merged by name.
+  Flags given on the command line win over both.
+
+PROCFILE:
+  Without processes from a configuration file, multiproc runs ./Procfile if
+  it exists, like foreman. Each "name: command" line is a process run with
+  "sh -c"; process i gets PORT=base+100*i (base: -port, else PORT from the
+  .env files or the environment, else 5000):
+
+    web: ./bin/server -port $PORT
+    worker: ./bin/worker
+
+  The variables of the .env files (-env, or ./.env if it exists) are added
+  to every process, from the Procfile or the configuration file. They are
+  KEY=value lines with optional "export", quotes and ${VAR} interpolation.
 
 EXIT CODES:
   0  - All processes completed successfully
   1  - One or more processes failed
+  2  - Invalid command-line usage or configuration file
 
 For more information, see: https://github.com/a2y-d5l/multiproc
 `)
@@ -62,66 +255,335 @@ For more information, see: https://github.com/a2y-d5l/multiproc
 func run() int {
 	fullScreen := flag.Bool("fullscreen", true, "Enable full-screen terminal rendering (TTY mode only)")
 	showSummary := flag.Bool("summary", true, "Show summary of process results after execution")
+	summarySort := flag.String("summary-sort", "order", "Summary order: 'order', 'duration' (longest first) or 'status' (failures first)")
+	summaryLayout := flag.String("summary-layout", "auto", "Summary layout: 'auto', 'full' or 'compact' (a table)")
+	summaryTail := flag.Int("summary-tail", renderer.DefaultSummaryTailLines, "Output lines shown in the summary for each failed process (-1 for none)")
 	showTimestamps := flag.Bool("timestamps", false, "Prefix each output line with an RFC3339 timestamp")
 	logPrefix := flag.String("prefix", "[%s]", "Format string for process name prefix (e.g., '[%s]', '%s:')")
-	maxLines := flag.Int("max-lines", 1000, "Maximum number of output lines to keep per process")
+	maxLines := intListFlag{1000}
+	flag.Var(&maxLines, "max-lines", "Maximum number of output lines to keep per process, or a comma-separated list with one value per process")
+	var maxBytes intListFlag
+	flag.Var(&maxBytes, "max-bytes", "Maximum bytes of output to keep per process (0 for no limit), or a comma-separated list with one value per process")
+	var colors listFlag
+	flag.Var(&colors, "colors", "Comma-separated colors of the processes' prefixes, in order (names such as 'cyan' or 0-255)")
+	var commands commandsFlag
+	flag.Var(&commands, "p", "Run a named process: -p 'name=command' (repeatable)")
+	var names, only, skip, groups listFlag
+	flag.Var(&only, "only", "Run only the processes whose name matches one of these comma-separated globs, or with one of these tags")
+	flag.Var(&skip, "skip", "Do not run the processes whose name matches one of these comma-separated globs, or with one of these tags")
+	flag.Var(&groups, "group", "Run only the processes of these comma-separated groups (combined with -only)")
+	flag.Var(&names, "names", "Comma-separated names of the commands given as arguments (like concurrently --names)")
 	shutdownSec := flag.Int("shutdown-timeout", 5, "Seconds to wait for graceful shutdown before force-killing")
+	format := flag.String("format", "text", "Output format: 'text' (terminal/log output) or 'json' (JSON Lines)")
+	logFile := flag.String("log-file", "", "Also write all output to this file (in addition to the terminal)")
+	interactive := flag.Bool("interactive", true, "Enable keyboard controls in full-screen mode (press ? for help)")
+	maxFPS := flag.Int("max-fps", 30, "Maximum full-screen redraws per second (-1 for no limit)")
+	wrap := flag.Bool("wrap", false, "Wrap long lines in full-screen mode instead of truncating them")
+	color := flag.String("color", "auto", "Colorize process prefixes: 'auto', 'always' or 'never'")
+	logFormat := flag.String("log-format", "text", "Format of -log-file: 'text' (prefixed lines) or 'json' (JSON Lines)")
+	output := flag.String("output", "interleaved", "Line output: 'interleaved' (as it arrives) or 'grouped' (one block per process)")
+	failedLast := flag.Bool("failed-last", false, "With -output=grouped, print failed processes last")
+	junit := flag.String("junit", "", "Write a JUnit XML report of the process results to this file")
+	htmlReport := flag.String("html", "", "Write a self-contained HTML report with the full logs to this file")
+	ci := flag.String("ci", "auto", "CI log folding: 'auto' (detect), 'github', 'gitlab' or 'none'")
+	configPath := flag.String("config", "", "Configuration file (default: $"+config.EnvVar+", then ./"+config.DefaultFile+" or ./"+config.DefaultTOMLFile+")")
+	procfile := flag.String("procfile", "", "Run the processes of this Procfile (default: ./"+config.DefaultProcfile+" if no configuration file lists processes)")
+	envFiles := flag.String("env", "", "Comma-separated .env files loaded into every process (default: ./"+config.DefaultEnvFile+" if it exists)")
+	basePort := flag.Int("port", 0, "PORT of the first Procfile process, +100 for each next one (default: PORT from the .env files or the environment, else 5000)")
+	var watch, watchIgnore listFlag
+	flag.Var(&watch, "watch", "Restart every process without watch patterns of its own when files matching these comma-separated globs change (e.g., '**/*.go')")
+	flag.Var(&watchIgnore, "watch-ignore", "Comma-separated globs of files whose changes -watch ignores (e.g., '*_test.go,node_modules')")
+	reloadFlag := flag.Bool("reload", true, "Reload the processes on SIGHUP and when the configuration file, Procfile or .env files change")
+	dryRun := flag.Bool("dry-run", false, "Print the plan of the run (commands, environment, limits, timeouts) and exit without starting anything; JSON with -format=json")
+	stressCount := flag.Int("count", defaultStressCount, "With stress, the number of runs (0 with -until-failure for no limit)")
+	stressParallel := flag.Int("parallel", runtime.NumCPU(), "With stress, the maximum number of runs at a time")
+	untilFailure := flag.Bool("until-failure", false, "With stress, stop at the first failed run")
+	failuresDir := flag.String("failures-dir", "", "With stress, keep the output of failed runs in this directory (default: a new temporary directory)")
 	help := flag.Bool("help", false, "Show this help message")
 
-	flag.Parse()
+	// "multiproc list ..." shows what would run instead of running it,
+	// "multiproc validate ..." checks it, and "multiproc stress ..." runs
+	// it many times.
+	args := os.Args[1:]
+	var subcommand string
+	if len(args) > 0 && (args[0] == cmdList || args[0] == cmdValidate || args[0] == cmdStress) {
+		subcommand, args = args[0], args[1:]
+	}
+	if err := flag.CommandLine.Parse(args); err != nil {
+		return exitUsage
+	}
 
 	if *help {
 		printHelp()
 		os.Exit(0)
 	}
 
-	specs := []engine.ProcessSpec{
-		{
-			Name:    "Subprocess A",
-			Command: "sh",
-			Args: []string{
-				"-c",
-				"echo 'A: Starting...'; sleep 1; echo 'A: working...'; sleep 1; echo 'A: Done.'",
-			},
-		},
-		{
-			Name:    "Subprocess B",
-			Command: "sh",
-			Args: []string{
-				"-c",
-				"echo 'B: Starting...'; sleep 1; echo 'B: working...'; sleep 1; echo 'B: working...'; sleep 1; echo 'B: working...'; sleep 1; echo 'B: Done.'",
-			},
-		},
-		{
-			Name:    "Subprocess C",
-			Command: "sh",
-			Args: []string{
-				"-c",
-				"echo 'C: Starting...'; sleep 1; echo 'C: working...'; sleep 1; echo 'C: working...'; sleep 1; echo 'C: Done.'",
-			},
-		},
+	// The stress flags would be ignored by the other modes.
+	var misplaced string
+	flag.Visit(func(f *flag.Flag) {
+		if subcommand != cmdStress && stressFlag(f.Name) && misplaced == "" {
+			misplaced = f.Name
+		}
+	})
+	if misplaced != "" {
+		fmt.Fprintf(os.Stderr, "multiproc: -%s only applies to multiproc stress\n", misplaced)
+		return exitUsage
+	}
+
+	if *format != formatText && *format != formatJSON {
+		fmt.Fprintf(os.Stderr, "multiproc: invalid -format %q (want %q or %q)\n", *format, formatText, formatJSON)
+		return exitUsage
+	}
+	colorMode, err := renderer.ParseColorMode(*color)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "multiproc: -color: %v\n", err)
+		return exitUsage
+	}
+	if *logFormat != formatText && *logFormat != formatJSON {
+		fmt.Fprintf(os.Stderr, "multiproc: invalid -log-format %q (want %q or %q)\n", *logFormat, formatText, formatJSON)
+		return exitUsage
+	}
+	outputMode, err := renderer.ParseOutputMode(*output)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "multiproc: -output: %v\n", err)
+		return exitUsage
+	}
+	sortOrder, err := renderer.ParseSummarySort(*summarySort)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "multiproc: -summary-sort: %v\n", err)
+		return exitUsage
+	}
+	layout, err := renderer.ParseSummaryLayout(*summaryLayout)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "multiproc: -summary-layout: %v\n", err)
+		return exitUsage
+	}
+	ciPlatform, err := renderer.ParseCIPlatform(*ci)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "multiproc: -ci: %v\n", err)
+		return exitUsage
 	}
 
 	ctx, cancel := context.WithCancelCause(context.Background())
 	defer cancel(nil)
 
+	// SIGHUP reloads the processes (see watchReload) of a normal run unless
+	// -reload=false; otherwise it stops multiproc like SIGTERM, so that
+	// subcommands and dry runs still clean up and report.
+	reloading := *reloadFlag && subcommand == "" && !*dryRun
+	stopSignals := []os.Signal{os.Interrupt, syscall.SIGTERM}
+	if !reloading {
+		stopSignals = append(stopSignals, syscall.SIGHUP)
+	}
 	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	signal.Notify(sigCh, stopSignals...)
 	go func() {
 		sig := <-sigCh
 		cancel(fmt.Errorf("received signal: %v", sig))
 	}()
 
+	for _, arg := range flag.Args() {
+		commands = append(commands, config.Command{Command: arg})
+	}
+	if unnamed := len(flag.Args()); len(names) > unnamed {
+		fmt.Fprintf(os.Stderr, "multiproc: -names has %d names for %d commands\n", len(names), unnamed)
+		return exitUsage
+	}
+	if len(commands) > 0 && *procfile != "" {
+		fmt.Fprintln(os.Stderr, "multiproc: -procfile cannot be combined with commands on the command line")
+		return exitUsage
+	}
+	src := specSource{
+		configPath: *configPath,
+		commands:   commands,
+		names:      names,
+		procfile:   *procfile,
+		envFiles:   *envFiles,
+		basePort:   *basePort,
+		selection:  config.Selection{Only: only, Groups: groups, Skip: skip},
+		maxLines:   maxLines,
+		maxBytes:   maxBytes,
+		colors:     colors,
+		watch:      watch,
+		ignore:     watchIgnore,
+	}
 	cfg := runner.DefaultConfig()
-	cfg.Specs = specs
-	cfg.FullScreen = *fullScreen
-	cfg.ShowSummary = *showSummary
-	cfg.ShowTimestamps = *showTimestamps
-	cfg.LogPrefix = *logPrefix
-	cfg.MaxLinesPerProc = *maxLines
-	cfg.ShutdownTimeout = time.Duration(*shutdownSec) * time.Second
-
-	return runner.Run(ctx, cfg)
+	cfg.CI = renderer.CIAuto // the -ci default; the library does not detect CI
+	path, err := src.load(&cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "multiproc: %v\n", err)
+		return exitUsage
+	}
+	if subcommand == cmdList {
+		if err = printList(os.Stdout, cfg.Specs); err != nil {
+			fmt.Fprintf(os.Stderr, "multiproc: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	if len(cfg.Specs) == 0 {
+		if path == "" {
+			fmt.Fprintf(os.Stderr, "multiproc: no processes to run (use -config, -procfile, %s, ./%s or ./%s)\n",
+				config.EnvVar, config.DefaultFile, config.DefaultProcfile)
+		} else {
+			fmt.Fprintf(os.Stderr, "multiproc: %s: no processes to run\n", path)
+		}
+		return exitUsage
+	}
+
+	// Flags given on the command line take precedence over the file.
+	setters := map[string]func(){
+		"fullscreen":       func() { cfg.FullScreen = *fullScreen },
+		"summary":          func() { cfg.ShowSummary = *showSummary },
+		"summary-sort":     func() { cfg.SummarySort = sortOrder },
+		"summary-layout":   func() { cfg.SummaryLayout = layout },
+		"summary-tail":     func() { cfg.SummaryTailLines = *summaryTail },
+		"timestamps":       func() { cfg.ShowTimestamps = *showTimestamps },
+		"prefix":           func() { cfg.LogPrefix = *logPrefix },
+		"max-lines":        func() { cfg.MaxLinesPerProc = maxLines[0] },
+		"shutdown-timeout": func() { cfg.ShutdownTimeout = time.Duration(*shutdownSec) * time.Second },
+		"color":            func() { cfg.Color = colorMode },
+		"output":           func() { cfg.Output = outputMode },
+		"failed-last":      func() { cfg.FailedLast = *failedLast },
+		"ci":               func() { cfg.CI = ciPlatform },
+		"junit":            func() { cfg.JUnitFile = *junit },
+		"html":             func() { cfg.HTMLFile = *htmlReport },
+		"wrap":             func() { cfg.WrapLines = *wrap },
+		"max-fps":          func() { cfg.MaxFrameRate = *maxFPS },
+		"interactive":      func() { cfg.Interactive = *interactive },
+	}
+	flag.Visit(func(f *flag.Flag) {
+		if set, ok := setters[f.Name]; ok {
+			set()
+		}
+	})
+	if subcommand == cmdValidate {
+		return validate(os.Stdout, os.Stderr, cfg)
+	}
+	if *dryRun {
+		return dryRunPlan(os.Stdout, os.Stderr, cfg, *format == formatJSON)
+	}
+	if err = cfg.Validate(); err != nil {
+		printErrors(os.Stderr, err)
+		return exitUsage
+	}
+	if subcommand == cmdStress {
+		opts := stressOptions{count: *stressCount, parallel: *stressParallel, untilFailure: *untilFailure, dir: *failuresDir}
+		if err = opts.check(); err != nil {
+			fmt.Fprintf(os.Stderr, "multiproc: %v\n", err)
+			return exitUsage
+		}
+		if len(cfg.Specs) != 1 {
+			fmt.Fprintf(os.Stderr, "multiproc: stress runs one process, but %d are selected (use -only or a single command)\n", len(cfg.Specs))
+			return exitUsage
+		}
+		return stress(ctx, os.Stdout, os.Stderr, cfg.Specs[0], cfg.ShutdownTimeout, opts)
+	}
+	if *format == formatJSON {
+		cfg.Renderers = []renderer.Renderer{renderer.NewJSONRenderer(os.Stdout)}
+	}
+	if reloading {
+		reload := make(chan []engine.ProcessSpec)
+		cfg.Reload = reload
+		go watchReload(ctx, src, src.files(path), reload, os.Stderr)
+	}
+
+	if *logFile == "" {
+		return runner.Run(ctx, cfg)
+	}
+
+	f, err := os.Create(*logFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "multiproc: %v\n", err)
+		return 1
+	}
+	w := bufio.NewWriter(f)
+
+	// Adding a sink replaces the built-in selection, so add the terminal
+	// renderer explicitly when none was chosen above.
+	if len(cfg.Renderers) == 0 {
+		cfg.Renderers = []renderer.Renderer{runner.DefaultRenderer(cfg)}
+	}
+	if *logFormat == formatJSON {
+		cfg.Renderers = append(cfg.Renderers, renderer.NewJSONRenderer(w))
+	} else {
+		logRenderer := renderer.NewIncrementalRenderer(w, true, cfg.LogPrefix)
+		logRenderer.Align = true
+		logRenderer.AnnounceStart = true
+		cfg.Renderers = append(cfg.Renderers, logRenderer)
+	}
+
+	code := runner.Run(ctx, cfg)
+	if err = errors.Join(w.Flush(), f.Close()); err != nil {
+		fmt.Fprintf(os.Stderr, "multiproc: %s: %v\n", *logFile, err)
+		return 1
+	}
+	return code
+}
+
+// loadConfig discovers and loads the configuration file (see
+// config.Discover) into cfg, and returns its path, or "" if there is none.
+func loadConfig(path string, cfg *runner.Config) (string, error) {
+	path, err := config.Discover(path)
+	if err != nil || path == "" {
+		return "", err
+	}
+	f, err := config.Load(path)
+	if err != nil {
+		return "", err
+	}
+	f.Apply(cfg)
+	return path, nil
+}
+
+// loadProcfileAndEnv loads the .env files (comma-separated, or ./.env if it
+// exists) and the Procfile, if procfile is given or cfg has no processes
+// and ./Procfile exists. The Procfile's processes replace cfg.Specs, and
+// every process gets the .env variables.
+//
+// As in foreman, the PORT of the first Procfile process is basePort if not
+// zero, else PORT from the .env files or the environment, else 5000.
+func loadProcfileAndEnv(cfg *runner.Config, procfile, envFiles string, basePort int) error {
+	var paths []string
+	if envFiles != "" {
+		paths = strings.Split(envFiles, ",")
+	} else if _, err := os.Stat(config.DefaultEnvFile); err == nil {
+		paths = []string{config.DefaultEnvFile}
+	}
+	env, err := config.LoadEnvFiles(paths...)
+	if err != nil {
+		return err
+	}
+
+	if procfile == "" && len(cfg.Specs) == 0 {
+		if _, err = os.Stat(config.DefaultProcfile); err == nil {
+			procfile = config.DefaultProcfile
+		}
+	}
+	if procfile != "" {
+		if basePort == 0 {
+			basePort = config.DefaultBasePort
+			port, ok := os.LookupEnv("PORT")
+			for _, e := range env {
+				if v, found := strings.CutPrefix(e, "PORT="); found {
+					port, ok = v, true
+				}
+			}
+			if ok {
+				if basePort, err = strconv.Atoi(port); err != nil {
+					return fmt.Errorf("invalid PORT %q: %w", port, err)
+				}
+			}
+		}
+		if cfg.Specs, err = config.LoadProcfile(procfile, basePort); err != nil {
+			return err
+		}
+	}
+
+	for i := range cfg.Specs {
+		cfg.Specs[i].Env = append(slices.Clone(env), cfg.Specs[i].Env...)
+	}
+	return nil
 }
 
 func main() {
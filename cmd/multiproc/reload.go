@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/a2y-d5l/multiproc/config"
+	"github.com/a2y-d5l/multiproc/engine"
+	"github.com/a2y-d5l/multiproc/runner"
+)
+
+// reloadPollInterval is how often the watched files are checked for changes.
+const reloadPollInterval = time.Second
+
+// specSource is where the processes come from: the configuration file, the
+// commands, the Procfile and .env files, and the flags that select and
+// adjust them, including -watch. The specs are built again from it on
+// reload.
+type specSource struct {
+	configPath string
+	commands   []config.Command
+	names      listFlag
+	procfile   string
+	envFiles   string
+	basePort   int
+	selection  config.Selection
+	maxLines   intListFlag
+	maxBytes   intListFlag
+	colors     listFlag
+	watch      listFlag
+	ignore     listFlag
+}
+
+// load applies the configuration file to cfg and builds its specs. It
+// returns the path of the configuration file, or "" if there is none.
+func (s specSource) load(cfg *runner.Config) (string, error) {
+	path, err := loadConfig(s.configPath, cfg)
+	if err != nil {
+		return "", err
+	}
+	if len(s.commands) > 0 {
+		cfg.Specs = config.CommandSpecs(s.commands, s.names)
+	}
+	if err = loadProcfileAndEnv(cfg, s.procfile, s.envFiles, s.basePort); err != nil {
+		return "", err
+	}
+	if cfg.Specs, err = config.Select(cfg.Specs, s.selection); err != nil {
+		return "", err
+	}
+	if err = applyPerProcess(cfg.Specs, s.maxLines, s.maxBytes, s.colors); err != nil {
+		return "", err
+	}
+	applyWatch(cfg.Specs, s.watch, s.ignore)
+	return path, nil
+}
+
+// files returns the files the specs are read from, existing or not: the
+// configuration file at path and its local override, the Procfile and the
+// .env files.
+func (s specSource) files(path string) []string {
+	var files []string
+	if path != "" {
+		files = append(files, path, config.LocalPath(path))
+	}
+	if s.procfile != "" {
+		files = append(files, s.procfile)
+	} else if len(s.commands) == 0 {
+		files = append(files, config.DefaultProcfile)
+	}
+	if s.envFiles != "" {
+		files = append(files, strings.Split(s.envFiles, ",")...)
+	} else {
+		files = append(files, config.DefaultEnvFile)
+	}
+	return files
+}
+
+// watchReload sends the specs of src to reload on SIGHUP, and when one of
+// files is created, changed or deleted, until ctx is done. Specs that
+// cannot be loaded are reported to errOut, and the running ones are kept.
+func watchReload(ctx context.Context, src specSource, files []string, reload chan<- []engine.ProcessSpec, errOut io.Writer) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	ticker := time.NewTicker(reloadPollInterval)
+	defer ticker.Stop()
+
+	stamps := fileStamps(files)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+		case <-ticker.C:
+			next := fileStamps(files)
+			if slices.Equal(next, stamps) {
+				continue
+			}
+			stamps = next
+		}
+
+		cfg := runner.DefaultConfig()
+		if _, err := src.load(&cfg); err != nil {
+			fmt.Fprintf(errOut, "multiproc: reload: %v\n", err)
+			continue
+		}
+		select {
+		case reload <- cfg.Specs:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// fileStamps returns the modification time and size of every file, or ""
+// for a file that does not exist.
+func fileStamps(files []string) []string {
+	stamps := make([]string, len(files))
+	for i, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			stamps[i] = info.ModTime().String() + " " + strconv.FormatInt(info.Size(), 10)
+		}
+	}
+	return stamps
+}
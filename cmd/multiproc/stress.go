@@ -0,0 +1,344 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/a2y-d5l/multiproc/engine"
+)
+
+const (
+	// defaultStressCount is the number of runs of the stress subcommand
+	// when -count is not given.
+	defaultStressCount = 100
+
+	// stressOutputBuffer is the number of output events buffered between
+	// the engine and the log file of one run.
+	stressOutputBuffer = 128
+
+	// stressHistogramBuckets is the number of bars of the duration histogram.
+	stressHistogramBuckets = 8
+
+	// stressBarWidth is the width of the longest bar of the duration histogram.
+	stressBarWidth = 40
+
+	// percent converts a ratio to a percentage.
+	percent = 100
+)
+
+// stressOptions are the flags of the stress subcommand.
+type stressOptions struct {
+	// count is the number of runs; 0 runs until the first failure.
+	count int
+
+	// parallel is the maximum number of runs at a time.
+	parallel int
+
+	// untilFailure stops starting runs after the first failure, and
+	// cancels the runs in progress.
+	untilFailure bool
+
+	// dir keeps the output of the failed runs. If empty, a temporary
+	// directory is created, and removed again if no run fails.
+	dir string
+}
+
+// check reports invalid options.
+func (o stressOptions) check() error {
+	switch {
+	case o.count < 0:
+		return fmt.Errorf("invalid -count %d", o.count)
+	case o.count == 0 && !o.untilFailure:
+		return errors.New("-count=0 requires -until-failure")
+	case o.parallel < 1:
+		return fmt.Errorf("invalid -parallel %d", o.parallel)
+	}
+	return nil
+}
+
+// stressFlag reports whether the flag called name is one of the flags of
+// the stress subcommand, which the other modes reject.
+func stressFlag(name string) bool {
+	switch name {
+	case "count", "parallel", "until-failure", "failures-dir":
+		return true
+	default:
+		return false
+	}
+}
+
+// stressResult is the outcome of one run of the stress subcommand.
+type stressResult struct {
+	// run is the number of the run, from 1.
+	run int
+
+	// duration is the time from start to exit.
+	duration time.Duration
+
+	// err is the exit error of a failed run.
+	err error
+
+	// canceled is set for a run that was stopped by an interrupt or by
+	// -until-failure; it is neither passed nor failed.
+	canceled bool
+
+	// log is the file with the output of a failed run.
+	log string
+}
+
+// stress runs spec opts.count times, up to opts.parallel at a time, for
+// the stress subcommand, and returns the exit code: 0 if every run passed,
+// 1 if one failed or the runs were interrupted.
+//
+// The output of every run is written to a log file in opts.dir, which is
+// removed again when the run passes. Each failure is reported to errOut as
+// it happens, and the report (see printStressReport) is written to out.
+func stress(ctx context.Context, out, errOut io.Writer, spec engine.ProcessSpec, shutdownTimeout time.Duration, opts stressOptions) int {
+	// Every run starts the process once: it is not rerun by its triggers.
+	spec.Watch, spec.Schedule = nil, ""
+
+	dir, created, err := stressDir(opts.dir)
+	if err != nil {
+		fmt.Fprintf(errOut, "multiproc: %v\n", err)
+		return 1
+	}
+
+	runCtx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	runs := make(chan int)
+	go func() {
+		defer close(runs)
+		for run := 1; opts.count == 0 || run <= opts.count; run++ {
+			select {
+			case runs <- run:
+			case <-runCtx.Done():
+				return
+			}
+		}
+	}()
+
+	workers := opts.parallel
+	if opts.count > 0 {
+		workers = min(workers, opts.count)
+	}
+	results := make(chan stressResult)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for run := range runs {
+				if runCtx.Err() != nil {
+					return
+				}
+				results <- stressRun(runCtx, spec, shutdownTimeout, run, filepath.Join(dir, stressLogName(spec.Name, run)))
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var done []stressResult
+	var ioErr error
+	for result := range results {
+		switch {
+		case result.canceled:
+			continue
+		case result.log == "" && result.err != nil:
+			// The log file could not be written: the run says nothing.
+			ioErr = errors.Join(ioErr, result.err)
+			stop()
+			continue
+		case result.err != nil:
+			fmt.Fprintf(errOut, "multiproc: run %d failed after %s: %v (output: %s)\n",
+				result.run, result.duration.Round(time.Millisecond), result.err, result.log)
+			if opts.untilFailure {
+				stop()
+			}
+		}
+		done = append(done, result)
+	}
+
+	failed := printStressReport(out, spec.Name, done, dir)
+	if created && failed == 0 {
+		_ = os.RemoveAll(dir)
+	}
+	if ioErr != nil {
+		printErrors(errOut, ioErr)
+		return 1
+	}
+	if failed > 0 || ctx.Err() != nil {
+		return 1
+	}
+	return 0
+}
+
+// stressDir returns the directory for the logs of failed runs: dir, which
+// is created if needed, or a new temporary directory if dir is empty.
+// created reports whether the directory is a temporary one.
+func stressDir(dir string) (string, bool, error) {
+	if dir == "" {
+		tmp, err := os.MkdirTemp("", "multiproc-stress-")
+		return tmp, err == nil, err
+	}
+	return dir, false, os.MkdirAll(dir, 0o750)
+}
+
+// stressLogName returns the name of the log file of a run of the process
+// called name, with the characters that are unsafe in file names replaced.
+func stressLogName(name string, run int) string {
+	safe := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '_', r == '-':
+			return r
+		default:
+			return '-'
+		}
+	}, name)
+	return fmt.Sprintf("%s-%04d.log", safe, run)
+}
+
+// stressRun runs spec once, writing its output to the file at log, and
+// removes the file again unless the run fails.
+func stressRun(ctx context.Context, spec engine.ProcessSpec, shutdownTimeout time.Duration, run int, log string) stressResult {
+	f, err := os.Create(log)
+	if err != nil {
+		return stressResult{run: run, err: err}
+	}
+	w := bufio.NewWriter(f)
+
+	output := make(chan engine.ProcessLine, stressOutputBuffer)
+	start := time.Now()
+	go engine.New([]engine.ProcessSpec{spec}, shutdownTimeout).Run(ctx, output)
+
+	result := stressResult{run: run}
+	for pl := range output {
+		switch {
+		case pl.IsComplete:
+			result.duration = pl.Time.Sub(start)
+			result.err = pl.Err
+			result.canceled = pl.Canceled
+		case !pl.IsRestart && !pl.IsSchedule && !pl.IsUpdate && !pl.IsRemoved:
+			fmt.Fprintln(w, pl.Line)
+		}
+	}
+	if result.err != nil && !result.canceled {
+		fmt.Fprintf(w, "[exit: %v]\n", result.err)
+	}
+
+	if err = errors.Join(w.Flush(), f.Close()); err != nil {
+		return stressResult{run: run, err: fmt.Errorf("%s: %w", log, err)}
+	}
+	if result.err == nil || result.canceled {
+		if err = os.Remove(log); err != nil {
+			return stressResult{run: run, err: err}
+		}
+		return result
+	}
+	result.log = log
+	return result
+}
+
+// printStressReport writes the results of the runs of the process called
+// name, and returns the number of failed runs:
+//
+//	go test: 200 runs, 197 passed, 3 failed (1.5% failure rate)
+//	durations: min 1.204s, p50 1.391s, p90 1.872s, p99 2.415s, max 2.6s
+//	  1.204s - 1.379s  ####################################     96
+//	  ...
+//	output of the failed runs: /tmp/multiproc-stress-123
+//	  go-test-0017.log  exit status 1
+func printStressReport(w io.Writer, name string, results []stressResult, dir string) int {
+	var failed []stressResult
+	durations := make([]time.Duration, len(results))
+	for i, result := range results {
+		durations[i] = result.duration
+		if result.err != nil {
+			failed = append(failed, result)
+		}
+	}
+
+	runs := len(results)
+	rate := 0.0
+	if runs > 0 {
+		rate = float64(len(failed)) / float64(runs) * percent
+	}
+	fmt.Fprintf(w, "%s: %d runs, %d passed, %d failed (%.1f%% failure rate)\n",
+		name, runs, runs-len(failed), len(failed), rate)
+	if runs == 0 {
+		return 0
+	}
+
+	slices.Sort(durations)
+	fmt.Fprintf(w, "durations: min %s, p50 %s, p90 %s, p99 %s, max %s\n",
+		roundDuration(durations[0]), roundDuration(percentile(durations, 50)),
+		roundDuration(percentile(durations, 90)), roundDuration(percentile(durations, 99)),
+		roundDuration(durations[runs-1]))
+	printHistogram(w, durations)
+
+	if len(failed) > 0 {
+		slices.SortFunc(failed, func(a, b stressResult) int { return a.run - b.run })
+		fmt.Fprintf(w, "output of the failed runs: %s\n", dir)
+		for _, result := range failed {
+			fmt.Fprintf(w, "  %s  %v\n", filepath.Base(result.log), result.err)
+		}
+	}
+	return len(failed)
+}
+
+// percentile returns the p-th percentile of sorted, by the nearest-rank
+// method.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p / percent * float64(len(sorted))))
+	return sorted[max(rank-1, 0)]
+}
+
+// printHistogram writes a histogram of sorted, in up to
+// stressHistogramBuckets ranges of equal, whole-millisecond width between
+// its minimum and maximum.
+func printHistogram(w io.Writer, sorted []time.Duration) {
+	lo, hi := roundDuration(sorted[0]), roundDuration(sorted[len(sorted)-1])
+	span := hi - lo + time.Millisecond
+	width := (span + stressHistogramBuckets*time.Millisecond - 1) / stressHistogramBuckets
+	width = width.Truncate(time.Millisecond)
+	buckets := int((span + width - 1) / width)
+
+	counts := make([]int, buckets)
+	for _, d := range sorted {
+		counts[int((roundDuration(d)-lo)/width)]++
+	}
+
+	labels := make([]string, buckets)
+	labelWidth := 0
+	for i := range buckets {
+		from := lo + time.Duration(i)*width
+		labels[i] = from.String() + " - " + (from + width).String()
+		labelWidth = max(labelWidth, len(labels[i]))
+	}
+	most := slices.Max(counts)
+	for i, count := range counts {
+		bar := strings.Repeat("#", count*stressBarWidth/most)
+		if count > 0 && bar == "" {
+			bar = "#"
+		}
+		fmt.Fprintf(w, "  %-*s  %-*s  %d\n", labelWidth, labels[i], stressBarWidth, bar, count)
+	}
+}
+
+// roundDuration rounds d to milliseconds, for the report.
+func roundDuration(d time.Duration) time.Duration {
+	return d.Round(time.Millisecond)
+}
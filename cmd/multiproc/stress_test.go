@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a2y-d5l/multiproc/engine"
+)
+
+// TestPercentile verifies nearest-rank percentiles.
+func TestPercentile(t *testing.T) {
+	var sorted []time.Duration
+	for i := 1; i <= 10; i++ {
+		sorted = append(sorted, time.Duration(i)*time.Millisecond)
+	}
+	tests := []struct {
+		sorted []time.Duration
+		p      float64
+		want   time.Duration
+	}{
+		{sorted, 0, time.Millisecond},
+		{sorted, 10, time.Millisecond},
+		{sorted, 11, 2 * time.Millisecond},
+		{sorted, 50, 5 * time.Millisecond},
+		{sorted, 90, 9 * time.Millisecond},
+		{sorted, 99, 10 * time.Millisecond},
+		{sorted, 100, 10 * time.Millisecond},
+		{sorted[:1], 50, time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := percentile(tt.sorted, tt.p); got != tt.want {
+			t.Errorf("percentile(%v, %v) = %v, want %v", tt.sorted, tt.p, got, tt.want)
+		}
+	}
+}
+
+// TestPrintHistogram verifies the buckets have whole-millisecond bounds,
+// cover every duration once, and never exceed stressHistogramBuckets.
+func TestPrintHistogram(t *testing.T) {
+	ms := func(values ...int) []time.Duration {
+		durations := make([]time.Duration, len(values))
+		for i, v := range values {
+			durations[i] = time.Duration(v) * time.Millisecond
+		}
+		return durations
+	}
+
+	var b strings.Builder
+	printHistogram(&b, ms(1, 1, 2, 9))
+	want := []string{
+		"  1ms - 3ms   " + strings.Repeat("#", stressBarWidth) + "  3",
+		"  3ms - 5ms   " + strings.Repeat(" ", stressBarWidth) + "  0",
+		"  5ms - 7ms   " + strings.Repeat(" ", stressBarWidth) + "  0",
+		"  7ms - 9ms   " + strings.Repeat(" ", stressBarWidth) + "  0",
+		"  9ms - 11ms  " + strings.Repeat("#", stressBarWidth/3) + strings.Repeat(" ", stressBarWidth-stressBarWidth/3) + "  1",
+	}
+	if got := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n"); strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Histogram:\n%s\nwant:\n%s", b.String(), strings.Join(want, "\n"))
+	}
+
+	b.Reset()
+	printHistogram(&b, ms(5, 5, 5))
+	if got := b.String(); !strings.HasPrefix(got, "  5ms - 6ms  ") || strings.Count(got, "\n") != 1 {
+		t.Errorf("Expected a single bucket for equal durations, got:\n%s", got)
+	}
+
+	for _, sorted := range [][]time.Duration{ms(0, 80), ms(3, 4, 5, 6), ms(100, 107, 250, 1999), {time.Microsecond, 1500 * time.Microsecond}} {
+		b.Reset()
+		printHistogram(&b, sorted)
+		lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+		total := 0
+		for _, line := range lines {
+			n, err := strconv.Atoi(line[strings.LastIndex(line, " ")+1:])
+			if err != nil {
+				t.Fatalf("Bad histogram line %q", line)
+			}
+			total += n
+		}
+		if len(lines) > stressHistogramBuckets || total != len(sorted) {
+			t.Errorf("Histogram of %v has %d buckets counting %d durations:\n%s", sorted, len(lines), total, b.String())
+		}
+	}
+}
+
+// TestPrintStressReport verifies the counts, the failure rate, the
+// percentiles and the list of kept logs.
+func TestPrintStressReport(t *testing.T) {
+	failure := errors.New("exit status 1")
+	results := []stressResult{
+		{run: 3, duration: 30 * time.Millisecond, err: failure, log: "/tmp/x/go-test-0003.log"},
+		{run: 1, duration: 10 * time.Millisecond},
+		{run: 2, duration: 20 * time.Millisecond, err: failure, log: "/tmp/x/go-test-0002.log"},
+		{run: 4, duration: 40 * time.Millisecond},
+	}
+
+	var b strings.Builder
+	if failed := printStressReport(&b, "go test", results, "/tmp/x"); failed != 2 {
+		t.Errorf("Expected 2 failed runs, got %d", failed)
+	}
+	for _, want := range []string{
+		"go test: 4 runs, 2 passed, 2 failed (50.0% failure rate)\n",
+		"durations: min 10ms, p50 20ms, p90 40ms, p99 40ms, max 40ms\n",
+		"output of the failed runs: /tmp/x\n  go-test-0002.log  exit status 1\n  go-test-0003.log  exit status 1\n",
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("Expected %q in report:\n%s", want, b.String())
+		}
+	}
+
+	b.Reset()
+	if failed := printStressReport(&b, "go test", nil, "/tmp/x"); failed != 0 || b.String() != "go test: 0 runs, 0 passed, 0 failed (0.0% failure rate)\n" {
+		t.Errorf("Unexpected report of no runs (%d failed):\n%s", failed, b.String())
+	}
+}
+
+// TestStress verifies runs with real processes: passing output is
+// discarded, failing output is kept, -until-failure stops an unlimited
+// run, and canceled runs count neither way.
+func TestStress(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping real process test in short mode")
+	}
+	sh := func(script string) engine.ProcessSpec {
+		return engine.ProcessSpec{Name: "sh test", Command: "sh", Args: []string{"-c", script}}
+	}
+	logs := func(dir string) []string {
+		entries, err := os.ReadDir(dir)
+		if err != nil && !os.IsNotExist(err) {
+			t.Fatal(err)
+		}
+		names := make([]string, len(entries))
+		for i, e := range entries {
+			names[i] = e.Name()
+		}
+		return names
+	}
+
+	t.Run("passing runs leave nothing behind", func(t *testing.T) {
+		tmp := t.TempDir()
+		t.Setenv("TMPDIR", tmp)
+		var out, errOut bytes.Buffer
+		code := stress(context.Background(), &out, &errOut, sh("echo ok"), time.Second, stressOptions{count: 5, parallel: 2})
+		if code != 0 || !strings.Contains(out.String(), "5 runs, 5 passed, 0 failed") {
+			t.Errorf("Expected 5 passed runs and exit code 0, got %d:\n%s%s", code, out.String(), errOut.String())
+		}
+		if names := logs(tmp); len(names) != 0 {
+			t.Errorf("Expected the temporary directory to be removed, found %q", names)
+		}
+	})
+
+	t.Run("failing runs keep their output", func(t *testing.T) {
+		dir := filepath.Join(t.TempDir(), "flakes")
+		var out, errOut bytes.Buffer
+		// Even runs fail. Runs are sequential, so the counter is the run number.
+		spec := sh(`n=$(( $(cat "$COUNTER" 2>/dev/null || echo 0) + 1 )); echo $n >"$COUNTER"; echo out; echo err >&2; exit $(( n % 2 == 0 ))`)
+		spec.Env = []string{"COUNTER=" + filepath.Join(t.TempDir(), "counter")}
+		code := stress(context.Background(), &out, &errOut, spec, time.Second, stressOptions{count: 6, parallel: 1, dir: dir})
+
+		if code != 1 || !strings.Contains(out.String(), "6 runs, 3 passed, 3 failed") {
+			t.Errorf("Expected 3 failures and exit code 1, got %d:\n%s%s", code, out.String(), errOut.String())
+		}
+		names := logs(dir)
+		want := []string{"sh-test-0002.log", "sh-test-0004.log", "sh-test-0006.log"}
+		if strings.Join(names, " ") != strings.Join(want, " ") {
+			t.Fatalf("Expected kept logs %q, found %q", want, names)
+		}
+		for _, name := range names {
+			data, err := os.ReadFile(filepath.Join(dir, name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := string(data); !strings.Contains(got, "out\n") || !strings.Contains(got, "err\n") || !strings.HasSuffix(got, "[exit: exit status 1]\n") {
+				t.Errorf("Unexpected log %s:\n%s", name, got)
+			}
+		}
+	})
+
+	t.Run("until-failure stops at the first failure", func(t *testing.T) {
+		dir := t.TempDir()
+		var out, errOut bytes.Buffer
+		// The first run to take the lock fails; the others pass.
+		spec := sh(`if mkdir "$LOCK" 2>/dev/null; then sleep 0.2; exit 1; fi; sleep 0.05`)
+		spec.Env = []string{"LOCK=" + filepath.Join(t.TempDir(), "lock")}
+		code := stress(context.Background(), &out, &errOut, spec, time.Second, stressOptions{parallel: 2, untilFailure: true, dir: dir})
+		if code != 1 || !strings.Contains(out.String(), " passed, 1 failed") {
+			t.Errorf("Expected exactly one failure and exit code 1, got %d:\n%s", code, out.String())
+		}
+		if names := logs(dir); len(names) != 1 {
+			t.Errorf("Expected the log of the failed run only, found %q", names)
+		}
+	})
+
+	t.Run("canceled runs are not counted", func(t *testing.T) {
+		dir := t.TempDir()
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		var out, errOut bytes.Buffer
+		code := stress(ctx, &out, &errOut, sh("sleep 5"), time.Second, stressOptions{count: 4, parallel: 2, dir: dir})
+		if code != 1 || !strings.HasPrefix(out.String(), "sh test: 0 runs, 0 passed, 0 failed") {
+			t.Errorf("Expected no counted runs and exit code 1, got %d:\n%s%s", code, out.String(), errOut.String())
+		}
+		if names := logs(dir); len(names) != 0 {
+			t.Errorf("Expected the logs of canceled runs to be removed, found %q", names)
+		}
+	})
+}
+
+// TestStressOptionsCheck verifies invalid flag combinations are rejected.
+func TestStressOptionsCheck(t *testing.T) {
+	tests := []struct {
+		opts stressOptions
+		want string
+	}{
+		{stressOptions{count: 10, parallel: 1}, ""},
+		{stressOptions{untilFailure: true, parallel: 4}, ""},
+		{stressOptions{count: -1, parallel: 1}, "invalid -count -1"},
+		{stressOptions{parallel: 1}, "-count=0 requires -until-failure"},
+		{stressOptions{count: 1}, "invalid -parallel 0"},
+	}
+	for _, tt := range tests {
+		got := ""
+		if err := tt.opts.check(); err != nil {
+			got = err.Error()
+		}
+		if got != tt.want {
+			t.Errorf("check(%+v) = %q, want %q", tt.opts, got, tt.want)
+		}
+	}
+}
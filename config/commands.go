@@ -0,0 +1,125 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/a2y-d5l/multiproc/engine"
+)
+
+// maxNameWords is the number of words of a command CommandName keeps.
+const maxNameWords = 3
+
+// Command is a process given on the command line: a shell command and an
+// optional name.
+type Command struct {
+	// Name is the process name. If empty, CommandSpecs picks one.
+	Name string
+
+	// Command is the shell command line.
+	Command string
+}
+
+// ParseNamedCommand parses a "name=command" argument, e.g., of the -p
+// flag:
+//
+//	config.ParseNamedCommand("api=go run ./cmd/api") // {Name: "api", Command: "go run ./cmd/api"}
+func ParseNamedCommand(s string) (Command, error) {
+	name, command, ok := strings.Cut(s, "=")
+	name, command = strings.TrimSpace(name), strings.TrimSpace(command)
+	if !ok || name == "" || command == "" {
+		return Command{}, fmt.Errorf("invalid process %q (want name=command)", s)
+	}
+	return Command{Name: name, Command: command}, nil
+}
+
+// CommandSpecs returns the specs of commands given on the command line.
+// Each command runs through the user's shell (see ShellCommand). Commands
+// without a name take the next of names, like concurrently --names, then
+// one derived from the command (see CommandName). Repeated names get a
+// " (2)", " (3)", ... suffix.
+//
+// Example:
+//
+//	specs := config.CommandSpecs([]config.Command{
+//	    {Command: "go vet ./..."},
+//	    {Command: "go test ./..."},
+//	}, nil) // named "go vet" and "go test"
+func CommandSpecs(commands []Command, names []string) []engine.ProcessSpec {
+	specs := make([]engine.ProcessSpec, len(commands))
+	seen := map[string]int{}
+	for i, c := range commands {
+		name := c.Name
+		if name == "" && len(names) > 0 {
+			name, names = strings.TrimSpace(names[0]), names[1:]
+		}
+		if name == "" {
+			name = CommandName(c.Command)
+		}
+		if seen[name]++; seen[name] > 1 {
+			name += " (" + strconv.Itoa(seen[name]) + ")"
+		}
+
+		shell, args := ShellCommand(c.Command)
+		specs[i] = engine.ProcessSpec{Name: name, Command: shell, Args: args}
+	}
+	return specs
+}
+
+// CommandName derives a process name from a shell command: the base name
+// of the program followed by up to two subcommand-like words, stopping at
+// the first option, path or other argument. Leading VAR=value assignments
+// are skipped.
+//
+// Example:
+//
+//	config.CommandName("go test -race ./...")      // "go test"
+//	config.CommandName("npm run dev")              // "npm run dev"
+//	config.CommandName("CGO_ENABLED=0 ./bin/api")  // "api"
+func CommandName(command string) string {
+	fields := strings.Fields(command)
+	for len(fields) > 1 && strings.Contains(fields[0], "=") {
+		fields = fields[1:]
+	}
+	if len(fields) == 0 {
+		return ""
+	}
+
+	words := []string{path.Base(strings.Trim(fields[0], `"'`))}
+	for _, f := range fields[1:] {
+		if len(words) == maxNameWords || !isSubcommand(f) {
+			break
+		}
+		words = append(words, f)
+	}
+	return strings.Join(words, " ")
+}
+
+// ShellCommand returns the command and arguments that run command through
+// the user's shell: "$SHELL -c", or "sh -c" if SHELL is not set, or
+// "cmd /C" on Windows.
+func ShellCommand(command string) (string, []string) {
+	if sh := os.Getenv("SHELL"); sh != "" && runtime.GOOS != "windows" {
+		return sh, []string{"-c", command}
+	}
+	return shellCommand(command)
+}
+
+// isSubcommand reports whether a word of a command looks like a
+// subcommand: a letter followed by letters, digits, "_", "-" or ":".
+func isSubcommand(word string) bool {
+	for i, c := range word {
+		isLetter := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+		if i == 0 && !isLetter {
+			return false
+		}
+		if !isLetter && (c < '0' || c > '9') && c != '_' && c != '-' && c != ':' {
+			return false
+		}
+	}
+	return word != ""
+}
@@ -0,0 +1,492 @@
+// Package config loads process specifications and runner options from a
+// configuration file, so that a project describes its processes once
+// instead of in code.
+//
+// A configuration file is JSON, or a subset of TOML if its name ends in
+// ".toml" (see the TOML section below). It lists the processes, with every
+// engine.ProcessSpec field, and optionally sets runner.Config options:
+//
+//	{
+//	  "shutdown_timeout": "10s",
+//	  "output": "grouped",
+//	  "processes": [
+//	    {"name": "build", "command": "go", "args": ["build", "./..."]},
+//	    {"name": "test", "command": "go", "args": ["test", "./..."], "max_lines": 5000,
+//	     "env": {"GOFLAGS": "-count=1"}, "group": "check", "tags": ["go", "slow"]}
+//	  ]
+//	}
+//
+// Options left out of the file keep their runner.DefaultConfig values, or
+// whatever the caller set before File.Apply. Enumerations use their flag
+// spellings ("grouped", "never", ...), durations are strings such as "10s"
+// or "1m30s".
+//
+// # Discovery
+//
+// Discover picks the file to load, in order:
+//  1. The path given explicitly (e.g., with -config)
+//  2. The path in the MULTIPROC_CONFIG environment variable
+//  3. multiproc.json, then multiproc.toml, in the working directory
+//
+// # Local overrides
+//
+// Load merges a local override file on top of the configuration file, if
+// one exists next to it: multiproc.local.json for multiproc.json (see
+// LocalPath). It is meant for per-developer tweaks and is usually kept out
+// of version control. Options in the override replace those of the file;
+// processes are matched by name, and the fields set in the override replace
+// those of the matching process. Processes with a new name are appended:
+//
+//	{"color": "never", "processes": [{"name": "test", "args": ["test", "-run", "TestParse", "./..."]}]}
+//
+// # TOML
+//
+// The TOML reader supports tables, arrays of tables, dotted keys, inline
+// tables and arrays, basic and literal strings (not multi-line ones),
+// integers, floats and booleans, which covers configuration files:
+//
+//	shutdown_timeout = "10s"
+//	output = "grouped"
+//
+//	[[processes]]
+//	name = "build"
+//	command = "go"
+//	args = ["build", "./..."]
+//
+//	[[processes]]
+//	name = "test"
+//	command = "go"
+//	args = ["test", "./..."]
+//	max_lines = 5000
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/a2y-d5l/multiproc/engine"
+	"github.com/a2y-d5l/multiproc/renderer"
+	"github.com/a2y-d5l/multiproc/runner"
+)
+
+const (
+	// EnvVar is the environment variable Discover reads the path of the
+	// configuration file from.
+	EnvVar = "MULTIPROC_CONFIG"
+
+	// DefaultFile is the JSON configuration file Discover looks for in the
+	// working directory.
+	DefaultFile = "multiproc.json"
+
+	// DefaultTOMLFile is the TOML configuration file Discover looks for in
+	// the working directory when there is no DefaultFile.
+	DefaultTOMLFile = "multiproc.toml"
+
+	// localSuffix is inserted before the extension of a configuration file
+	// to name its local override file.
+	localSuffix = ".local"
+
+	// processesKey is the key of the process list, which local overrides
+	// merge by name.
+	processesKey = "processes"
+)
+
+// File is a loaded configuration file.
+type File struct {
+	// Path is the configuration file.
+	Path string `json:"-"`
+
+	// LocalPath is the local override merged on top of Path, or empty if
+	// there was none.
+	LocalPath string `json:"-"`
+
+	// Processes are the processes to run, in order.
+	Processes []Process `json:"processes"`
+
+	Options
+}
+
+// Process describes a process in a configuration file. The fields are
+// those of engine.ProcessSpec.
+type Process struct {
+	// Name is the label of the process (ProcessSpec.Name).
+	Name string `json:"name"`
+
+	// Command is the executable to run (ProcessSpec.Command).
+	Command string `json:"command"`
+
+	// Args are the arguments of the command (ProcessSpec.Args).
+	Args []string `json:"args"`
+
+	// MaxLines is the number of output lines to keep (ProcessSpec.MaxLines).
+	MaxLines int `json:"max_lines"`
+
+	// MaxBytes is the number of output bytes to keep (ProcessSpec.MaxBytes).
+	MaxBytes int `json:"max_bytes"`
+
+	// Color is the display color of the process (ProcessSpec.Color).
+	Color string `json:"color"`
+
+	// Env holds extra environment variables of the process
+	// (ProcessSpec.Env). A local override merges it key by key.
+	Env map[string]string `json:"env"`
+
+	// Group is the group of the process (ProcessSpec.Group).
+	Group string `json:"group"`
+
+	// Tags label the process (ProcessSpec.Tags).
+	Tags []string `json:"tags"`
+
+	// DependsOn names the processes that must finish before this one
+	// starts (ProcessSpec.DependsOn).
+	DependsOn []string `json:"depends_on"`
+
+	// Dir is the working directory of the process (ProcessSpec.Dir). A
+	// relative directory is relative to the directory of the file.
+	Dir string `json:"dir"`
+
+	// Watch lists glob patterns of files that restart the process when they
+	// change (ProcessSpec.Watch).
+	Watch []string `json:"watch"`
+
+	// WatchIgnore lists glob patterns of files whose changes are ignored
+	// (ProcessSpec.WatchIgnore).
+	WatchIgnore []string `json:"watch_ignore"`
+
+	// WatchDebounce is how long changes must settle before the process is
+	// restarted (ProcessSpec.WatchDebounce).
+	WatchDebounce Duration `json:"watch_debounce"`
+
+	// WatchClear clears the output of the previous runs on a restart caused
+	// by a change (ProcessSpec.WatchClear).
+	WatchClear bool `json:"watch_clear"`
+
+	// Schedule runs the process again periodically, e.g., "30s" or
+	// "*/5 * * * *" (ProcessSpec.Schedule).
+	Schedule string `json:"schedule"`
+
+	// ScheduleOverlap is "skip" or "queue" (ProcessSpec.ScheduleOverlap).
+	ScheduleOverlap engine.ScheduleOverlap `json:"schedule_overlap"`
+
+	// RunHistory is the number of run results kept for the process
+	// (ProcessSpec.RunHistory).
+	RunHistory int `json:"run_history"`
+}
+
+// Options are the runner.Config options a configuration file can set.
+// Each field sets the runner.Config field of the same name; nil fields
+// were not in the file and leave it unchanged.
+type Options struct {
+	IsTTY            *bool                   `json:"is_tty"`
+	LogPrefix        *string                 `json:"log_prefix"`
+	MaxLinesPerProc  *int                    `json:"max_lines_per_proc"`
+	ShutdownTimeout  *Duration               `json:"shutdown_timeout"`
+	FullScreen       *bool                   `json:"full_screen"`
+	ShowSummary      *bool                   `json:"show_summary"`
+	SummarySort      *renderer.SummarySort   `json:"summary_sort"`
+	SummaryLayout    *renderer.SummaryLayout `json:"summary_layout"`
+	SummaryTailLines *int                    `json:"summary_tail_lines"`
+	ShowTimestamps   *bool                   `json:"show_timestamps"`
+	Color            *renderer.ColorMode     `json:"color"`
+	Output           *renderer.OutputMode    `json:"output"`
+	FailedLast       *bool                   `json:"failed_last"`
+	CI               *renderer.CIPlatform    `json:"ci"`
+	WrapLines        *bool                   `json:"wrap_lines"`
+	Interactive      *bool                   `json:"interactive"`
+	TickInterval     *Duration               `json:"tick_interval"`
+	MaxFrameRate     *int                    `json:"max_frame_rate"`
+	JUnitFile        *string                 `json:"junit_file"`
+	HTMLFile         *string                 `json:"html_file"`
+}
+
+// Duration is a time.Duration written as a string, such as "500ms", "10s"
+// or "1m30s".
+type Duration time.Duration
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler with time.ParseDuration.
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// Discover returns the configuration file to load: path if not empty, else
+// the file named by the MULTIPROC_CONFIG environment variable, else
+// DefaultFile or DefaultTOMLFile in the working directory if one exists.
+// It returns "" if there is no configuration file.
+//
+// Example:
+//
+//	path, err := config.Discover(*configFlag)
+func Discover(path string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	if path = os.Getenv(EnvVar); path != "" {
+		return path, nil
+	}
+	for _, name := range []string{DefaultFile, DefaultTOMLFile} {
+		_, err := os.Stat(name)
+		if err == nil {
+			return name, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+// LocalPath returns the local override file of the configuration file
+// path: ".local" inserted before the extension, e.g., "multiproc.local.json"
+// for "multiproc.json".
+func LocalPath(path string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + localSuffix + ext
+}
+
+// Load reads the configuration file at path and merges its local override
+// (see LocalPath) on top, if it exists. Unknown keys, invalid values and
+// processes without a command are errors.
+//
+// Example:
+//
+//	f, err := config.Load("multiproc.json")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	cfg := runner.DefaultConfig()
+//	f.Apply(&cfg)
+func Load(path string) (*File, error) {
+	tree, err := readTree(path)
+	if err != nil {
+		return nil, err
+	}
+
+	local := LocalPath(path)
+	override, err := readTree(local)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		local = ""
+	case err != nil:
+		return nil, err
+	default:
+		merge(tree, override)
+	}
+
+	data, err := json.Marshal(tree)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	f, err := decode(data)
+	if err == nil {
+		err = f.check()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	f.Path, f.LocalPath = path, local
+	return f, nil
+}
+
+// Specs returns the engine.ProcessSpec of every process.
+func (f *File) Specs() []engine.ProcessSpec {
+	specs := make([]engine.ProcessSpec, len(f.Processes))
+	for i, p := range f.Processes {
+		specs[i] = engine.ProcessSpec{
+			Name:     p.Name,
+			Command:  p.Command,
+			Args:     p.Args,
+			MaxLines: p.MaxLines,
+			MaxBytes: p.MaxBytes,
+			Color:    p.Color,
+			Group:    p.Group,
+			Tags:     p.Tags,
+			Dir:      p.Dir,
+
+			DependsOn: p.DependsOn,
+
+			Watch:         p.Watch,
+			WatchIgnore:   p.WatchIgnore,
+			WatchDebounce: time.Duration(p.WatchDebounce),
+			WatchClear:    p.WatchClear,
+
+			Schedule:        p.Schedule,
+			ScheduleOverlap: p.ScheduleOverlap,
+			RunHistory:      p.RunHistory,
+		}
+		if p.Dir != "" && !filepath.IsAbs(p.Dir) && f.Path != "" {
+			specs[i].Dir = filepath.Join(filepath.Dir(f.Path), p.Dir)
+		}
+		for _, k := range slices.Sorted(maps.Keys(p.Env)) {
+			specs[i].Env = append(specs[i].Env, k+"="+p.Env[k])
+		}
+	}
+	return specs
+}
+
+// Apply sets cfg.Specs to the processes of the file, if it has any, and
+// the options the file sets. Options the file leaves out are unchanged.
+func (f *File) Apply(cfg *runner.Config) {
+	if len(f.Processes) > 0 {
+		cfg.Specs = f.Specs()
+	}
+
+	o := &f.Options
+	if o.IsTTY != nil {
+		v := *o.IsTTY
+		cfg.IsTTY = &v
+	}
+	set(&cfg.LogPrefix, o.LogPrefix)
+	set(&cfg.MaxLinesPerProc, o.MaxLinesPerProc)
+	if o.ShutdownTimeout != nil {
+		cfg.ShutdownTimeout = time.Duration(*o.ShutdownTimeout)
+	}
+	set(&cfg.FullScreen, o.FullScreen)
+	set(&cfg.ShowSummary, o.ShowSummary)
+	set(&cfg.SummarySort, o.SummarySort)
+	set(&cfg.SummaryLayout, o.SummaryLayout)
+	set(&cfg.SummaryTailLines, o.SummaryTailLines)
+	set(&cfg.ShowTimestamps, o.ShowTimestamps)
+	set(&cfg.Color, o.Color)
+	set(&cfg.Output, o.Output)
+	set(&cfg.FailedLast, o.FailedLast)
+	set(&cfg.CI, o.CI)
+	set(&cfg.WrapLines, o.WrapLines)
+	set(&cfg.Interactive, o.Interactive)
+	if o.TickInterval != nil {
+		cfg.TickInterval = time.Duration(*o.TickInterval)
+	}
+	set(&cfg.MaxFrameRate, o.MaxFrameRate)
+	set(&cfg.JUnitFile, o.JUnitFile)
+	set(&cfg.HTMLFile, o.HTMLFile)
+}
+
+// set copies *src to *dst if src is not nil.
+func set[T any](dst, src *T) {
+	if src != nil {
+		*dst = *src
+	}
+}
+
+// readTree reads a configuration file into a generic tree of maps, slices
+// and values. The file is also decoded on its own, so that unknown keys and
+// invalid values are reported with its path rather than after merging.
+func readTree(path string) (map[string]any, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var tree map[string]any
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		tree, err = parseTOML(string(data))
+		if err == nil {
+			data, err = json.Marshal(tree)
+		}
+	} else {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		err = dec.Decode(&tree)
+	}
+	if err == nil {
+		_, err = decode(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return tree, nil
+}
+
+// decode decodes a configuration in JSON, rejecting unknown keys.
+func decode(data []byte) (*File, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var f File
+	if err := dec.Decode(&f); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// check reports processes without a command and duplicate process names,
+// which would make local overrides ambiguous.
+func (f *File) check() error {
+	names := make(map[string]bool, len(f.Processes))
+	for i, p := range f.Processes {
+		if p.Command == "" {
+			return fmt.Errorf("process %d (%q) has no command", i+1, p.Name)
+		}
+		if p.Name != "" && names[p.Name] {
+			return fmt.Errorf("duplicate process name %q", p.Name)
+		}
+		names[p.Name] = true
+	}
+	return nil
+}
+
+// merge merges override into base: objects are merged key by key, the
+// process list by process name (see mergeProcesses), and every other value
+// in override replaces the one in base.
+func merge(base, override map[string]any) {
+	for k, v := range override {
+		if k == processesKey {
+			base[k] = mergeProcesses(base[k], v)
+			continue
+		}
+		dst, ok := base[k].(map[string]any)
+		src, isMap := v.(map[string]any)
+		if ok && isMap {
+			merge(dst, src)
+			continue
+		}
+		base[k] = v
+	}
+}
+
+// mergeProcesses merges the processes of an override into those of the
+// base: an override process is merged into the base process of the same
+// name, or appended if there is none.
+func mergeProcesses(base, override any) any {
+	procs, _ := base.([]any)
+	overrides, ok := override.([]any)
+	if !ok {
+		return override
+	}
+	for _, o := range overrides {
+		op, isMap := o.(map[string]any)
+		if !isMap {
+			procs = append(procs, o)
+			continue
+		}
+		merged := false
+		for _, p := range procs {
+			bp, isBaseMap := p.(map[string]any)
+			if isBaseMap && op["name"] != nil && bp["name"] == op["name"] {
+				merge(bp, op)
+				merged = true
+				break
+			}
+		}
+		if !merged {
+			procs = append(procs, op)
+		}
+	}
+	return procs
+}
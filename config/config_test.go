@@ -0,0 +1,545 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/a2y-d5l/multiproc/config"
+	"github.com/a2y-d5l/multiproc/engine"
+	"github.com/a2y-d5l/multiproc/renderer"
+	"github.com/a2y-d5l/multiproc/runner"
+)
+
+// writeFile writes a file in dir and returns its path.
+func writeFile(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const jsonConfig = `{
+  "shutdown_timeout": "10s",
+  "output": "grouped",
+  "color": "never",
+  "max_lines_per_proc": 200,
+  "is_tty": false,
+  "processes": [
+    {"name": "build", "command": "go", "args": ["build", "./..."], "color": "cyan", "dir": "web",
+     "watch": ["**/*.go"], "watch_ignore": ["*_test.go"], "watch_debounce": "1s", "watch_clear": true},
+    {"name": "test", "command": "go", "args": ["test", "./..."], "max_lines": 5000, "max_bytes": 65536,
+     "env": {"GOFLAGS": "-count=1", "CGO_ENABLED": "0"}, "depends_on": ["build"],
+     "schedule": "*/5 * * * *", "schedule_overlap": "queue", "run_history": 20}
+  ]
+}`
+
+const tomlConfig = `# Same as jsonConfig.
+shutdown_timeout = "10s"
+output = "grouped"   # one block per process
+color = 'never'
+max_lines_per_proc = 200
+is_tty = false
+
+[[processes]]
+name = "build"
+command = "go"
+args = ["build", "./..."]
+color = "cyan"
+dir = "web"
+watch = ["**/*.go"]
+watch_ignore = ["*_test.go"]
+watch_debounce = "1s"
+watch_clear = true
+
+[[processes]]
+name = "test"
+command = "go"
+args = [
+  "test",
+  "./...", # trailing comma
+]
+max_lines = 5_000
+max_bytes = 0x10000
+env = { GOFLAGS = "-count=1", CGO_ENABLED = "0" }
+depends_on = ["build"]
+schedule = "*/5 * * * *"
+schedule_overlap = "queue"
+run_history = 20
+`
+
+// TestLoad verifies JSON and TOML files load into the same configuration.
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	wantSpecs := []engine.ProcessSpec{
+		{Name: "build", Command: "go", Args: []string{"build", "./..."}, Color: "cyan", Dir: filepath.Join(dir, "web"),
+			Watch: []string{"**/*.go"}, WatchIgnore: []string{"*_test.go"}, WatchDebounce: time.Second, WatchClear: true},
+		{Name: "test", Command: "go", Args: []string{"test", "./..."}, MaxLines: 5000, MaxBytes: 65536,
+			Env: []string{"CGO_ENABLED=0", "GOFLAGS=-count=1"}, DependsOn: []string{"build"},
+			Schedule: "*/5 * * * *", ScheduleOverlap: engine.OverlapQueue, RunHistory: 20},
+	}
+
+	for _, path := range []string{
+		writeFile(t, dir, "multiproc.json", jsonConfig),
+		writeFile(t, dir, "multiproc.toml", tomlConfig),
+	} {
+		f, err := config.Load(path)
+		if err != nil {
+			t.Fatalf("Load(%s): %v", path, err)
+		}
+		if f.Path != path || f.LocalPath != "" {
+			t.Errorf("Expected Path %q and no LocalPath, got %q and %q", path, f.Path, f.LocalPath)
+		}
+
+		cfg := runner.DefaultConfig()
+		cfg.ShowSummary = false
+		f.Apply(&cfg)
+		if !reflect.DeepEqual(cfg.Specs, wantSpecs) {
+			t.Errorf("%s: expected specs %+v, got %+v", path, wantSpecs, cfg.Specs)
+		}
+		if cfg.ShutdownTimeout != 10*time.Second || cfg.MaxLinesPerProc != 200 {
+			t.Errorf("%s: expected timeout 10s and 200 lines, got %v and %d", path, cfg.ShutdownTimeout, cfg.MaxLinesPerProc)
+		}
+		if cfg.Output != renderer.OutputGrouped || cfg.Color != renderer.ColorNever {
+			t.Errorf("%s: expected grouped output without colors, got %v and %v", path, cfg.Output, cfg.Color)
+		}
+		if cfg.IsTTY == nil || *cfg.IsTTY {
+			t.Errorf("%s: expected IsTTY false, got %v", path, cfg.IsTTY)
+		}
+		// Options left out of the file keep their previous values.
+		if cfg.ShowSummary || !cfg.FullScreen || cfg.TickInterval != 100*time.Millisecond {
+			t.Errorf("%s: expected options missing from the file unchanged, got %+v", path, cfg)
+		}
+	}
+}
+
+// TestLoadLocalOverride verifies the local override is merged on top.
+func TestLoadLocalOverride(t *testing.T) {
+	dir := t.TempDir()
+	path := writeFile(t, dir, "multiproc.json", jsonConfig)
+	local := writeFile(t, dir, "multiproc.local.json", `{
+  "color": "always",
+  "processes": [
+    {"name": "test", "args": ["test", "-run", "TestParse", "./..."], "env": {"GOFLAGS": "-v"}},
+    {"name": "lint", "command": "golangci-lint", "args": ["run"]}
+  ]
+}`)
+
+	f, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.LocalPath != local {
+		t.Errorf("Expected LocalPath %q, got %q", local, f.LocalPath)
+	}
+	cfg := runner.DefaultConfig()
+	f.Apply(&cfg)
+
+	want := []engine.ProcessSpec{
+		{Name: "build", Command: "go", Args: []string{"build", "./..."}, Color: "cyan", Dir: filepath.Join(dir, "web"),
+			Watch: []string{"**/*.go"}, WatchIgnore: []string{"*_test.go"}, WatchDebounce: time.Second, WatchClear: true},
+		{Name: "test", Command: "go", Args: []string{"test", "-run", "TestParse", "./..."}, MaxLines: 5000, MaxBytes: 65536,
+			Env: []string{"CGO_ENABLED=0", "GOFLAGS=-v"}, DependsOn: []string{"build"}, Schedule: "*/5 * * * *", ScheduleOverlap: engine.OverlapQueue, RunHistory: 20},
+		{Name: "lint", Command: "golangci-lint", Args: []string{"run"}},
+	}
+	if !reflect.DeepEqual(cfg.Specs, want) {
+		t.Errorf("Expected merged specs %+v, got %+v", want, cfg.Specs)
+	}
+	if cfg.Color != renderer.ColorAlways || cfg.Output != renderer.OutputGrouped {
+		t.Errorf("Expected color from the override and output from the file, got %v and %v", cfg.Color, cfg.Output)
+	}
+
+	if got := config.LocalPath("ci/multiproc.toml"); got != "ci/multiproc.local.toml" {
+		t.Errorf("Unexpected LocalPath: %q", got)
+	}
+}
+
+// TestLoadErrors verifies invalid files are rejected with their path.
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name, content, want string
+	}{
+		{"unknown.json", `{"procesess": []}`, `unknown field "procesess"`},
+		{"enum.json", `{"output": "stacked"}`, `invalid output mode "stacked"`},
+		{"duration.json", `{"shutdown_timeout": "soon"}`, `invalid duration "soon"`},
+		{"command.json", `{"processes": [{"name": "x"}]}`, `process 1 ("x") has no command`},
+		{"duplicate.json", `{"processes": [{"name": "x", "command": "a"}, {"name": "x", "command": "b"}]}`, `duplicate process name "x"`},
+		{"syntax.json", `{"processes": [}`, "invalid character"},
+		{"value.toml", "\n\noutput = grouped", `line 3: invalid value "grouped"`},
+		{"dup.toml", "color = 'never'\ncolor = 'always'", `line 2: duplicate key "color"`},
+		{"string.toml", `log_prefix = "[%s]`, "line 1: unterminated string"},
+		{"array.toml", "[[processes]]\nargs = [1 2]", "line 2: expected ',' or ']' in array"},
+		{"trailing.toml", `color = "never" full_screen = true`, "line 1: expected a new line"},
+		{"type.toml", "max_frame_rate = 'fast'", "cannot unmarshal string"},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		path := writeFile(t, dir, tt.name, tt.content)
+		_, err := config.Load(path)
+		if err == nil || !strings.HasPrefix(err.Error(), path+": ") || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: expected an error containing %q, got %v", tt.name, tt.want, err)
+		}
+	}
+
+	// Errors in the override name the override.
+	path := writeFile(t, dir, "base.json", `{"processes": [{"name": "x", "command": "a"}]}`)
+	local := writeFile(t, dir, "base.local.json", `{"colour": "never"}`)
+	if _, err := config.Load(path); err == nil || !strings.HasPrefix(err.Error(), local+": ") {
+		t.Errorf("Expected an error for %s, got %v", local, err)
+	}
+
+	if _, err := config.Load(filepath.Join(dir, "missing.json")); !os.IsNotExist(err) {
+		t.Errorf("Expected a not-exist error, got %v", err)
+	}
+}
+
+// TestLoadTOML covers the rest of the TOML subset.
+func TestLoadTOML(t *testing.T) {
+	path := writeFile(t, t.TempDir(), "x.toml", `
+"log_prefix" = "\u00bb %s \u00bb\t"
+summary_tail_lines = -1
+
+[[processes]]
+name = 'C:\tools'
+command = "\"quoted\""
+args = []
+
+[[processes]]
+name = "plain"
+command = "sh"
+args = ["a", 'b']
+`)
+	f, err := config.Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := runner.DefaultConfig()
+	f.Apply(&cfg)
+	if cfg.LogPrefix != "» %s »\t" || cfg.SummaryTailLines != -1 {
+		t.Errorf("Unexpected options: %q, %d", cfg.LogPrefix, cfg.SummaryTailLines)
+	}
+	if len(cfg.Specs) != 2 || cfg.Specs[0].Name != `C:\tools` || cfg.Specs[0].Command != `"quoted"` {
+		t.Errorf("Unexpected specs: %+v", cfg.Specs)
+	}
+}
+
+// TestDiscover verifies the discovery order: flag, environment, files in
+// the working directory.
+func TestDiscover(t *testing.T) {
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv(config.EnvVar, "")
+
+	if path, err := config.Discover(""); err != nil || path != "" {
+		t.Errorf("Expected no configuration file, got %q, %v", path, err)
+	}
+
+	writeFile(t, dir, config.DefaultTOMLFile, "")
+	if path, _ := config.Discover(""); path != config.DefaultTOMLFile {
+		t.Errorf("Expected %s, got %q", config.DefaultTOMLFile, path)
+	}
+	writeFile(t, dir, config.DefaultFile, "{}")
+	if path, _ := config.Discover(""); path != config.DefaultFile {
+		t.Errorf("Expected %s before %s, got %q", config.DefaultFile, config.DefaultTOMLFile, path)
+	}
+
+	t.Setenv(config.EnvVar, "from-env.json")
+	if path, _ := config.Discover(""); path != "from-env.json" {
+		t.Errorf("Expected the file from %s, got %q", config.EnvVar, path)
+	}
+	if path, _ := config.Discover("from-flag.json"); path != "from-flag.json" {
+		t.Errorf("Expected the file from the flag, got %q", path)
+	}
+}
+
+// TestParseProcfile verifies Procfile entries become shell commands with
+// foreman's PORT assignment.
+func TestParseProcfile(t *testing.T) {
+	specs, err := config.ParseProcfile(strings.NewReader(`# Processes
+web: bundle exec rails server -p $PORT
+
+worker:   bundle exec sidekiq  
+release_tasks: ./release.sh && echo "done: ok"
+`), 3000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []engine.ProcessSpec{
+		{Name: "web", Command: "sh", Args: []string{"-c", "bundle exec rails server -p $PORT"}, Env: []string{"PORT=3000"}},
+		{Name: "worker", Command: "sh", Args: []string{"-c", "bundle exec sidekiq"}, Env: []string{"PORT=3100"}},
+		{Name: "release_tasks", Command: "sh", Args: []string{"-c", `./release.sh && echo "done: ok"`}, Env: []string{"PORT=3200"}},
+	}
+	if runtime.GOOS != "windows" && !reflect.DeepEqual(specs, want) {
+		t.Errorf("Expected specs %+v, got %+v", want, specs)
+	}
+
+	for _, tt := range []struct{ in, want string }{
+		{"web ./server", `line 1: expected "name: command"`},
+		{"\nweb:", `line 2: process "web" has no command`},
+		{"web: a\nweb: b", `line 2: duplicate process name "web"`},
+		{"my web: a", "line 1: expected"},
+	} {
+		if _, err = config.ParseProcfile(strings.NewReader(tt.in), config.DefaultBasePort); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseProcfile(%q): expected an error containing %q, got %v", tt.in, tt.want, err)
+		}
+	}
+
+	path := writeFile(t, t.TempDir(), "Procfile", "web: a\nweb: b\n")
+	if _, err = config.LoadProcfile(path, config.DefaultBasePort); err == nil || !strings.HasPrefix(err.Error(), path+": line 2: ") {
+		t.Errorf("Expected an error with the path and line, got %v", err)
+	}
+}
+
+// TestParseEnv verifies the .env syntax: export, quoting, escapes, comments
+// and interpolation.
+func TestParseEnv(t *testing.T) {
+	lookup := func(k string) (string, bool) {
+		v, ok := map[string]string{"HOME": "/home/dev", "EMPTY": ""}[k]
+		return v, ok
+	}
+	env, err := config.ParseEnv(strings.NewReader(`# Development settings
+PLAIN=value
+export EXPORTED=1
+	SPACED = padded value   # comment
+HASH=a#b
+EMPTY_VALUE=
+SINGLE='$HOME \n # kept'
+DOUBLE="tab\there \"quoted\" \$HOME"
+MULTI="line 1
+line 2"
+INTERP=$HOME/${PLAIN}/$UNSET/end
+DEFAULT=${UNSET:-fallback} ${EMPTY:-empty} ${PLAIN:-unused}
+DOLLAR=costs $5 or $
+PLAIN=redefined
+LATER=$PLAIN
+CRLF=windows`+"\r\n"), lookup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"PLAIN=value",
+		"EXPORTED=1",
+		"SPACED=padded value",
+		"HASH=a#b",
+		"EMPTY_VALUE=",
+		`SINGLE=$HOME \n # kept`,
+		"DOUBLE=tab\there \"quoted\" $HOME",
+		"MULTI=line 1\nline 2",
+		"INTERP=/home/dev/value//end",
+		"DEFAULT=fallback empty value",
+		"DOLLAR=costs $5 or $",
+		"PLAIN=redefined",
+		"LATER=redefined",
+		"CRLF=windows",
+	}
+	if !slices.Equal(env, want) {
+		t.Errorf("Unexpected variables:\n%q\nwant:\n%q", env, want)
+	}
+
+	for _, tt := range []struct{ in, want string }{
+		{"\nNO_EQUALS", "line 2: expected '=' after NO_EQUALS"},
+		{"1ABC=x", "line 1: expected a variable name"},
+		{`A="unterminated`, `line 1: unterminated "-quoted value`},
+		{"A='x' trailing", `unexpected 't' after quoted value`},
+		{"A=${B", "line 1: unterminated ${"},
+	} {
+		if _, err = config.ParseEnv(strings.NewReader(tt.in), nil); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("ParseEnv(%q): expected an error containing %q, got %v", tt.in, tt.want, err)
+		}
+	}
+}
+
+// TestLoadEnvFiles verifies later files see the variables of earlier files
+// and of the environment.
+func TestLoadEnvFiles(t *testing.T) {
+	t.Setenv("MULTIPROC_TEST_USER", "dev")
+	dir := t.TempDir()
+	first := writeFile(t, dir, ".env", "HOST=localhost\nPORT=5000\n")
+	second := writeFile(t, dir, ".env.local", "PORT=6000\nURL=http://$MULTIPROC_TEST_USER@$HOST:$PORT\n")
+
+	env, err := config.LoadEnvFiles(first, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"HOST=localhost", "PORT=5000", "PORT=6000", "URL=http://dev@localhost:6000"}
+	if !slices.Equal(env, want) {
+		t.Errorf("Expected %q, got %q", want, env)
+	}
+
+	bad := writeFile(t, dir, "bad.env", "A='x")
+	if _, err = config.LoadEnvFiles(first, bad); err == nil || !strings.HasPrefix(err.Error(), bad+": line 1: ") {
+		t.Errorf("Expected an error with the path and line, got %v", err)
+	}
+	if env, err = config.LoadEnvFiles(); err != nil || env != nil {
+		t.Errorf("Expected no variables without files, got %q, %v", env, err)
+	}
+}
+
+// TestCommandSpecs verifies command-line processes get shell commands and
+// names from -p, -names or the command.
+func TestCommandSpecs(t *testing.T) {
+	t.Setenv("SHELL", "/bin/zsh")
+	named, err := config.ParseNamedCommand(" api = go run ./cmd/api ")
+	if err != nil || named != (config.Command{Name: "api", Command: "go run ./cmd/api"}) {
+		t.Errorf("Unexpected named command %+v, %v", named, err)
+	}
+	for _, bad := range []string{"go test", "=go test", "api="} {
+		if _, err = config.ParseNamedCommand(bad); err == nil {
+			t.Errorf("ParseNamedCommand(%q): expected an error", bad)
+		}
+	}
+
+	specs := config.CommandSpecs([]config.Command{
+		named,
+		{Command: "go vet ./..."},
+		{Command: "go test ./..."},
+		{Command: "go test -race ./..."},
+		{Command: "go test ./..."},
+	}, []string{"lint"})
+	var got []string
+	for _, s := range specs {
+		got = append(got, s.Name)
+	}
+	if want := []string{"api", "lint", "go test", "go test (2)", "go test (3)"}; !slices.Equal(got, want) {
+		t.Errorf("Expected names %q, got %q", want, got)
+	}
+	if runtime.GOOS != "windows" && (specs[1].Command != "/bin/zsh" || !slices.Equal(specs[1].Args, []string{"-c", "go vet ./..."})) {
+		t.Errorf("Expected the command to run through $SHELL -c, got %q %q", specs[1].Command, specs[1].Args)
+	}
+}
+
+// TestCommandName verifies names derived from commands.
+func TestCommandName(t *testing.T) {
+	tests := []struct{ command, want string }{
+		{"go vet ./...", "go vet"},
+		{"go test -race ./...", "go test"},
+		{"npm run dev", "npm run dev"},
+		{"npm run build:prod --verbose", "npm run build:prod"},
+		{"docker compose up api db", "docker compose up"},
+		{"./bin/server --port 8080", "server"},
+		{"CGO_ENABLED=0 GOOS=linux go build", "go build"},
+		{`"./bin/api" -v`, "api"},
+		{"sleep 10", "sleep"},
+		{"python -m http.server", "python"},
+		{"  ", ""},
+	}
+	for _, tt := range tests {
+		if got := config.CommandName(tt.command); got != tt.want {
+			t.Errorf("CommandName(%q) = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+}
+
+// TestSelect verifies -only, -group and -skip selection.
+func TestSelect(t *testing.T) {
+	specs := []engine.ProcessSpec{
+		{Name: "api", Group: "backend", Tags: []string{"go"}},
+		{Name: "worker", Group: "backend", Tags: []string{"go", "slow"}},
+		{Name: "web", Tags: []string{"node"}},
+		{Name: "test-unit", Tags: []string{"go"}},
+		{Name: "test-e2e", Tags: []string{"node", "slow"}},
+	}
+	tests := []struct {
+		sel  config.Selection
+		want []string
+	}{
+		{config.Selection{}, []string{"api", "worker", "web", "test-unit", "test-e2e"}},
+		{config.Selection{Only: []string{"test-*"}}, []string{"test-unit", "test-e2e"}},
+		{config.Selection{Only: []string{"node"}}, []string{"web", "test-e2e"}},
+		{config.Selection{Only: []string{"web"}, Groups: []string{"backend"}}, []string{"api", "worker", "web"}},
+		{config.Selection{Groups: []string{"backend"}, Skip: []string{"slow"}}, []string{"api"}},
+		{config.Selection{Skip: []string{"go", "missing"}}, []string{"web", "test-e2e"}},
+		{config.Selection{Only: []string{"?eb"}}, []string{"web"}},
+	}
+	for _, tt := range tests {
+		selected, err := config.Select(specs, tt.sel)
+		if err != nil {
+			t.Errorf("Select(%+v): %v", tt.sel, err)
+			continue
+		}
+		var got []string
+		for _, s := range selected {
+			got = append(got, s.Name)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Select(%+v) = %q, want %q", tt.sel, got, tt.want)
+		}
+	}
+
+	for _, tt := range []struct {
+		sel  config.Selection
+		want string
+	}{
+		{config.Selection{Only: []string{"nope"}}, `no process matches "nope"`},
+		{config.Selection{Groups: []string{"frontend"}}, `no process in group "frontend"`},
+		{config.Selection{Only: []string{"api"}, Skip: []string{"go"}}, "no processes selected"},
+		{config.Selection{Skip: []string{"[a-"}}, `invalid pattern "[a-"`},
+	} {
+		if _, err := config.Select(specs, tt.sel); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Select(%+v): expected an error containing %q, got %v", tt.sel, tt.want, err)
+		}
+	}
+}
+
+// TestSelectDependencies verifies that selecting a process pulls in what it
+// depends on, transitively, unless -skip excludes it.
+func TestSelectDependencies(t *testing.T) {
+	specs := []engine.ProcessSpec{
+		{Name: "gen"},
+		{Name: "build", DependsOn: []string{"gen"}},
+		{Name: "lint"},
+		{Name: "web"},
+		{Name: "test", DependsOn: []string{"build", "missing"}},
+		{Name: "e2e", DependsOn: []string{"test", "web"}},
+	}
+	tests := []struct {
+		sel       config.Selection
+		want      []string
+		dependsOn map[string][]string
+	}{
+		{
+			sel:       config.Selection{Only: []string{"e2e"}},
+			want:      []string{"gen", "build", "web", "test", "e2e"},
+			dependsOn: map[string][]string{"build": {"gen"}, "test": {"build", "missing"}, "e2e": {"test", "web"}},
+		},
+		{
+			// Skipping build drops it and what only it pulled in; test no
+			// longer waits for it.
+			sel:       config.Selection{Only: []string{"test"}, Skip: []string{"build"}},
+			want:      []string{"test"},
+			dependsOn: map[string][]string{"test": {"missing"}},
+		},
+		{
+			sel:       config.Selection{Only: []string{"e2e", "lint"}, Skip: []string{"web", "g*"}},
+			want:      []string{"build", "lint", "test", "e2e"},
+			dependsOn: map[string][]string{"build": {}, "test": {"build", "missing"}, "e2e": {"test"}},
+		},
+	}
+	for _, tt := range tests {
+		selected, err := config.Select(specs, tt.sel)
+		if err != nil {
+			t.Errorf("Select(%+v): %v", tt.sel, err)
+			continue
+		}
+		var got []string
+		for _, s := range selected {
+			got = append(got, s.Name)
+			if want := tt.dependsOn[s.Name]; !slices.Equal(s.DependsOn, want) {
+				t.Errorf("Select(%+v): %s depends on %q, want %q", tt.sel, s.Name, s.DependsOn, want)
+			}
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Select(%+v) = %q, want %q", tt.sel, got, tt.want)
+		}
+	}
+	if specs[1].DependsOn[0] != "gen" || len(specs[4].DependsOn) != 2 {
+		t.Errorf("Select modified the DependsOn of its input: %+v", specs)
+	}
+}
@@ -0,0 +1,284 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// DefaultEnvFile is the .env file the CLI loads when it exists and no
+// other files are given.
+const DefaultEnvFile = ".env"
+
+// ParseEnv reads a .env file and returns its variables as "KEY=value"
+// entries, in order. The format is the one of foreman and dotenv:
+//
+//	# Comments and blank lines are ignored
+//	export DATABASE_URL=postgres://localhost/dev   # "export" is optional
+//	GREETING="hello\nworld"                        # escapes and variables
+//	PATTERN='$not_expanded'                        # taken literally
+//	URL=http://${HOST:-localhost}:$PORT/
+//	CERT="-----BEGIN CERTIFICATE-----
+//	...
+//	-----END CERTIFICATE-----"
+//
+// Values may be unquoted (trailing spaces and " #" comments are removed),
+// single-quoted (taken literally) or double-quoted (with \n, \r, \t, \",
+// \\ and \$ escapes). Quoted values may span lines. In unquoted and
+// double-quoted values, $VAR and ${VAR} are replaced with the variable's
+// value, and ${VAR:-default} with default if the variable is unset or
+// empty. Variables are looked up among those defined earlier in the file,
+// then with lookup (which may be nil); unknown variables are empty.
+func ParseEnv(r io.Reader, lookup func(string) (string, bool)) ([]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	p := &envParser{src: string(data), vars: map[string]string{}, lookup: lookup}
+	return p.parse()
+}
+
+// LoadEnvFiles reads .env files (see ParseEnv) and returns their variables
+// in order. Each file may refer to the variables of the files before it and
+// to the environment of the current process.
+//
+// Example:
+//
+//	env, err := config.LoadEnvFiles(".env", ".env.local")
+//	spec.Env = append(env, spec.Env...)
+func LoadEnvFiles(paths ...string) ([]string, error) {
+	var env []string
+	vars := map[string]string{}
+	lookup := func(k string) (string, bool) {
+		if v, ok := vars[k]; ok {
+			return v, true
+		}
+		return os.LookupEnv(k)
+	}
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		entries, err := ParseEnv(f, lookup)
+		_ = f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		for _, e := range entries {
+			k, v, _ := strings.Cut(e, "=")
+			vars[k] = v
+		}
+		env = append(env, entries...)
+	}
+	return env, nil
+}
+
+// envParser reads a .env file from src.
+type envParser struct {
+	src    string
+	pos    int
+	vars   map[string]string
+	lookup func(string) (string, bool)
+}
+
+func (p *envParser) eof() bool { return p.pos >= len(p.src) }
+
+func (p *envParser) peek() byte { return p.src[p.pos] }
+
+// errorf returns an error at the current line.
+func (p *envParser) errorf(format string, args ...any) error {
+	line := strings.Count(p.src[:min(p.pos, len(p.src))], "\n") + 1
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// skipSpace skips spaces, tabs and carriage returns.
+func (p *envParser) skipSpace() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t' || p.peek() == '\r') {
+		p.pos++
+	}
+}
+
+// skipLine skips the rest of the line, including the new line.
+func (p *envParser) skipLine() {
+	for !p.eof() && p.peek() != '\n' {
+		p.pos++
+	}
+	p.pos++
+}
+
+// parse reads every assignment.
+func (p *envParser) parse() ([]string, error) {
+	var env []string
+	for !p.eof() {
+		p.skipSpace()
+		if p.eof() || p.peek() == '\n' || p.peek() == '#' {
+			p.skipLine()
+			continue
+		}
+
+		if strings.HasPrefix(p.src[p.pos:], "export ") || strings.HasPrefix(p.src[p.pos:], "export\t") {
+			p.pos += len("export")
+			p.skipSpace()
+		}
+		start := p.pos
+		for !p.eof() && isEnvNameChar(p.peek(), p.pos == start) {
+			p.pos++
+		}
+		key := p.src[start:p.pos]
+		if key == "" {
+			return nil, p.errorf("expected a variable name")
+		}
+		p.skipSpace()
+		if p.eof() || p.peek() != '=' {
+			return nil, p.errorf("expected '=' after %s", key)
+		}
+		p.pos++
+		p.skipSpace()
+
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		p.vars[key] = value
+		env = append(env, key+"="+value)
+	}
+	return env, nil
+}
+
+// value reads the value of an assignment and the rest of its line.
+func (p *envParser) value() (string, error) {
+	if p.eof() {
+		return "", nil
+	}
+	var b strings.Builder
+	switch quote := p.peek(); quote {
+	case '\'', '"':
+		p.pos++
+		for {
+			if p.eof() {
+				return "", p.errorf("unterminated %c-quoted value", quote)
+			}
+			c := p.peek()
+			switch {
+			case c == quote:
+				p.pos++
+				p.skipSpace()
+				if !p.eof() && p.peek() != '\n' && p.peek() != '#' {
+					return "", p.errorf("unexpected %q after quoted value", p.peek())
+				}
+				p.skipLine()
+				return b.String(), nil
+			case quote == '"' && c == '\\':
+				p.escape(&b)
+			case quote == '"' && c == '$':
+				if err := p.variable(&b); err != nil {
+					return "", err
+				}
+			default:
+				b.WriteByte(c)
+				p.pos++
+			}
+		}
+	default:
+		for !p.eof() && p.peek() != '\n' {
+			c := p.peek()
+			if c == '#' && p.pos > 0 && (p.src[p.pos-1] == ' ' || p.src[p.pos-1] == '\t') {
+				break
+			}
+			if c == '$' {
+				if err := p.variable(&b); err != nil {
+					return "", err
+				}
+				continue
+			}
+			b.WriteByte(c)
+			p.pos++
+		}
+		p.skipLine()
+		return strings.TrimRight(b.String(), " \t\r"), nil
+	}
+}
+
+// escape reads a backslash escape of a double-quoted value into b. Unknown
+// escapes are kept as they are.
+func (p *envParser) escape(b *strings.Builder) {
+	p.pos++ // backslash
+	if p.eof() {
+		b.WriteByte('\\')
+		return
+	}
+	switch c := p.peek(); c {
+	case 'n':
+		b.WriteByte('\n')
+	case 'r':
+		b.WriteByte('\r')
+	case 't':
+		b.WriteByte('\t')
+	case '"', '\\', '$':
+		b.WriteByte(c)
+	default:
+		b.WriteByte('\\')
+		b.WriteByte(c)
+	}
+	p.pos++
+}
+
+// variable reads a $VAR, ${VAR} or ${VAR:-default} reference into b as
+// the variable's value. A "$" not followed by a name is kept.
+func (p *envParser) variable(b *strings.Builder) error {
+	p.pos++ // $
+	if !p.eof() && p.peek() == '{' {
+		end := strings.IndexByte(p.src[p.pos:], '}')
+		if end < 0 {
+			return p.errorf("unterminated ${")
+		}
+		ref := p.src[p.pos+1 : p.pos+end]
+		p.pos += end + 1
+		name, def, hasDefault := strings.Cut(ref, ":-")
+		if v := p.get(name); v != "" || !hasDefault {
+			b.WriteString(v)
+		} else {
+			b.WriteString(def)
+		}
+		return nil
+	}
+
+	start := p.pos
+	for !p.eof() && isEnvNameChar(p.peek(), p.pos == start) && p.peek() != '.' {
+		p.pos++
+	}
+	if p.pos == start {
+		b.WriteByte('$')
+		return nil
+	}
+	b.WriteString(p.get(p.src[start:p.pos]))
+	return nil
+}
+
+// get returns the value of a variable defined earlier in the file, or
+// found by lookup.
+func (p *envParser) get(name string) string {
+	if v, ok := p.vars[name]; ok {
+		return v
+	}
+	if p.lookup != nil {
+		v, _ := p.lookup(name)
+		return v
+	}
+	return ""
+}
+
+// isEnvNameChar reports whether c may appear in a variable name: letters,
+// "_" and, except first, digits and ".".
+func isEnvNameChar(c byte, first bool) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c == '_':
+		return true
+	case c >= '0' && c <= '9', c == '.':
+		return !first
+	default:
+		return false
+	}
+}
@@ -0,0 +1,107 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/a2y-d5l/multiproc/engine"
+)
+
+// Procfile support, for compatibility with foreman and honcho.
+
+const (
+	// DefaultProcfile is the Procfile the CLI looks for in the working
+	// directory when no configuration file lists processes.
+	DefaultProcfile = "Procfile"
+
+	// DefaultBasePort is the PORT of the first Procfile process when
+	// neither a base port nor the PORT environment variable is given, as
+	// in foreman.
+	DefaultBasePort = 5000
+
+	// PortStep is the difference between the PORT values of consecutive
+	// Procfile processes.
+	PortStep = 100
+)
+
+// ParseProcfile reads a Procfile: one "name: command" line per process,
+// with blank lines and lines starting with "#" ignored. Names consist of
+// letters, digits, "_" and "-".
+//
+// Each command is run through the shell ("sh -c", or "cmd /C" on Windows),
+// so it may use pipes, redirections and variables. As in foreman, process i
+// (from 0) gets PORT=basePort+100×i in its environment:
+//
+//	web: bundle exec rails server -p $PORT   # PORT=5000
+//	worker: bundle exec sidekiq              # PORT=5100
+//
+// Example:
+//
+//	specs, err := config.ParseProcfile(strings.NewReader("web: ./server\n"), config.DefaultBasePort)
+func ParseProcfile(r io.Reader, basePort int) ([]engine.ProcessSpec, error) {
+	entry := regexp.MustCompile(`^([A-Za-z0-9_-]+):\s*(.*)$`)
+
+	var specs []engine.ProcessSpec
+	names := map[string]bool{}
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		m := entry.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("line %d: expected \"name: command\", got %q", n, line)
+		}
+		name, command := m[1], m[2]
+		if command == "" {
+			return nil, fmt.Errorf("line %d: process %q has no command", n, name)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("line %d: duplicate process name %q", n, name)
+		}
+		names[name] = true
+
+		shell, args := shellCommand(command)
+		specs = append(specs, engine.ProcessSpec{
+			Name:    name,
+			Command: shell,
+			Args:    args,
+			Env:     []string{"PORT=" + strconv.Itoa(basePort+PortStep*len(specs))},
+		})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return specs, nil
+}
+
+// LoadProcfile reads the Procfile at path (see ParseProcfile).
+func LoadProcfile(path string, basePort int) ([]engine.ProcessSpec, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	specs, err := ParseProcfile(f, basePort)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return specs, nil
+}
+
+// shellCommand returns the command and arguments that run command through
+// the shell of the platform.
+func shellCommand(command string) (string, []string) {
+	if runtime.GOOS == "windows" {
+		return "cmd", []string{"/C", command}
+	}
+	return "sh", []string{"-c", command}
+}
@@ -0,0 +1,134 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"slices"
+
+	"github.com/a2y-d5l/multiproc/engine"
+)
+
+// Selection picks the processes of a run, e.g., from the -only, -skip and
+// -group flags. Patterns match a process if they match its name as a glob
+// (see path.Match: "api-*", "test-?") or equal one of its tags. The zero
+// value selects every process.
+type Selection struct {
+	// Only selects the processes matching any of the patterns.
+	Only []string
+
+	// Groups selects the processes of any of the groups (ProcessSpec.Group).
+	Groups []string
+
+	// Skip removes the processes matching any of the patterns, whether they
+	// were selected with Only, Groups or by default.
+	Skip []string
+}
+
+// Empty reports whether the selection selects every process.
+func (s Selection) Empty() bool {
+	return len(s.Only) == 0 && len(s.Groups) == 0 && len(s.Skip) == 0
+}
+
+// Select returns the selected processes, in their order. If Only or Groups
+// is set, a process is selected if it matches an Only pattern or is in one
+// of the Groups; otherwise every process is. Then processes matching a Skip
+// pattern are removed, and the processes the remaining ones depend on
+// (ProcessSpec.DependsOn), directly or not, are added unless they match a
+// Skip pattern too. A skipped dependency is dropped from the DependsOn of
+// the selected processes, which then start without waiting for it.
+//
+// An Only pattern or group that matches no process is an error, as is a
+// selection that leaves nothing to run; a Skip pattern that matches nothing
+// is not.
+//
+// Example:
+//
+//	specs, err := config.Select(cfg.Specs, config.Selection{Only: []string{"api-*", "db"}, Skip: []string{"slow"}})
+func Select(specs []engine.ProcessSpec, sel Selection) ([]engine.ProcessSpec, error) {
+	if sel.Empty() {
+		return specs, nil
+	}
+	if err := checkSelection(specs, sel); err != nil {
+		return nil, err
+	}
+
+	chosen := make([]bool, len(specs))
+	var pending []int
+	all := len(sel.Only) == 0 && len(sel.Groups) == 0
+	for i, spec := range specs {
+		picked := all || matchesAny(spec, sel.Only) || spec.Group != "" && slices.Contains(sel.Groups, spec.Group)
+		if picked && !matchesAny(spec, sel.Skip) {
+			chosen[i] = true
+			pending = append(pending, i)
+		}
+	}
+	// Pull in the dependencies of the chosen processes, transitively.
+	for len(pending) > 0 {
+		spec := specs[pending[0]]
+		pending = pending[1:]
+		for _, name := range spec.DependsOn {
+			dep := specIndex(specs, name)
+			if dep >= 0 && !chosen[dep] && !matchesAny(specs[dep], sel.Skip) {
+				chosen[dep] = true
+				pending = append(pending, dep)
+			}
+		}
+	}
+
+	var selected []engine.ProcessSpec
+	for i, spec := range specs {
+		if !chosen[i] {
+			continue
+		}
+		// Unknown names are kept for engine.Engine.Validate to report.
+		spec.DependsOn = slices.DeleteFunc(slices.Clone(spec.DependsOn), func(name string) bool {
+			dep := specIndex(specs, name)
+			return dep >= 0 && !chosen[dep]
+		})
+		selected = append(selected, spec)
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no processes selected")
+	}
+	return selected, nil
+}
+
+// checkSelection reports invalid patterns, and Only patterns and groups
+// that match no process.
+func checkSelection(specs []engine.ProcessSpec, sel Selection) error {
+	for _, pattern := range slices.Concat(sel.Only, sel.Skip) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	for _, pattern := range sel.Only {
+		if !slices.ContainsFunc(specs, func(s engine.ProcessSpec) bool { return matches(s, pattern) }) {
+			return fmt.Errorf("no process matches %q", pattern)
+		}
+	}
+	for _, group := range sel.Groups {
+		if !slices.ContainsFunc(specs, func(s engine.ProcessSpec) bool { return s.Group == group }) {
+			return fmt.Errorf("no process in group %q", group)
+		}
+	}
+	return nil
+}
+
+// specIndex returns the index of the spec called name, or -1.
+func specIndex(specs []engine.ProcessSpec, name string) int {
+	return slices.IndexFunc(specs, func(s engine.ProcessSpec) bool { return s.Name == name })
+}
+
+// matchesAny reports whether spec matches one of the patterns.
+func matchesAny(spec engine.ProcessSpec, patterns []string) bool {
+	return slices.ContainsFunc(patterns, func(p string) bool { return matches(spec, p) })
+}
+
+// matches reports whether the name of spec matches the glob pattern, or
+// one of its tags equals it.
+func matches(spec engine.ProcessSpec, pattern string) bool {
+	if ok, _ := path.Match(pattern, spec.Name); ok {
+		return true
+	}
+	return slices.Contains(spec.Tags, pattern)
+}
@@ -0,0 +1,401 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Lengths of the hexadecimal code points of TOML string escapes.
+const (
+	tomlShortEscapeLen = 4 // \uXXXX
+	tomlLongEscapeLen  = 8 // \UXXXXXXXX
+)
+
+// parseTOML parses the subset of TOML described in the package
+// documentation into a tree of maps (tables), slices (arrays) and strings,
+// int64, float64 and bool values.
+func parseTOML(src string) (map[string]any, error) {
+	p := &tomlParser{src: src}
+	root := map[string]any{}
+	current := root
+	for {
+		p.skipBlank(true)
+		if p.eof() {
+			return root, nil
+		}
+
+		var err error
+		if p.peek() == '[' {
+			current, err = p.header(root)
+		} else {
+			err = p.keyValue(current)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		p.skipBlank(false)
+		if !p.eof() && p.peek() != '\n' {
+			return nil, p.errorf("expected a new line, found %q", p.peek())
+		}
+	}
+}
+
+// tomlParser reads TOML from src.
+type tomlParser struct {
+	src string
+	pos int
+}
+
+func (p *tomlParser) eof() bool { return p.pos >= len(p.src) }
+
+func (p *tomlParser) peek() byte { return p.src[p.pos] }
+
+// errorf returns an error at the current line.
+func (p *tomlParser) errorf(format string, args ...any) error {
+	line := strings.Count(p.src[:min(p.pos, len(p.src))], "\n") + 1
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// skipBlank skips spaces, tabs, carriage returns and comments, and new
+// lines too if newlines is set.
+func (p *tomlParser) skipBlank(newlines bool) {
+	for !p.eof() {
+		switch c := p.peek(); {
+		case c == ' ', c == '\t', c == '\r':
+			p.pos++
+		case c == '\n' && newlines:
+			p.pos++
+		case c == '#':
+			for !p.eof() && p.peek() != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// header reads a [table] or [[array of tables]] header and returns the
+// table that the following keys go into.
+func (p *tomlParser) header(root map[string]any) (map[string]any, error) {
+	array := strings.HasPrefix(p.src[p.pos:], "[[")
+	closing := "]"
+	p.pos++
+	if array {
+		closing = "]]"
+		p.pos++
+	}
+	p.skipBlank(false)
+	keys, err := p.key()
+	if err != nil {
+		return nil, err
+	}
+	p.skipBlank(false)
+	if !strings.HasPrefix(p.src[p.pos:], closing) {
+		return nil, p.errorf("expected %q after table name", closing)
+	}
+	p.pos += len(closing)
+
+	parent, err := p.table(root, keys[:len(keys)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := keys[len(keys)-1]
+	if !array {
+		return p.table(parent, keys[len(keys)-1:])
+	}
+	t := map[string]any{}
+	switch v := parent[last].(type) {
+	case nil:
+		parent[last] = []any{t}
+	case []any:
+		parent[last] = append(v, t)
+	default:
+		return nil, p.errorf("key %q is not an array of tables", last)
+	}
+	return t, nil
+}
+
+// table returns the table at keys below t, creating missing tables. A key
+// holding an array of tables refers to its last table.
+func (p *tomlParser) table(t map[string]any, keys []string) (map[string]any, error) {
+	for _, k := range keys {
+		switch v := t[k].(type) {
+		case nil:
+			sub := map[string]any{}
+			t[k] = sub
+			t = sub
+		case map[string]any:
+			t = v
+		case []any:
+			last, ok := any(nil), false
+			if len(v) > 0 {
+				last = v[len(v)-1]
+			}
+			if t, ok = last.(map[string]any); !ok {
+				return nil, p.errorf("key %q is not a table", k)
+			}
+		default:
+			return nil, p.errorf("key %q is not a table", k)
+		}
+	}
+	return t, nil
+}
+
+// keyValue reads a "key = value" pair into t.
+func (p *tomlParser) keyValue(t map[string]any) error {
+	keys, err := p.key()
+	if err != nil {
+		return err
+	}
+	p.skipBlank(false)
+	if p.eof() || p.peek() != '=' {
+		return p.errorf("expected '=' after key %q", strings.Join(keys, "."))
+	}
+	p.pos++
+	p.skipBlank(false)
+	v, err := p.value()
+	if err != nil {
+		return err
+	}
+
+	parent, err := p.table(t, keys[:len(keys)-1])
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	if _, ok := parent[last]; ok {
+		return p.errorf("duplicate key %q", strings.Join(keys, "."))
+	}
+	parent[last] = v
+	return nil
+}
+
+// key reads a bare, quoted or dotted key.
+func (p *tomlParser) key() ([]string, error) {
+	var keys []string
+	for {
+		p.skipBlank(false)
+		if p.eof() {
+			return nil, p.errorf("expected a key")
+		}
+		var k string
+		var err error
+		switch p.peek() {
+		case '"':
+			k, err = p.basicString()
+		case '\'':
+			k, err = p.literalString()
+		default:
+			start := p.pos
+			for !p.eof() && isBareKeyChar(p.peek()) {
+				p.pos++
+			}
+			if k = p.src[start:p.pos]; k == "" {
+				return nil, p.errorf("expected a key, found %q", p.peek())
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+
+		p.skipBlank(false)
+		if p.eof() || p.peek() != '.' {
+			return keys, nil
+		}
+		p.pos++
+	}
+}
+
+// value reads a string, number, boolean, array or inline table.
+func (p *tomlParser) value() (any, error) {
+	if p.eof() {
+		return nil, p.errorf("expected a value")
+	}
+	switch p.peek() {
+	case '"':
+		if strings.HasPrefix(p.src[p.pos:], `"""`) {
+			return nil, p.errorf("multi-line strings are not supported")
+		}
+		return p.basicString()
+	case '\'':
+		if strings.HasPrefix(p.src[p.pos:], "'''") {
+			return nil, p.errorf("multi-line strings are not supported")
+		}
+		return p.literalString()
+	case '[':
+		return p.array()
+	case '{':
+		return p.inlineTable()
+	}
+
+	start := p.pos
+	for !p.eof() && (isBareKeyChar(p.peek()) || p.peek() == '+' || p.peek() == '.') {
+		p.pos++
+	}
+	tok := p.src[start:p.pos]
+	switch tok {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	if i, err := strconv.ParseInt(tok, 0, 64); err == nil {
+		return i, nil
+	}
+	if f, err := strconv.ParseFloat(strings.ReplaceAll(tok, "_", ""), 64); err == nil {
+		return f, nil
+	}
+	p.pos = start
+	if tok == "" {
+		return nil, p.errorf("expected a value, found %q", p.peek())
+	}
+	return nil, p.errorf("invalid value %q", tok)
+}
+
+// array reads an array, which may span lines and have a trailing comma.
+func (p *tomlParser) array() ([]any, error) {
+	p.pos++ // [
+	arr := []any{}
+	for {
+		p.skipBlank(true)
+		if p.eof() {
+			return nil, p.errorf("unterminated array")
+		}
+		if p.peek() == ']' {
+			p.pos++
+			return arr, nil
+		}
+		v, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, v)
+
+		p.skipBlank(true)
+		if p.eof() {
+			return nil, p.errorf("unterminated array")
+		}
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+		default:
+			return nil, p.errorf("expected ',' or ']' in array, found %q", p.peek())
+		}
+	}
+}
+
+// inlineTable reads an inline table ({ key = value, ... }) on one line.
+func (p *tomlParser) inlineTable() (map[string]any, error) {
+	p.pos++ // {
+	t := map[string]any{}
+	p.skipBlank(false)
+	if !p.eof() && p.peek() == '}' {
+		p.pos++
+		return t, nil
+	}
+	for {
+		if err := p.keyValue(t); err != nil {
+			return nil, err
+		}
+		p.skipBlank(false)
+		if p.eof() {
+			return nil, p.errorf("unterminated inline table")
+		}
+		switch p.peek() {
+		case ',':
+			p.pos++
+			p.skipBlank(false)
+		case '}':
+			p.pos++
+			return t, nil
+		default:
+			return nil, p.errorf("expected ',' or '}' in inline table, found %q", p.peek())
+		}
+	}
+}
+
+// basicString reads a "double-quoted" string with escapes.
+func (p *tomlParser) basicString() (string, error) {
+	p.pos++ // "
+	var b strings.Builder
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", p.errorf("unterminated string")
+		}
+		c := p.peek()
+		p.pos++
+		switch c {
+		case '"':
+			return b.String(), nil
+		case '\\':
+			if err := p.escape(&b); err != nil {
+				return "", err
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+// escape reads the escape sequence after a backslash into b.
+func (p *tomlParser) escape(b *strings.Builder) error {
+	if p.eof() {
+		return p.errorf("unterminated string")
+	}
+	c := p.peek()
+	p.pos++
+	simple := map[byte]byte{'b': '\b', 't': '\t', 'n': '\n', 'f': '\f', 'r': '\r', '"': '"', '\\': '\\'}
+	if r, ok := simple[c]; ok {
+		b.WriteByte(r)
+		return nil
+	}
+
+	var n int
+	switch c {
+	case 'u':
+		n = tomlShortEscapeLen
+	case 'U':
+		n = tomlLongEscapeLen
+	default:
+		return p.errorf("invalid escape \\%c", c)
+	}
+	if p.pos+n > len(p.src) {
+		return p.errorf("invalid escape \\%c", c)
+	}
+	code, err := strconv.ParseUint(p.src[p.pos:p.pos+n], 16, 32)
+	if err != nil || !utf8.ValidRune(rune(code)) {
+		return p.errorf("invalid escape \\%c%s", c, p.src[p.pos:p.pos+n])
+	}
+	p.pos += n
+	b.WriteRune(rune(code))
+	return nil
+}
+
+// literalString reads a 'single-quoted' string, which has no escapes.
+func (p *tomlParser) literalString() (string, error) {
+	p.pos++ // '
+	start := p.pos
+	for !p.eof() && p.peek() != '\'' {
+		if p.peek() == '\n' {
+			return "", p.errorf("unterminated string")
+		}
+		p.pos++
+	}
+	if p.eof() {
+		return "", p.errorf("unterminated string")
+	}
+	s := p.src[start:p.pos]
+	p.pos++
+	return s, nil
+}
+
+// isBareKeyChar reports whether c may appear in a bare key.
+func isBareKeyChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
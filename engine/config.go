@@ -2,6 +2,8 @@ package engine
 
 import (
 	"context"
+	"errors"
+	"sync"
 	"time"
 )
 
@@ -92,6 +94,143 @@ type Engine struct {
 	//
 	// Example: 10*time.Second allows slow processes more time to clean up.
 	ShutdownTimeout time.Duration
+
+	// mu guards run and started.
+	mu sync.Mutex
+
+	// run is the state of the current (or last) Run, used by Restart and Stop.
+	run *runState
+
+	// started is closed by the first Run once run is set (see Started).
+	// It is created on first use.
+	started chan struct{}
+}
+
+// ErrNoRun is returned by Restart and Stop when no run is in progress.
+var ErrNoRun = errors.New("no run in progress")
+
+// ErrNotRunning is returned by Stop when the process has already exited.
+var ErrNotRunning = errors.New("process is not running")
+
+// ErrStopped is reported as the cancellation cause of a process stopped
+// (or restarted) with Stop or Restart.
+var ErrStopped = errors.New("stopped by request")
+
+// ErrRemoved is returned by Restart, Stop, Replace and Remove for a process
+// removed with Remove.
+var ErrRemoved = errors.New("process was removed")
+
+// runState is the supervision state of a Run in progress.
+// specs, procs and active are guarded by Engine.mu.
+type runState struct {
+	ctx     context.Context
+	factory CommandFactory
+	output  chan<- ProcessLine
+
+	// specs are the specs of the run, by index: Engine.Specs, then the
+	// processes added with Add. Replace changes them.
+	specs []ProcessSpec
+	procs []procControl
+
+	// active counts processes that are running or about to be restarted,
+	// and the file watchers and schedulers of processes.
+	active int
+
+	// idle is closed when active drops to zero, ending the run.
+	idle chan struct{}
+}
+
+// procControl is the supervision state of one process.
+type procControl struct {
+	// stop is closed to stop the current instance of the process.
+	stop chan struct{}
+
+	// running is true while an instance is running (or being restarted).
+	running bool
+
+	// stopping is true once stop has been closed.
+	stopping bool
+
+	// restart requests a new instance once the current one has exited.
+	restart bool
+
+	// update requests an update event before the next instance starts.
+	update bool
+
+	// removed is true once the process was removed with Remove.
+	removed bool
+
+	// reason is the Reason of the next restart event, if any.
+	reason string
+
+	// queued requests a scheduled run once the current instance has
+	// exited (OverlapQueue).
+	queued bool
+
+	// detach is closed to stop the file watcher and the scheduler of the
+	// process, if any.
+	detach chan struct{}
+
+	// started is true once an instance has been started, after the
+	// processes it depends on; later instances do not wait for them.
+	started bool
+
+	// exited is closed once the process has exited without a pending
+	// restart for the first time, releasing the processes that depend on
+	// it. exitErr is the error of its latest such exit.
+	exited  chan struct{}
+	exitErr error
+}
+
+// newProcControl returns the state of a process that is about to start.
+func newProcControl() procControl {
+	return procControl{stop: make(chan struct{}), running: true, exited: make(chan struct{})}
+}
+
+// settled reports whether p.exited is closed. The caller must hold
+// Engine.mu.
+func (p *procControl) settled() bool {
+	select {
+	case <-p.exited:
+		return true
+	default:
+		return false
+	}
+}
+
+// settle records err as the exit error of p, and closes p.exited unless it
+// is already closed. The caller must hold Engine.mu.
+func (p *procControl) settle(err error) {
+	p.exitErr = err
+	if !p.settled() {
+		close(p.exited)
+	}
+}
+
+// rearm prepares p for a new instance of the process. A pending update or
+// restart reason, the file watcher and the scheduler are kept.
+func (p *procControl) rearm() {
+	p.stop = make(chan struct{})
+	p.running = true
+	p.stopping = false
+	p.restart = false
+}
+
+// release drops one of the reasons counted by active, and ends the run if
+// it was the last. The caller must hold Engine.mu.
+func (run *runState) release() {
+	run.active--
+	if run.active == 0 {
+		close(run.idle)
+	}
+}
+
+// requestStop closes p.stop once. The caller must hold Engine.mu.
+func (p *procControl) requestStop() {
+	if !p.stopping {
+		p.stopping = true
+		close(p.stop)
+	}
 }
 
 // New creates a new Engine with the given specs and optional shutdown timeout.
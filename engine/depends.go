@@ -0,0 +1,178 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ErrDependencyFailed is reported, wrapped, as the error of a process that
+// was not started because one of the processes it depends on failed.
+var ErrDependencyFailed = errors.New("dependency failed")
+
+// checkDependsOn reports the DependsOn entries of specs[index] that name
+// no process or the process itself, and a dependency cycle through it.
+func checkDependsOn(specs []ProcessSpec, index int) []error {
+	var problems []error
+	spec := specs[index]
+	for _, name := range spec.DependsOn {
+		switch {
+		case name == spec.Name:
+			problems = append(problems, errors.New("depends on itself"))
+		case specIndex(specs, name) < 0:
+			problems = append(problems, fmt.Errorf("depends on unknown process %q", name))
+		}
+	}
+	if cycle := dependencyCycle(specs, index); cycle != nil {
+		problems = append(problems, fmt.Errorf("dependency cycle: %s", strings.Join(cycle, " -> ")))
+	}
+	return problems
+}
+
+// dependencyCycle returns the names of a cycle of dependencies that starts
+// and ends at specs[index], e.g., [api db api], or nil if there is none.
+// A process depending on itself is not reported as a cycle.
+func dependencyCycle(specs []ProcessSpec, index int) []string {
+	visited := make([]bool, len(specs))
+	var path []string
+	var visit func(i int) bool
+	visit = func(i int) bool {
+		path = append(path, specs[i].Name)
+		for _, name := range specs[i].DependsOn {
+			dep := specIndex(specs, name)
+			if dep == index && i != index {
+				path = append(path, name)
+				return true
+			}
+			if dep < 0 || dep == index || visited[dep] {
+				continue
+			}
+			visited[dep] = true
+			if visit(dep) {
+				return true
+			}
+		}
+		path = path[:len(path)-1]
+		return false
+	}
+	if visit(index) {
+		return path
+	}
+	return nil
+}
+
+// Waves returns the indexes of specs grouped in the order Run starts them:
+// the processes of the first wave depend on no other process, and those of
+// each next wave on processes of earlier waves, at least one of them in
+// the wave just before. Within a wave, indexes are in spec order.
+// DependsOn entries that name no other process are ignored.
+//
+// Returns an error for a dependency cycle, which Validate reports in
+// detail.
+//
+// Example:
+//
+//	waves, err := engine.Waves(specs) // build, then test, then deploy: [[0] [1] [2]]
+func Waves(specs []ProcessSpec) ([][]int, error) {
+	wave := make([]int, len(specs))
+	for i := range wave {
+		wave[i] = -1
+	}
+	var waves [][]int
+	for placed := 0; placed < len(specs); {
+		var next []int
+		for i, spec := range specs {
+			if wave[i] >= 0 {
+				continue
+			}
+			ready := true
+			for _, name := range spec.DependsOn {
+				dep := specIndex(specs, name)
+				if dep >= 0 && dep != i && wave[dep] < 0 {
+					ready = false
+					break
+				}
+			}
+			if ready {
+				next = append(next, i)
+			}
+		}
+		if len(next) == 0 {
+			var names []string
+			for i, spec := range specs {
+				if wave[i] < 0 {
+					names = append(names, spec.Name)
+				}
+			}
+			return nil, fmt.Errorf("dependency cycle among %s", strings.Join(names, ", "))
+		}
+		for _, i := range next {
+			wave[i] = len(waves)
+		}
+		waves = append(waves, next)
+		placed += len(next)
+	}
+	return waves, nil
+}
+
+// specIndex returns the index of the spec called name, or -1.
+func specIndex(specs []ProcessSpec, name string) int {
+	return slices.IndexFunc(specs, func(s ProcessSpec) bool { return s.Name == name })
+}
+
+// awaitDependencies waits until the processes that process idx depends on
+// have exited and are not restarting. It returns an error wrapping
+// ErrDependencyFailed if one of them failed, ErrStopped if stop is closed
+// first, and the cancellation cause if the run is cancelled first.
+// Dependencies that are not part of the run, or were removed, are not
+// waited for.
+func (eng *Engine) awaitDependencies(run *runState, idx int, spec ProcessSpec, stop <-chan struct{}) error {
+	var waiting []string
+	eng.mu.Lock()
+	for _, name := range spec.DependsOn {
+		if dep := specIndex(run.specs, name); dep >= 0 && dep != idx && !run.procs[dep].removed && !run.procs[dep].settled() {
+			waiting = append(waiting, name)
+		}
+	}
+	eng.mu.Unlock()
+	if len(waiting) == 0 {
+		return nil
+	}
+
+	run.output <- ProcessLine{
+		Index:  idx,
+		Line:   "[waiting for " + strings.Join(waiting, ", ") + "]",
+		Stream: StreamSystem,
+		Time:   time.Now(),
+	}
+	for _, name := range waiting {
+		eng.mu.Lock()
+		dep := specIndex(run.specs, name)
+		if dep < 0 {
+			eng.mu.Unlock()
+			continue
+		}
+		exited := run.procs[dep].exited
+		eng.mu.Unlock()
+
+		select {
+		case <-exited:
+		case <-stop:
+			return ErrStopped
+		case <-run.ctx.Done():
+			return fmt.Errorf("not started: %w", context.Cause(run.ctx))
+		}
+
+		eng.mu.Lock()
+		removed, err := run.procs[dep].removed, run.procs[dep].exitErr
+		eng.mu.Unlock()
+		if err != nil && !removed {
+			// The exit code of the dependency is not this process's.
+			return fmt.Errorf("%w: %s: %v", ErrDependencyFailed, name, err)
+		}
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"slices"
 	"strings"
 	"sync"
 	"syscall"
@@ -78,18 +79,361 @@ func (eng *Engine) Run(ctx context.Context, output chan<- ProcessLine) {
 		factory = DefaultCommandFactory
 	}
 
-	var wg sync.WaitGroup
-	for i, spec := range eng.Specs {
-		wg.Add(1)
-		go eng.runProcess(ctx, i, spec, factory, output, &wg)
+	run := &runState{
+		ctx:     ctx,
+		factory: factory,
+		output:  output,
+		specs:   slices.Clone(eng.Specs),
+		procs:   make([]procControl, len(eng.Specs)),
+		active:  len(eng.Specs),
+		idle:    make(chan struct{}),
 	}
+	for i := range run.procs {
+		run.procs[i] = newProcControl()
+	}
+	if run.active == 0 {
+		close(run.idle)
+	}
+
+	eng.mu.Lock()
+	eng.run = run
+	select {
+	case <-eng.startedChan():
+	default:
+		close(eng.started)
+	}
+	for i := range run.procs {
+		eng.startTriggers(run, i)
+	}
+	eng.mu.Unlock()
+
+	for i := range eng.Specs {
+		go eng.supervise(run, i, false)
+	}
+
+	<-run.idle
+}
+
+// Started returns a channel that is closed once Run has started: from then
+// on, Restart, Stop, Add, Replace and Remove act on the run instead of
+// returning ErrNoRun. Goroutines that control the run from its start wait
+// for it rather than racing with Run.
+//
+// Example:
+//
+//	go eng.Run(ctx, output)
+//	<-eng.Started()
+//	idx, err := eng.Add(spec)
+func (eng *Engine) Started() <-chan struct{} {
+	eng.mu.Lock()
+	defer eng.mu.Unlock()
+	return eng.startedChan()
+}
+
+// startedChan returns eng.started, creating it if needed. The caller must
+// hold eng.mu.
+func (eng *Engine) startedChan() chan struct{} {
+	if eng.started == nil {
+		eng.started = make(chan struct{})
+	}
+	return eng.started
+}
+
+// Restart restarts process index during Run. A running process is stopped
+// first (SIGTERM → ShutdownTimeout → SIGKILL); a finished one is started
+// again, as long as the run is still in progress (at least one other
+// process is running).
+//
+// The restart is asynchronous: Restart returns immediately, and the output
+// channel receives an IsRestart event followed by the events of the new
+// instance.
+//
+// Returns an error if index is out of range, if no run is in progress, or
+// if the run is being cancelled.
+//
+// Example:
+//
+//	if err := eng.Restart(1); err != nil {
+//	    log.Printf("restart: %v", err)
+//	}
+func (eng *Engine) Restart(index int) error {
+	eng.mu.Lock()
+	defer eng.mu.Unlock()
+
+	run, err := eng.activeRun(index)
+	if err != nil {
+		return err
+	}
+	run.procs[index].reason = ""
+	eng.restart(run, index)
+	return nil
+}
+
+// restart stops process index to run it again, or starts it again if it
+// has exited. A pending update is kept. The caller must hold eng.mu.
+func (eng *Engine) restart(run *runState, index int) {
+	p := &run.procs[index]
+	if p.running {
+		p.restart = true
+		p.requestStop()
+		return
+	}
+
+	p.rearm()
+	run.active++
+	go eng.supervise(run, index, true)
+}
+
+// startTriggers starts the file watcher and the scheduler of process
+// index, if its spec has Watch patterns or a valid Schedule. They hold the
+// run open until they are stopped with stopTriggers or the run is
+// cancelled. The caller must hold eng.mu.
+func (eng *Engine) startTriggers(run *runState, index int) {
+	spec := run.specs[index]
+	var sched Schedule
+	if spec.Schedule != "" {
+		sched, _ = ParseSchedule(spec.Schedule)
+	}
+	if len(spec.Watch) == 0 && sched == nil {
+		return
+	}
+
+	detach := make(chan struct{})
+	run.procs[index].detach = detach
+	if len(spec.Watch) > 0 {
+		debounce := spec.WatchDebounce
+		if debounce <= 0 {
+			debounce = DefaultWatchDebounce
+		}
+		run.active++
+		go eng.watch(run, index, newWatchSet(spec), debounce, detach)
+	}
+	if sched != nil {
+		run.active++
+		go eng.schedule(run, index, sched, spec.ScheduleOverlap, detach)
+	}
+}
+
+// stopTriggers stops the file watcher and the scheduler of process index,
+// if any. The caller must hold Engine.mu.
+func (run *runState) stopTriggers(index int) {
+	p := &run.procs[index]
+	if p.detach != nil {
+		close(p.detach)
+		p.detach = nil
+	}
+}
+
+// Stop gracefully stops process index during Run (SIGTERM →
+// ShutdownTimeout → SIGKILL), without affecting the other processes.
+// The process emits its completion event as usual; a pending restart or
+// queued scheduled run is cancelled. A scheduled process still runs again
+// at its next scheduled time.
+//
+// Returns ErrNotRunning if the process has already exited, and an error
+// if index is out of range or no run is in progress.
+func (eng *Engine) Stop(index int) error {
+	eng.mu.Lock()
+	defer eng.mu.Unlock()
+
+	run, err := eng.activeRun(index)
+	if err != nil {
+		return err
+	}
+	p := &run.procs[index]
+	if !p.running {
+		return ErrNotRunning
+	}
+	p.restart, p.reason, p.queued = false, "", false
+	p.requestStop()
+	return nil
+}
+
+// Add starts a new process during Run, e.g., one added to a reloaded
+// configuration. It gets the next free index, which is returned; the
+// output channel receives an update event (IsUpdate=true, with spec)
+// followed by the events of the process. Engine.Specs is not modified.
+//
+// Returns ErrNoRun if no run is in progress, and an error if the run is
+// being cancelled.
+//
+// Example:
+//
+//	idx, err := eng.Add(engine.ProcessSpec{Name: "worker", Command: "./worker"})
+func (eng *Engine) Add(spec ProcessSpec) (int, error) {
+	eng.mu.Lock()
+	defer eng.mu.Unlock()
+
+	run, err := eng.currentRun()
+	if err != nil {
+		return 0, err
+	}
+	index := len(run.procs)
+	run.specs = append(run.specs, spec)
+	p := newProcControl()
+	p.update = true
+	run.procs = append(run.procs, p)
+	run.active++
+	eng.startTriggers(run, index)
+	go eng.supervise(run, index, false)
+	return index, nil
+}
+
+// Replace changes the spec of process index during Run and restarts it
+// with the new spec, as Restart does: a running process is stopped first,
+// a finished one is started again. The output channel receives an update
+// event (IsUpdate=true, with spec) before the restart event.
+// Engine.Specs is not modified.
+//
+// Returns ErrRemoved for a removed process, and the errors of Restart.
+func (eng *Engine) Replace(index int, spec ProcessSpec) error {
+	eng.mu.Lock()
+	defer eng.mu.Unlock()
+
+	run, err := eng.activeRun(index)
+	if err != nil {
+		return err
+	}
+	run.specs[index] = spec
+	run.procs[index].update = true
+	run.procs[index].reason = ""
+	// Watch and schedule the process as the new spec says.
+	run.stopTriggers(index)
+	eng.startTriggers(run, index)
+	eng.restart(run, index)
+	return nil
+}
+
+// Remove removes process index from the run in progress: the output
+// channel receives a removal event (IsRemoved=true), then a running
+// process is stopped like with Stop, and is never restarted. Its index is
+// not reused. The run ends as usual once no process is left running.
+//
+// Returns ErrRemoved if the process was already removed, and an error if
+// index is out of range or no run is in progress.
+func (eng *Engine) Remove(index int) error {
+	eng.mu.Lock()
+	run, err := eng.activeRun(index)
+	if err != nil {
+		eng.mu.Unlock()
+		return err
+	}
+	run.procs[index].removed = true
+	// Hold the run open while the event is emitted without the lock.
+	run.active++
+	eng.mu.Unlock()
+
+	run.output <- ProcessLine{Index: index, IsRemoved: true, Time: time.Now()}
+
+	eng.mu.Lock()
+	defer eng.mu.Unlock()
+	p := &run.procs[index]
+	p.restart, p.update, p.reason, p.queued = false, false, "", false
+	run.stopTriggers(index)
+	if p.running {
+		p.requestStop()
+	}
+	run.release()
+	return nil
+}
+
+// activeRun returns the run in progress after validating index, which
+// must not be removed. The caller must hold eng.mu.
+func (eng *Engine) activeRun(index int) (*runState, error) {
+	run, err := eng.currentRun()
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 || index >= len(run.procs) {
+		return nil, fmt.Errorf("process index %d out of range [0, %d)", index, len(run.procs))
+	}
+	if run.procs[index].removed {
+		return nil, ErrRemoved
+	}
+	return run, nil
+}
 
-	wg.Wait()
+// currentRun returns the run in progress, unless it is being cancelled.
+// The caller must hold eng.mu.
+func (eng *Engine) currentRun() (*runState, error) {
+	run := eng.run
+	if run == nil || run.active == 0 {
+		return nil, ErrNoRun
+	}
+	if err := run.ctx.Err(); err != nil {
+		return nil, fmt.Errorf("run is shutting down: %w", context.Cause(run.ctx))
+	}
+	return run, nil
+}
+
+// supervise runs process idx, and runs it again for as long as restarts
+// are requested. When restarted is true, an IsRestart event is emitted
+// before the first instance starts. Until an instance has started, each
+// one waits for the processes it depends on (see awaitDependencies), and
+// does not start if one of them fails; a restart requested while waiting
+// waits again. Each instance runs the current spec of the process,
+// preceded by an IsUpdate event if it changed.
+func (eng *Engine) supervise(run *runState, idx int, restarted bool) {
+	for {
+		eng.mu.Lock()
+		p := &run.procs[idx]
+		stop, spec, update, reason, started := p.stop, run.specs[idx], p.update, p.reason, p.started
+		p.update, p.reason = false, ""
+		eng.mu.Unlock()
+
+		if update {
+			run.output <- ProcessLine{Index: idx, IsUpdate: true, Spec: spec, Time: time.Now()}
+		}
+		if restarted {
+			run.output <- ProcessLine{
+				Index:       idx,
+				IsRestart:   true,
+				Reason:      reason,
+				ClearOutput: reason != "" && spec.WatchClear,
+				Time:        time.Now(),
+			}
+		}
+		var err error
+		if !started {
+			err = eng.awaitDependencies(run, idx, spec, stop)
+		}
+		if err != nil {
+			run.output <- ProcessLine{
+				Index:      idx,
+				IsComplete: true,
+				Err:        err,
+				Time:       time.Now(),
+				Canceled:   run.ctx.Err() != nil,
+			}
+		} else {
+			started = true
+			err = eng.runProcess(run.ctx, idx, spec, run.factory, run.output, stop)
+		}
+
+		eng.mu.Lock()
+		// Add may have grown run.procs meanwhile.
+		p = &run.procs[idx]
+		p.started = started
+		if p.queued && !p.restart {
+			p.restart, p.reason = true, reasonSchedule
+		}
+		p.queued = false
+		if !p.restart || run.ctx.Err() != nil {
+			p.settle(err)
+			p.running = false
+			run.release()
+			eng.mu.Unlock()
+			return
+		}
+		p.rearm()
+		eng.mu.Unlock()
+		restarted = true
+	}
 }
 
 // streamReader reads from a pipe line-by-line and emits ProcessLine events.
 // This is a helper function for runProcess to reduce complexity.
-func streamReader(scanner *bufio.Scanner, idx int, output chan<- ProcessLine, wg *sync.WaitGroup) {
+func streamReader(scanner *bufio.Scanner, idx int, stream Stream, output chan<- ProcessLine, wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	// Increase buffer size for long lines.
@@ -103,6 +447,8 @@ func streamReader(scanner *bufio.Scanner, idx int, output chan<- ProcessLine, wg
 		output <- ProcessLine{
 			Index:      idx,
 			Line:       line,
+			Stream:     stream,
+			Time:       time.Now(),
 			IsComplete: false,
 		}
 	}
@@ -111,25 +457,24 @@ func streamReader(scanner *bufio.Scanner, idx int, output chan<- ProcessLine, wg
 		output <- ProcessLine{
 			Index:      idx,
 			Line:       fmt.Sprintf("[stream error: %v]", err),
+			Stream:     StreamSystem,
+			Time:       time.Now(),
 			IsComplete: false,
 		}
 	}
 }
 
 // handleGracefulShutdown manages the graceful shutdown sequence for a process.
-// Returns true if handled shutdown, false if process completed normally.
+// Returns the exit error if the process completed on its own, else the
+// cause of the shutdown.
 func (eng *Engine) handleGracefulShutdown(
 	ctx context.Context,
 	idx int,
 	cmd Command,
 	done <-chan error,
+	stop <-chan struct{},
 	output chan<- ProcessLine,
-) bool {
-	shutdownTimeout := eng.ShutdownTimeout
-	if shutdownTimeout <= 0 {
-		shutdownTimeout = defaultShutdownTimeout
-	}
-
+) error {
 	select {
 	case waitErr := <-done:
 		// Process completed normally before cancellation.
@@ -137,71 +482,113 @@ func (eng *Engine) handleGracefulShutdown(
 			Index:      idx,
 			IsComplete: true,
 			Err:        waitErr,
+			Time:       time.Now(),
 		}
-		return false
+		return waitErr
 
 	case <-ctx.Done():
 		// Context cancelled - initiate graceful shutdown.
-		cause := context.Cause(ctx)
-		if cause != nil && !errors.Is(cause, context.Canceled) {
-			output <- ProcessLine{
-				Index: idx,
-				Line:  fmt.Sprintf("[cancellation: %v]", cause),
-			}
+		eng.shutdownProcess(idx, cmd, done, context.Cause(ctx), output)
+		return context.Cause(ctx)
+
+	case <-stop:
+		// Stopped (or restarted) on request - same shutdown sequence.
+		eng.shutdownProcess(idx, cmd, done, ErrStopped, output)
+		return ErrStopped
+	}
+}
+
+// shutdownProcess stops a running process gracefully (SIGTERM, then SIGKILL
+// after ShutdownTimeout) and emits its completion event, marked Canceled
+// unless cause is ErrStopped. cause is reported as a status line unless it
+// is a plain context cancellation.
+func (eng *Engine) shutdownProcess(
+	idx int,
+	cmd Command,
+	done <-chan error,
+	cause error,
+	output chan<- ProcessLine,
+) {
+	canceled := !errors.Is(cause, ErrStopped)
+
+	shutdownTimeout := eng.ShutdownTimeout
+	if shutdownTimeout <= 0 {
+		shutdownTimeout = defaultShutdownTimeout
+	}
+
+	if cause != nil && !errors.Is(cause, context.Canceled) {
+		output <- ProcessLine{
+			Index:  idx,
+			Line:   fmt.Sprintf("[cancellation: %v]", cause),
+			Stream: StreamSystem,
+			Time:   time.Now(),
+		}
+	}
+
+	// Try graceful termination with SIGTERM first.
+	proc := cmd.Process()
+	if proc != nil {
+		output <- ProcessLine{
+			Index:  idx,
+			Line:   "[sending SIGTERM for graceful shutdown...]",
+			Stream: StreamSystem,
+			Time:   time.Now(),
 		}
+		_ = proc.Signal(syscall.SIGTERM)
 
-		// Try graceful termination with SIGTERM first.
-		proc := cmd.Process()
-		if proc != nil {
+		// Wait for graceful shutdown with timeout.
+		select {
+		case waitErr := <-done:
+			output <- ProcessLine{
+				Index:  idx,
+				Line:   "[gracefully terminated]",
+				Stream: StreamSystem,
+				Time:   time.Now(),
+			}
 			output <- ProcessLine{
-				Index: idx,
-				Line:  "[sending SIGTERM for graceful shutdown...]",
+				Index:      idx,
+				IsComplete: true,
+				Err:        waitErr,
+				Time:       time.Now(),
+				Canceled:   canceled,
 			}
-			_ = proc.Signal(syscall.SIGTERM)
-
-			// Wait for graceful shutdown with timeout.
-			select {
-			case waitErr := <-done:
-				output <- ProcessLine{
-					Index: idx,
-					Line:  "[gracefully terminated]",
-				}
-				output <- ProcessLine{
-					Index:      idx,
-					IsComplete: true,
-					Err:        waitErr,
-				}
-
-			case <-time.After(shutdownTimeout):
-				// Timeout exceeded, force kill.
-				output <- ProcessLine{
-					Index: idx,
-					Line:  fmt.Sprintf("[graceful shutdown timeout (%v), force killing...]", shutdownTimeout),
-				}
-				_ = proc.Kill()
-
-				// Wait for kill to complete.
-				waitErr := <-done
-				output <- ProcessLine{
-					Index: idx,
-					Line:  "[force killed]",
-				}
-				output <- ProcessLine{
-					Index:      idx,
-					IsComplete: true,
-					Err:        waitErr,
-				}
+
+		case <-time.After(shutdownTimeout):
+			// Timeout exceeded, force kill.
+			output <- ProcessLine{
+				Index:  idx,
+				Line:   fmt.Sprintf("[graceful shutdown timeout (%v), force killing...]", shutdownTimeout),
+				Stream: StreamSystem,
+				Time:   time.Now(),
 			}
-		} else {
-			// Process already exited, just emit the done event.
+			_ = proc.Kill()
+
+			// Wait for kill to complete.
 			waitErr := <-done
+			output <- ProcessLine{
+				Index:  idx,
+				Line:   "[force killed]",
+				Stream: StreamSystem,
+				Time:   time.Now(),
+			}
 			output <- ProcessLine{
 				Index:      idx,
 				IsComplete: true,
 				Err:        waitErr,
+				Time:       time.Now(),
+				Canceled:   canceled,
 			}
 		}
-		return true
+	} else {
+		// Process already exited, just emit the done event.
+		waitErr := <-done
+		output <- ProcessLine{
+			Index:      idx,
+			IsComplete: true,
+			Err:        waitErr,
+			Time:       time.Now(),
+			Canceled:   canceled,
+		}
 	}
 }
 
@@ -218,6 +605,7 @@ func (eng *Engine) handleGracefulShutdown(
 //  7. Emit final completion event
 //
 // Error handling:
+//   - Cancelled before start: Emit completion event with error, marked Canceled
 //   - Command creation errors: Emit completion event with error
 //   - Pipe setup errors: Emit completion event with error
 //   - Start errors: Emit completion event with error
@@ -230,61 +618,83 @@ func (eng *Engine) handleGracefulShutdown(
 //  3. If timeout expires, send SIGKILL
 //  4. Emit status messages at each step
 //
+// Closing stop shuts the process down like a cancellation, but only this one.
+//
 // This function always emits exactly one completion event, even if errors occur.
+// It returns nil if the process exited successfully on its own, else the
+// error of the completion event or the cause of the shutdown.
 func (eng *Engine) runProcess(
 	ctx context.Context,
 	idx int,
 	spec ProcessSpec,
 	factory CommandFactory,
 	output chan<- ProcessLine,
-	wg *sync.WaitGroup,
-) {
-	defer wg.Done()
+	stop <-chan struct{},
+) error {
+	if ctx.Err() != nil {
+		err := fmt.Errorf("not started: %w", context.Cause(ctx))
+		output <- ProcessLine{
+			Index:      idx,
+			IsComplete: true,
+			Err:        err,
+			Time:       time.Now(),
+			Canceled:   true,
+		}
+		return err
+	}
 
 	cmd, err := factory(ctx, spec)
 	if err != nil {
+		err = fmt.Errorf("create command: %w", err)
 		output <- ProcessLine{
 			Index:      idx,
 			IsComplete: true,
-			Err:        fmt.Errorf("create command: %w", err),
+			Err:        err,
+			Time:       time.Now(),
 		}
-		return
+		return err
 	}
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
+		err = fmt.Errorf("stdout pipe: %w", err)
 		output <- ProcessLine{
 			Index:      idx,
 			IsComplete: true,
-			Err:        fmt.Errorf("stdout pipe: %w", err),
+			Err:        err,
+			Time:       time.Now(),
 		}
-		return
+		return err
 	}
 
 	stderr, err := cmd.StderrPipe()
 	if err != nil {
+		err = fmt.Errorf("stderr pipe: %w", err)
 		output <- ProcessLine{
 			Index:      idx,
 			IsComplete: true,
-			Err:        fmt.Errorf("stderr pipe: %w", err),
+			Err:        err,
+			Time:       time.Now(),
 		}
-		return
+		return err
 	}
 
 	if startErr := cmd.Start(); startErr != nil {
+		err = fmt.Errorf("start: %w", startErr)
 		output <- ProcessLine{
 			Index:      idx,
 			IsComplete: true,
-			Err:        fmt.Errorf("start: %w", startErr),
+			Err:        err,
+			Time:       time.Now(),
 		}
-		return
+		return err
 	}
 
 	var streamsWG sync.WaitGroup
 	streamsWG.Add(streamGoRoutines)
 
-	go streamReader(bufio.NewScanner(stdout), idx, output, &streamsWG)
-	go streamReader(bufio.NewScanner(stderr), idx, output, &streamsWG)
+	go streamReader(bufio.NewScanner(stdout), idx, StreamStdout, output, &streamsWG)
+	go streamReader(bufio.NewScanner(stderr), idx, StreamStderr, output, &streamsWG)
 
 	// Monitor for process completion and context cancellation concurrently.
 	done := make(chan error, 1)
@@ -293,5 +703,5 @@ func (eng *Engine) runProcess(
 		done <- cmd.Wait()
 	}()
 
-	eng.handleGracefulShutdown(ctx, idx, cmd, done, output)
+	return eng.handleGracefulShutdown(ctx, idx, cmd, done, stop, output)
 }
@@ -5,6 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"syscall"
@@ -312,6 +315,43 @@ func TestEngineCancellationStopsStreaming(t *testing.T) {
 	}
 }
 
+// TestEngineCanceledCompletion verifies completion events are marked Canceled
+// when the run is cancelled, and that processes are not started afterwards.
+func TestEngineCanceledCompletion(t *testing.T) {
+	// Cancelled before the start: the factory is never called.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	factory := func(_ context.Context, _ engine.ProcessSpec) (engine.Command, error) {
+		t.Error("Factory called after cancellation")
+		return nil, errors.New("unexpected")
+	}
+	eng := engine.New([]engine.ProcessSpec{{Name: "late", Command: "mock"}}, 50*time.Millisecond).
+		WithCommandFactory(factory)
+	output := make(chan engine.ProcessLine, 10)
+	go eng.Run(ctx, output)
+	for ev := range output {
+		if !ev.IsComplete || !ev.Canceled || ev.Err == nil || !strings.Contains(ev.Err.Error(), "not started") {
+			t.Errorf("Expected a cancelled 'not started' completion, got %+v", ev)
+		}
+	}
+
+	// Cancelled while running.
+	ctx, cancel = context.WithCancel(context.Background())
+	mockCmd := NewMockCommand(engine.ProcessSpec{Name: "sleeper"}).WithSleep(100 * time.Millisecond)
+	eng = engine.New([]engine.ProcessSpec{{Name: "sleeper", Command: "mock"}}, 50*time.Millisecond).
+		WithCommandFactory(func(_ context.Context, _ engine.ProcessSpec) (engine.Command, error) {
+			return mockCmd, nil
+		})
+	output = make(chan engine.ProcessLine, 10)
+	go eng.Run(ctx, output)
+	time.AfterFunc(20*time.Millisecond, cancel)
+	for ev := range output {
+		if ev.IsComplete && !ev.Canceled {
+			t.Errorf("Expected the completion to be marked Canceled, got %+v", ev)
+		}
+	}
+}
+
 // TestEngineErrorHandlingPipeFailures verifies error handling for pipe failures.
 //
 //nolint:gocognit // Test complexity is acceptable for comprehensive error coverage
@@ -562,6 +602,42 @@ func TestEngineStdoutAndStderrInterleaved(t *testing.T) {
 	}
 }
 
+// TestEngineStreamTagging verifies that line events carry their source stream and a timestamp.
+func TestEngineStreamTagging(t *testing.T) {
+	ctx := context.Background()
+
+	mockCmd := NewMockCommand(engine.ProcessSpec{Name: "test"}).
+		WithStdout("out").
+		WithStderr("err")
+
+	factory := func(_ context.Context, _ engine.ProcessSpec) (engine.Command, error) {
+		return mockCmd, nil
+	}
+
+	eng := engine.New([]engine.ProcessSpec{{Name: "test", Command: "mock"}}, 5*time.Second).
+		WithCommandFactory(factory)
+
+	output := make(chan engine.ProcessLine, 10)
+	go eng.Run(ctx, output)
+
+	streams := make(map[string]engine.Stream)
+	for ev := range output {
+		if ev.Time.IsZero() {
+			t.Errorf("Expected non-zero Time on event %+v", ev)
+		}
+		if !ev.IsComplete {
+			streams[ev.Line] = ev.Stream
+		}
+	}
+
+	if streams["out"] != engine.StreamStdout {
+		t.Errorf("Expected stdout stream for 'out', got %q", streams["out"])
+	}
+	if streams["err"] != engine.StreamStderr {
+		t.Errorf("Expected stderr stream for 'err', got %q", streams["err"])
+	}
+}
+
 // TestEngineCommandFactoryError verifies error handling when CommandFactory returns an error.
 func TestEngineCommandFactoryError(t *testing.T) {
 	ctx := context.Background()
@@ -1075,6 +1151,40 @@ func TestDefaultCommandFactoryWithFailure(t *testing.T) {
 	}
 }
 
+// TestDefaultCommandFactoryEnv verifies the spec's Env is added to the
+// parent's environment, later entries winning.
+func TestDefaultCommandFactoryEnv(t *testing.T) {
+	t.Setenv("MULTIPROC_TEST_PARENT", "inherited")
+	dir, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	specs := []engine.ProcessSpec{{
+		Name:    "env",
+		Command: "sh",
+		Args:    []string{"-c", "echo $MULTIPROC_TEST_PARENT $PORT $(pwd -P)"},
+		Env:     []string{"PORT=5000", "PORT=5100"},
+		Dir:     dir,
+	}}
+
+	eng := engine.New(specs, 5*time.Second)
+	output := make(chan engine.ProcessLine, 10)
+	go eng.Run(context.Background(), output)
+
+	var lines []string
+	for ev := range output {
+		if ev.IsComplete && ev.Err != nil {
+			t.Errorf("Expected successful exit, got error: %v", ev.Err)
+		}
+		if !ev.IsComplete {
+			lines = append(lines, ev.Line)
+		}
+	}
+	if len(lines) != 1 || lines[0] != "inherited 5100 "+dir {
+		t.Errorf("Expected the parent's and the spec's variables and the spec's dir, got %q", lines)
+	}
+}
+
 // TestRealProcessCancellation verifies graceful shutdown with real processes.
 func TestRealProcessCancellation(t *testing.T) {
 	if testing.Short() {
@@ -1261,3 +1371,526 @@ func (c *customErrorCommand) Wait() error {
 func (c *customErrorCommand) Process() engine.ProcessHandle {
 	return nil
 }
+
+// TestEngineRestartAndStop verifies per-process restart and stop during a run.
+func TestEngineRestartAndStop(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping real process test in short mode")
+	}
+
+	specs := []engine.ProcessSpec{
+		{Name: "server", Command: "sh", Args: []string{"-c", "echo start; exec sleep 10"}},
+		{Name: "idle", Command: "sleep", Args: []string{"10"}},
+	}
+	eng := engine.New(specs, time.Second)
+	output := make(chan engine.ProcessLine, 20)
+	go eng.Run(context.Background(), output)
+
+	// next returns the next event for process 0, failing after a timeout.
+	next := func() engine.ProcessLine {
+		t.Helper()
+		for {
+			select {
+			case pl := <-output:
+				if pl.Index == 0 {
+					return pl
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Timed out waiting for an event")
+			}
+		}
+	}
+	// waitFor skips events of process 0 until one matches.
+	waitFor := func(match func(engine.ProcessLine) bool) {
+		t.Helper()
+		for pl := next(); !match(pl); pl = next() {
+		}
+	}
+
+	waitFor(func(pl engine.ProcessLine) bool { return pl.Line == "start" })
+	if err := eng.Restart(0); err != nil {
+		t.Fatalf("Restart failed: %v", err)
+	}
+	waitFor(func(pl engine.ProcessLine) bool { return pl.IsComplete })
+	if pl := next(); !pl.IsRestart {
+		t.Fatalf("Expected a restart event after completion, got %+v", pl)
+	}
+	waitFor(func(pl engine.ProcessLine) bool { return pl.Line == "start" })
+
+	if err := eng.Stop(0); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	waitFor(func(pl engine.ProcessLine) bool { return pl.IsComplete })
+	if err := eng.Stop(0); !errors.Is(err, engine.ErrNotRunning) {
+		t.Errorf("Expected ErrNotRunning stopping a finished process, got %v", err)
+	}
+	if err := eng.Restart(2); err == nil {
+		t.Error("Expected an error for an out-of-range index")
+	}
+
+	if err := eng.Stop(1); err != nil {
+		t.Fatalf("Stop failed: %v", err)
+	}
+	//nolint:revive // drain output channel until the run ends
+	for range output {
+	}
+	if err := eng.Restart(0); !errors.Is(err, engine.ErrNoRun) {
+		t.Errorf("Expected ErrNoRun after the run ended, got %v", err)
+	}
+}
+
+// TestEngineAddReplaceRemove verifies processes can be added, changed and
+// removed during a run, with their update and removal events.
+func TestEngineAddReplaceRemove(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping real process test in short mode")
+	}
+
+	specs := []engine.ProcessSpec{
+		{Name: "server", Command: "sh", Args: []string{"-c", "echo v1; exec sleep 10"}},
+	}
+	eng := engine.New(specs, time.Second)
+	if _, err := eng.Add(specs[0]); !errors.Is(err, engine.ErrNoRun) {
+		t.Fatalf("Add() before Run = %v, want ErrNoRun", err)
+	}
+	select {
+	case <-eng.Started():
+		t.Fatal("Started is closed before Run")
+	default:
+	}
+	output := make(chan engine.ProcessLine, 20)
+	go eng.Run(context.Background(), output)
+	select {
+	case <-eng.Started():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Started is not closed by Run")
+	}
+
+	// waitFor skips events until one matches, failing after a timeout.
+	waitFor := func(match func(engine.ProcessLine) bool) engine.ProcessLine {
+		t.Helper()
+		for {
+			select {
+			case pl := <-output:
+				if match(pl) {
+					return pl
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Timed out waiting for an event")
+			}
+		}
+	}
+
+	waitFor(func(pl engine.ProcessLine) bool { return pl.Line == "v1" })
+	worker := engine.ProcessSpec{Name: "worker", Command: "sh", Args: []string{"-c", "echo working; exec sleep 10"}}
+	idx, err := eng.Add(worker)
+	if err != nil || idx != 1 {
+		t.Fatalf("Add() = %d, %v, want 1, nil", idx, err)
+	}
+	pl := waitFor(func(pl engine.ProcessLine) bool { return pl.Index == 1 })
+	if !pl.IsUpdate || pl.Spec.Name != "worker" {
+		t.Fatalf("Expected an update event for the added process, got %+v", pl)
+	}
+	waitFor(func(pl engine.ProcessLine) bool { return pl.Index == 1 && pl.Line == "working" })
+
+	v2 := engine.ProcessSpec{Name: "server", Command: "sh", Args: []string{"-c", "echo v2; exec sleep 10"}}
+	if err = eng.Replace(0, v2); err != nil {
+		t.Fatalf("Replace failed: %v", err)
+	}
+	waitFor(func(pl engine.ProcessLine) bool { return pl.Index == 0 && pl.IsComplete })
+	if pl = waitFor(func(pl engine.ProcessLine) bool { return pl.Index == 0 }); !pl.IsUpdate || pl.Spec.Args[1] != v2.Args[1] {
+		t.Fatalf("Expected an update event with the new spec, got %+v", pl)
+	}
+	if pl = waitFor(func(pl engine.ProcessLine) bool { return pl.Index == 0 }); !pl.IsRestart {
+		t.Fatalf("Expected a restart event after the update, got %+v", pl)
+	}
+	waitFor(func(pl engine.ProcessLine) bool { return pl.Index == 0 && pl.Line == "v2" })
+	if eng.Specs[0].Args[1] != specs[0].Args[1] {
+		t.Error("Replace must not modify Engine.Specs")
+	}
+
+	if err = eng.Remove(1); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	if pl = waitFor(func(pl engine.ProcessLine) bool { return pl.Index == 1 }); !pl.IsRemoved {
+		t.Fatalf("Expected a removal event, got %+v", pl)
+	}
+	waitFor(func(pl engine.ProcessLine) bool { return pl.Index == 1 && pl.IsComplete })
+	for _, err = range []error{eng.Remove(1), eng.Restart(1), eng.Stop(1), eng.Replace(1, worker)} {
+		if !errors.Is(err, engine.ErrRemoved) {
+			t.Errorf("Expected ErrRemoved for a removed process, got %v", err)
+		}
+	}
+
+	if err = eng.Remove(0); err != nil {
+		t.Fatalf("Remove failed: %v", err)
+	}
+	//nolint:revive // drain output channel until the run ends
+	for range output {
+	}
+	if _, err = eng.Add(worker); !errors.Is(err, engine.ErrNoRun) {
+		t.Errorf("Expected ErrNoRun after the run ended, got %v", err)
+	}
+}
+
+// TestEngineWatch verifies a process is restarted when its watched files
+// change, and not for ignored files.
+func TestEngineWatch(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping real process test in short mode")
+	}
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "src", "pkg"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+	write := func(name string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	specs := []engine.ProcessSpec{{
+		Name:          "build",
+		Command:       "sh",
+		Args:          []string{"-c", "echo built"},
+		Dir:           dir,
+		Watch:         []string{"*.txt", "src/**/*.go"},
+		WatchIgnore:   []string{"skip.txt"},
+		WatchDebounce: 50 * time.Millisecond,
+	}}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	output := make(chan engine.ProcessLine, 20)
+	go engine.New(specs, time.Second).Run(ctx, output)
+
+	// next returns the next event, failing after a timeout.
+	next := func() engine.ProcessLine {
+		t.Helper()
+		select {
+		case pl, ok := <-output:
+			if !ok {
+				t.Fatal("Run ended while files are watched")
+			}
+			return pl
+		case <-time.After(5 * time.Second):
+			t.Fatal("Timed out waiting for an event")
+		}
+		return engine.ProcessLine{}
+	}
+
+	if pl := next(); pl.Line != "built" {
+		t.Fatalf("Expected the first run, got %+v", pl)
+	}
+	if pl := next(); !pl.IsComplete || pl.Err != nil {
+		t.Fatalf("Expected the first run to complete, got %+v", pl)
+	}
+
+	// The watcher is set up at the start of the run; give it a moment.
+	time.Sleep(100 * time.Millisecond)
+	write("skip.txt")
+	write(filepath.Join("src", "pkg", "main.go"))
+	write(filepath.Join("src", "pkg", "main.go"))
+
+	if pl := next(); pl.Line != "[restart due to change: src/pkg/main.go]" || pl.Stream != engine.StreamSystem {
+		t.Fatalf("Expected a restart line naming the changed file, got %+v", pl)
+	}
+	if pl := next(); !pl.IsRestart || pl.Reason != "change: src/pkg/main.go" || pl.ClearOutput {
+		t.Fatalf("Expected a restart event with the change as reason, got %+v", pl)
+	}
+	if pl := next(); pl.Line != "built" {
+		t.Fatalf("Expected the second run, got %+v", pl)
+	}
+	if pl := next(); !pl.IsComplete {
+		t.Fatalf("Expected the second run to complete, got %+v", pl)
+	}
+
+	cancel()
+	//nolint:revive // drain output channel until the run ends
+	for pl := range output {
+		if pl.IsRestart {
+			t.Errorf("Unexpected restart after cancellation: %+v", pl)
+		}
+	}
+}
+
+// TestParseSchedule verifies intervals, shorthands and cron expressions.
+func TestParseSchedule(t *testing.T) {
+	// A Wednesday.
+	from := time.Date(2025, time.January, 15, 10, 7, 30, 0, time.UTC)
+	tests := []struct {
+		schedule string
+		want     time.Time
+	}{
+		{"30s", from.Add(30 * time.Second)},
+		{"@every 1m30s", from.Add(90 * time.Second)},
+		{"@hourly", time.Date(2025, time.January, 15, 11, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2025, time.January, 16, 0, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2025, time.January, 15, 10, 15, 0, 0, time.UTC)},
+		{"0,30 9-17 * * mon-fri", time.Date(2025, time.January, 15, 10, 30, 0, 0, time.UTC)},
+		{"0 9 * * sat", time.Date(2025, time.January, 18, 9, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2025, time.January, 19, 0, 0, 0, 0, time.UTC)},
+		{"0 12 1 feb *", time.Date(2025, time.February, 1, 12, 0, 0, 0, time.UTC)},
+		{"5-59/20 10 * * *", time.Date(2025, time.January, 15, 10, 25, 0, 0, time.UTC)},
+		// Both days restricted: either matches (the 20th or a Friday).
+		{"0 0 20 * fri", time.Date(2025, time.January, 17, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		sched, err := engine.ParseSchedule(tt.schedule)
+		if err != nil {
+			t.Errorf("ParseSchedule(%q): %v", tt.schedule, err)
+			continue
+		}
+		if got := sched.Next(from); !got.Equal(tt.want) {
+			t.Errorf("ParseSchedule(%q).Next() = %v, want %v", tt.schedule, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "-5s", "@every", "* * * *", "60 * * * *", "* * * foo *", "*/0 * * * *", "5-1 * * * *"} {
+		if _, err := engine.ParseSchedule(bad); err == nil {
+			t.Errorf("ParseSchedule(%q): expected an error", bad)
+		}
+	}
+}
+
+// TestEngineSchedule verifies a scheduled process runs again on schedule,
+// and that runs overlapping a running instance are skipped or queued.
+func TestEngineSchedule(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping real process test in short mode")
+	}
+
+	specs := []engine.ProcessSpec{
+		{Name: "check", Command: "sh", Args: []string{"-c", "echo tick"}, Schedule: "@every 100ms"},
+		{Name: "slow", Command: "sh", Args: []string{"-c", "exec sleep 0.25"}, Schedule: "100ms"},
+		{
+			Name: "queued", Command: "sh", Args: []string{"-c", "exec sleep 0.25"}, Schedule: "100ms",
+			ScheduleOverlap: engine.OverlapQueue,
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 600*time.Millisecond)
+	defer cancel()
+	output := make(chan engine.ProcessLine, 20)
+	go engine.New(specs, time.Second).Run(ctx, output)
+
+	var ticks, schedules, scheduledRestarts, skipped, queued int
+	for pl := range output {
+		switch {
+		case pl.Index == 0 && pl.Line == "tick":
+			ticks++
+		case pl.Index == 0 && pl.IsSchedule:
+			schedules++
+			if pl.NextRun.Before(pl.Time) {
+				t.Errorf("Expected the next run in the future, got %v at %v", pl.NextRun, pl.Time)
+			}
+		case pl.Index == 0 && pl.IsRestart:
+			if pl.Reason == "schedule" {
+				scheduledRestarts++
+			}
+		case pl.Index == 1 && pl.IsSchedule && pl.Skipped:
+			skipped++
+		case pl.Index == 2 && pl.Line == "[scheduled run queued: still running]":
+			queued++
+		case pl.Index == 2 && pl.IsSchedule && pl.Skipped:
+			t.Errorf("Expected queued runs not to be skipped, got %+v", pl)
+		}
+	}
+	// The last scheduled run may be cancelled before it prints.
+	if ticks < 3 || scheduledRestarts < ticks-1 || scheduledRestarts > ticks || schedules < ticks {
+		t.Errorf("Expected at least 3 runs, all but the first scheduled, got %d runs, %d scheduled restarts and %d schedule events",
+			ticks, scheduledRestarts, schedules)
+	}
+	if skipped == 0 || queued == 0 {
+		t.Errorf("Expected overlapping runs to be skipped and queued, got %d and %d", skipped, queued)
+	}
+}
+
+// TestCommandLine verifies arguments are quoted for the shell.
+func TestCommandLine(t *testing.T) {
+	spec := engine.ProcessSpec{Command: "sh", Args: []string{"-c", "echo 'hi' $PORT", "", "a=b,c/d.e"}}
+	if got, want := spec.CommandLine(), `sh -c 'echo '\''hi'\'' $PORT' '' a=b,c/d.e`; got != want {
+		t.Errorf("CommandLine() = %s, want %s", got, want)
+	}
+}
+
+// TestEngineDependsOn verifies that a process starts once the processes it
+// depends on have exited successfully, and not at all if one fails.
+func TestEngineDependsOn(t *testing.T) {
+	specs := []engine.ProcessSpec{
+		{Name: "test", Command: "test", DependsOn: []string{"build"}},
+		{Name: "deploy", Command: "deploy", DependsOn: []string{"test", "build"}},
+		{Name: "build", Command: "build"},
+		{Name: "docs", Command: "docs"},
+	}
+	var mu sync.Mutex
+	var started []string
+	factory := func(_ context.Context, spec engine.ProcessSpec) (engine.Command, error) {
+		mu.Lock()
+		started = append(started, spec.Name)
+		mu.Unlock()
+		cmd := NewMockCommand(spec).WithStdout(spec.Name + " ran")
+		switch spec.Name {
+		case "build":
+			cmd.WithSleep(50 * time.Millisecond)
+		case "test":
+			cmd.WithExitError(errors.New("exit status 1"))
+		}
+		return cmd, nil
+	}
+
+	output := make(chan engine.ProcessLine, 20)
+	go engine.New(specs, time.Second).WithCommandFactory(factory).Run(context.Background(), output)
+
+	var order []string
+	done := map[string]engine.ProcessLine{}
+	for pl := range output {
+		name := specs[pl.Index].Name
+		switch {
+		case pl.IsComplete:
+			done[name] = pl
+			order = append(order, name+" done")
+		case pl.Stream == engine.StreamSystem:
+			order = append(order, name+" "+pl.Line)
+		default:
+			order = append(order, pl.Line)
+		}
+	}
+
+	if i, j := slices.Index(order, "build done"), slices.Index(order, "test ran"); i < 0 || j < i {
+		t.Errorf("Expected test to run after build exited, got %q", order)
+	}
+	if !slices.Contains(order, "test [waiting for build]") || !slices.Contains(order, "deploy [waiting for test, build]") {
+		t.Errorf("Expected waiting lines for test and deploy, got %q", order)
+	}
+	if slices.Contains(started, "deploy") {
+		t.Error("deploy must not start after test failed")
+	}
+	deploy := done["deploy"]
+	if !errors.Is(deploy.Err, engine.ErrDependencyFailed) || deploy.Canceled || !strings.Contains(deploy.Err.Error(), "test") {
+		t.Errorf("Expected deploy to fail with ErrDependencyFailed naming test, got %+v", deploy)
+	}
+	if err := done["docs"].Err; err != nil {
+		t.Errorf("Expected docs to run regardless, got %v", err)
+	}
+}
+
+// TestEngineDependsOnRestart verifies that a process restarted while it
+// waits for its dependencies waits for them again.
+func TestEngineDependsOnRestart(t *testing.T) {
+	specs := []engine.ProcessSpec{
+		{Name: "build", Command: "build"},
+		{Name: "test", Command: "test", DependsOn: []string{"build"}},
+	}
+	factory := func(_ context.Context, spec engine.ProcessSpec) (engine.Command, error) {
+		cmd := NewMockCommand(spec).WithStdout(spec.Name + " ran")
+		if spec.Name == "build" {
+			cmd.WithSleep(200 * time.Millisecond)
+		}
+		return cmd, nil
+	}
+
+	eng := engine.New(specs, time.Second).WithCommandFactory(factory)
+	output := make(chan engine.ProcessLine, 20)
+	go eng.Run(context.Background(), output)
+
+	var order []string
+	for pl := range output {
+		name := specs[pl.Index].Name
+		switch {
+		case pl.IsComplete:
+			order = append(order, name+" done")
+		case pl.IsRestart:
+			order = append(order, name+" restart")
+		case pl.Stream == engine.StreamSystem:
+			order = append(order, name+" "+pl.Line)
+			if pl.Line == "[waiting for build]" && !slices.Contains(order, "test restart") {
+				if err := eng.Restart(1); err != nil {
+					t.Fatalf("Restart failed: %v", err)
+				}
+			}
+		default:
+			order = append(order, pl.Line)
+		}
+	}
+
+	want := []string{"test [waiting for build]", "test done", "test restart", "test [waiting for build]", "test ran", "test done"}
+	var got []string
+	for _, event := range order {
+		if strings.HasPrefix(event, "test") {
+			got = append(got, event)
+		}
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected test events %q, got %q", want, got)
+	}
+	if i, j := slices.Index(order, "build done"), slices.Index(order, "test ran"); i < 0 || j < i {
+		t.Errorf("Expected test to run after build exited, got %q", order)
+	}
+}
+
+// TestEngineValidate verifies every problem with the specs is reported.
+func TestEngineValidate(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	specs := []engine.ProcessSpec{
+		{Name: "ok", Command: "sh", Dir: t.TempDir()},
+		{Name: "ok", Command: "multiproc-no-such-command"},
+		{Command: " "},
+		{Name: "dirs", Command: "./run.sh", Dir: filepath.Join(t.TempDir(), "missing")},
+		{Name: "file", Command: "sh", Dir: file, MaxLines: -1, MaxBytes: -2},
+		{Name: "watch", Command: "sh", Watch: []string{"src/[a-"}, WatchIgnore: []string{""}, WatchDebounce: -1},
+		{Name: "cron", Command: "sh", Schedule: "0 0 30 2 *", ScheduleOverlap: 7, RunHistory: -1},
+		{Name: "every", Command: "sh", Schedule: "every 5s"},
+		{Name: "a", Command: "sh", DependsOn: []string{"b", "nope"}},
+		{Name: "b", Command: "sh", DependsOn: []string{"a", "b"}},
+	}
+
+	err := engine.New(specs, time.Second).Validate()
+	want := []string{
+		`process "ok": duplicate name`,
+		`process "ok": command "multiproc-no-such-command" not found in PATH`,
+		`process 2: empty name`,
+		`process 2: empty command`,
+		`process "dirs": working directory: stat `,
+		`process "dirs": command "./run.sh": stat `,
+		`process "file": working directory ` + file + ` is not a directory`,
+		`process "file": negative MaxLines -1`,
+		`process "file": negative MaxBytes -2`,
+		`process "watch": watch pattern "src/[a-": syntax error in pattern`,
+		`process "watch": empty watch pattern`,
+		`process "watch": negative WatchDebounce -1ns`,
+		`process "cron": schedule "0 0 30 2 *" never fires`,
+		`process "cron": invalid ScheduleOverlap(7)`,
+		`process "cron": negative RunHistory -1`,
+		`process "every": invalid schedule "every 5s"`,
+		`process "a": depends on unknown process "nope"`,
+		`process "a": dependency cycle: a -> b -> a`,
+		`process "b": depends on itself`,
+		`process "b": dependency cycle: b -> a -> b`,
+	}
+	lines := strings.Split(fmt.Sprint(err), "\n")
+	if len(lines) != len(want) {
+		t.Fatalf("Expected %d problems, got:\n%v", len(want), err)
+	}
+	for i, prefix := range want {
+		if !strings.HasPrefix(lines[i], prefix) {
+			t.Errorf("Problem %d: expected prefix %q, got %q", i, prefix, lines[i])
+		}
+	}
+	var specErr *engine.SpecError
+	if !errors.As(err, &specErr) || specErr.Index != 1 {
+		t.Errorf("Expected a SpecError for process 1, got %#v", specErr)
+	}
+
+	// Other command factories may run commands elsewhere: no PATH lookup.
+	specs = []engine.ProcessSpec{{Name: "remote", Command: "multiproc-no-such-command"}}
+	factory := func(_ context.Context, spec engine.ProcessSpec) (engine.Command, error) {
+		return NewMockCommand(spec), nil
+	}
+	if err = engine.New(specs, time.Second).WithCommandFactory(factory).Validate(); err != nil {
+		t.Errorf("Expected no error with a custom factory, got %v", err)
+	}
+}
@@ -16,7 +16,8 @@ import (
 // The factory:
 //   - Creates an exec.Cmd using the spec's Command and Args
 //   - Wraps it in execCommand to implement the Command interface
-//   - Does not modify environment or working directory (uses parent process settings)
+//   - Adds the spec's Env to the parent process's environment
+//   - Runs in the spec's Dir, or the parent's working directory
 //   - Inherits stdin from parent (connected to /dev/null or equivalent)
 //
 // This factory is used automatically when Engine.CommandFactory is nil.
@@ -33,10 +34,12 @@ import (
 //	    CommandFactory: engine.DefaultCommandFactory,
 //	}
 func DefaultCommandFactory(ctx context.Context, spec ProcessSpec) (Command, error) {
-	return &execCommand{
-		spec: spec,
-		cmd:  newExecCmdWrapper(ctx, spec.Command, spec.Args...),
-	}, nil
+	cmd := newExecCmdWrapper(ctx, spec.Command, spec.Args...)
+	if len(spec.Env) > 0 {
+		cmd.Env = append(os.Environ(), spec.Env...)
+	}
+	cmd.Dir = spec.Dir
+	return &execCommand{spec: spec, cmd: cmd}, nil
 }
 
 // execCommand wraps exec.Cmd to implement the Command interface.
@@ -0,0 +1,389 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// reasonSchedule is the Reason of the restart events of scheduled runs.
+const reasonSchedule = "schedule"
+
+// cronSearchYears bounds the search for the next time of a cron
+// expression, so that impossible dates ("0 0 30 2 *") do not loop forever.
+const cronSearchYears = 5
+
+// Fields of a cron expression, in order.
+const (
+	cronMinute = iota
+	cronHour
+	cronDayOfMonth
+	cronMonth
+	cronDayOfWeek
+	cronFields
+)
+
+// cronField returns the lowest and highest values of cron field i, and
+// the names accepted for its values, the first being lo. Day of week 7 is
+// Sunday, like 0.
+func cronField(i int) (int, int, []string) {
+	switch i {
+	case cronMinute:
+		return 0, 59, nil
+	case cronHour:
+		return 0, 23, nil
+	case cronDayOfMonth:
+		return 1, 31, nil
+	case cronMonth:
+		return 1, 12, []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	default:
+		return 0, 7, []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+	}
+}
+
+// cronDescriptor returns the cron expression of an "@" shorthand, or s.
+func cronDescriptor(s string) string {
+	switch strings.ToLower(s) {
+	case "@yearly", "@annually":
+		return "0 0 1 1 *"
+	case "@monthly":
+		return "0 0 1 * *"
+	case "@weekly":
+		return "0 0 * * 0"
+	case "@daily", "@midnight":
+		return "0 0 * * *"
+	case "@hourly":
+		return "0 * * * *"
+	default:
+		return s
+	}
+}
+
+// ScheduleOverlap decides what happens when a scheduled process is still
+// running at one of its scheduled times (ProcessSpec.ScheduleOverlap).
+type ScheduleOverlap int
+
+const (
+	// OverlapSkip skips the scheduled run: the process runs again at the
+	// next scheduled time after it has exited.
+	OverlapSkip ScheduleOverlap = iota
+
+	// OverlapQueue runs the process again as soon as it exits. At most one
+	// run is queued.
+	OverlapQueue
+)
+
+// String returns the configuration spelling of the policy ("skip", "queue").
+func (o ScheduleOverlap) String() string {
+	switch o {
+	case OverlapSkip:
+		return "skip"
+	case OverlapQueue:
+		return "queue"
+	default:
+		return fmt.Sprintf("ScheduleOverlap(%d)", int(o))
+	}
+}
+
+// ParseScheduleOverlap parses "skip" or "queue" (case-insensitive); the
+// empty string is OverlapSkip.
+//
+// Example:
+//
+//	overlap, err := engine.ParseScheduleOverlap("queue")
+func ParseScheduleOverlap(s string) (ScheduleOverlap, error) {
+	switch strings.ToLower(s) {
+	case "", "skip":
+		return OverlapSkip, nil
+	case "queue":
+		return OverlapQueue, nil
+	default:
+		return OverlapSkip, fmt.Errorf("invalid schedule overlap %q (want skip or queue)", s)
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler with the configuration
+// spelling.
+func (o ScheduleOverlap) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler with
+// ParseScheduleOverlap, so the policy can be read from configuration files.
+func (o *ScheduleOverlap) UnmarshalText(text []byte) error {
+	v, err := ParseScheduleOverlap(string(text))
+	if err != nil {
+		return err
+	}
+	*o = v
+	return nil
+}
+
+// Schedule decides when a scheduled process runs (ProcessSpec.Schedule).
+type Schedule interface {
+	// Next returns the first scheduled time after t, or the zero time if
+	// there is none.
+	Next(t time.Time) time.Time
+}
+
+// ParseSchedule parses a ProcessSpec.Schedule, which is one of:
+//   - an interval: "30s", "@every 5m"
+//   - a shorthand: "@hourly", "@daily" (or "@midnight"), "@weekly",
+//     "@monthly", "@yearly" (or "@annually")
+//   - a cron expression with five fields, in local time: minute, hour, day
+//     of month, month and day of week ("*/5 * * * *", "0 9 * * mon-fri").
+//     Each field is "*", a value, a range ("1-5"), a list ("1,15") or a
+//     step ("*/10", "0-30/5"). Months and days of week may be names
+//     ("jan", "mon"); day of week 0 and 7 are Sunday. As in cron, when both
+//     days are restricted, a time matching either of them is scheduled.
+//
+// Example:
+//
+//	sched, err := engine.ParseSchedule("*/15 * * * *")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	fmt.Println(sched.Next(time.Now())) // the next quarter hour
+func ParseSchedule(s string) (Schedule, error) {
+	s = strings.TrimSpace(s)
+	if every, ok := strings.CutPrefix(s, "@every "); ok {
+		return parseInterval(strings.TrimSpace(every))
+	}
+	fields := strings.Fields(cronDescriptor(s))
+	if len(fields) == 1 {
+		return parseInterval(s)
+	}
+	if len(fields) != cronFields {
+		return nil, fmt.Errorf("invalid schedule %q (want an interval such as 30s, or five cron fields)", s)
+	}
+
+	var sched cronSchedule
+	for i, field := range fields {
+		lo, hi, names := cronField(i)
+		bits, err := parseCronField(field, lo, hi, names)
+		if err != nil {
+			return nil, fmt.Errorf("schedule %q: %w", s, err)
+		}
+		sched.fields[i] = bits
+	}
+	// Sunday is both 0 and 7.
+	if sched.fields[cronDayOfWeek]&(1<<7) != 0 {
+		sched.fields[cronDayOfWeek] |= 1
+	}
+	sched.anyDayOfMonth = strings.HasPrefix(fields[cronDayOfMonth], "*")
+	sched.anyDayOfWeek = strings.HasPrefix(fields[cronDayOfWeek], "*")
+	return sched, nil
+}
+
+// parseInterval parses the interval of an interval schedule.
+func parseInterval(s string) (Schedule, error) {
+	every, err := time.ParseDuration(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule interval %q: %w", s, err)
+	}
+	if every <= 0 {
+		return nil, fmt.Errorf("invalid schedule interval %q: must be positive", s)
+	}
+	return intervalSchedule(every), nil
+}
+
+// intervalSchedule runs a process at a fixed interval.
+type intervalSchedule time.Duration
+
+// Next returns t plus the interval.
+func (s intervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(s))
+}
+
+// cronSchedule runs a process at the times matching a cron expression.
+type cronSchedule struct {
+	// fields holds the values of each field as a bit set.
+	fields [cronFields]uint64
+
+	// anyDayOfMonth and anyDayOfWeek are true for day fields starting
+	// with "*", such as "*" and "*/2".
+	anyDayOfMonth bool
+	anyDayOfWeek  bool
+}
+
+// Next returns the first matching minute after t.
+func (s cronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(cronSearchYears, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case !s.has(cronMonth, int(t.Month())):
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case !s.has(cronHour, t.Hour()):
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case !s.has(cronMinute, t.Minute()):
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// has reports whether value is set in field.
+func (s cronSchedule) has(field, value int) bool {
+	return s.fields[field]&(1<<uint(value)) != 0 //nolint:gosec // values are within the field range
+}
+
+// dayMatches reports whether the day of t matches the day fields.
+func (s cronSchedule) dayMatches(t time.Time) bool {
+	dom := s.has(cronDayOfMonth, t.Day())
+	dow := s.has(cronDayOfWeek, int(t.Weekday()))
+	if s.anyDayOfMonth || s.anyDayOfWeek {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// parseCronField parses one field of a cron expression into a bit set of
+// the values between lo and hi it selects.
+func parseCronField(field string, lo, hi int, names []string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		span, stepText, stepped := strings.Cut(part, "/")
+		step := 1
+		if stepped {
+			var err error
+			if step, err = strconv.Atoi(stepText); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepText)
+			}
+		}
+		start, end := lo, hi
+		if span != "*" {
+			first, last, ranged := strings.Cut(span, "-")
+			var err error
+			if start, err = cronValue(first, lo, hi, names); err != nil {
+				return 0, err
+			}
+			switch {
+			case ranged:
+				if end, err = cronValue(last, lo, hi, names); err != nil {
+					return 0, err
+				}
+			case !stepped:
+				end = start
+			}
+			if start > end {
+				return 0, fmt.Errorf("invalid range %q", span)
+			}
+		}
+		for v := start; v <= end; v += step {
+			bits |= 1 << uint(v) //nolint:gosec // values are within the field range
+		}
+	}
+	return bits, nil
+}
+
+// cronValue parses a value of a cron field: a number between lo and hi, or
+// one of names (the first being lo).
+func cronValue(s string, lo, hi int, names []string) (int, error) {
+	for i, name := range names {
+		if strings.EqualFold(s, name) {
+			return lo + i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	if v < lo || v > hi {
+		return 0, fmt.Errorf("value %d out of range [%d, %d]", v, lo, hi)
+	}
+	return v, nil
+}
+
+// checkSchedule reports an invalid Schedule, one that never fires, and an
+// invalid ScheduleOverlap.
+func checkSchedule(spec ProcessSpec) []error {
+	var problems []error
+	if spec.Schedule != "" {
+		sched, err := ParseSchedule(spec.Schedule)
+		switch {
+		case err != nil:
+			problems = append(problems, err)
+		case sched.Next(time.Now()).IsZero():
+			problems = append(problems, fmt.Errorf("schedule %q never fires", spec.Schedule))
+		}
+	}
+	if spec.ScheduleOverlap != OverlapSkip && spec.ScheduleOverlap != OverlapQueue {
+		problems = append(problems, errors.New("invalid "+spec.ScheduleOverlap.String()))
+	}
+	if spec.RunHistory < 0 {
+		problems = append(problems, fmt.Errorf("negative RunHistory %d", spec.RunHistory))
+	}
+	return problems
+}
+
+// schedule runs process index again at every time of sched, until detach
+// is closed or the run is cancelled. Before each wait, it emits a schedule
+// event with the next run time.
+func (eng *Engine) schedule(run *runState, index int, sched Schedule, overlap ScheduleOverlap, detach <-chan struct{}) {
+	defer func() {
+		eng.mu.Lock()
+		run.release()
+		eng.mu.Unlock()
+	}()
+
+	last, skipped := time.Now(), false
+	for {
+		next := sched.Next(last)
+		if now := time.Now(); !next.IsZero() && next.Before(now) {
+			// Runs missed while the machine was asleep are not caught up.
+			next = sched.Next(now)
+		}
+		if next.IsZero() {
+			return
+		}
+		run.output <- ProcessLine{Index: index, IsSchedule: true, NextRun: next, Skipped: skipped, Time: time.Now()}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-run.ctx.Done():
+			timer.Stop()
+			return
+		case <-detach:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		skipped = eng.runScheduled(run, index, overlap)
+		last = next
+	}
+}
+
+// runScheduled starts a scheduled run of process index. If the process is
+// still running, the run is skipped, or queued with OverlapQueue. It reports
+// whether the run was skipped.
+func (eng *Engine) runScheduled(run *runState, index int, overlap ScheduleOverlap) bool {
+	eng.mu.Lock()
+	p := &run.procs[index]
+	if run.ctx.Err() != nil || p.removed {
+		eng.mu.Unlock()
+		return false
+	}
+	if !p.running {
+		p.reason = reasonSchedule
+		eng.restart(run, index)
+		eng.mu.Unlock()
+		return false
+	}
+	line := "[scheduled run skipped: still running]"
+	if overlap == OverlapQueue {
+		p.queued = true
+		line = "[scheduled run queued: still running]"
+	}
+	eng.mu.Unlock()
+
+	run.output <- ProcessLine{Index: index, Line: line, Stream: StreamSystem, Time: time.Now()}
+	return overlap != OverlapQueue
+}
@@ -31,7 +31,25 @@ package engine
 
 import (
 	"io"
+	"strings"
 	"syscall"
+	"time"
+)
+
+// Stream identifies where a line of output came from.
+// The zero value means the stream is unspecified (e.g., completion events).
+type Stream string
+
+const (
+	// StreamStdout marks lines read from the process's standard output.
+	StreamStdout Stream = "stdout"
+
+	// StreamStderr marks lines read from the process's standard error.
+	StreamStderr Stream = "stderr"
+
+	// StreamSystem marks status lines generated by the engine itself,
+	// such as "[sending SIGTERM for graceful shutdown...]".
+	StreamSystem Stream = "system"
 )
 
 // ProcessLine represents a single line of output or completion event from a process.
@@ -42,6 +60,15 @@ import (
 //  1. Zero or more line events (IsComplete=false, Line contains output)
 //  2. Exactly one completion event (IsComplete=true, Err contains exit status)
 //
+// If the process is restarted with Engine.Restart, the sequence repeats,
+// introduced by one restart event (IsRestart=true).
+//
+// Processes can change during a run: Engine.Add and Engine.Replace emit an
+// update event (IsUpdate=true, with the new Spec) before the process
+// starts, and Engine.Remove emits a removal event (IsRemoved=true). Indexes
+// are never reused: added processes get the next index, and a removed
+// process keeps its own.
+//
 // Example handling:
 //
 //	for pl := range output {
@@ -67,14 +94,70 @@ type ProcessLine struct {
 	// Line endings (CRLF/LF/CR) are stripped for cross-platform consistency.
 	Line string
 
+	// Stream identifies the source of Line (stdout, stderr or engine status).
+	// Only meaningful when IsComplete is false.
+	Stream Stream
+
+	// Time is when the engine observed the line or the process exit.
+	Time time.Time
+
 	// Index identifies which process emitted this event.
-	// It corresponds to the position in the ProcessSpec slice passed to Engine.
+	// It corresponds to the position in the ProcessSpec slice passed to Engine,
+	// or, for processes added with Engine.Add, the index Add returned.
 	Index int
 
+	// Spec is the new spec of the process on an update event.
+	Spec ProcessSpec
+
 	// IsComplete indicates whether this is the final event for this process.
 	// When true, the process has exited and Err contains the exit status.
 	// When false, this is a regular output line and Line contains the text.
 	IsComplete bool
+
+	// IsRestart indicates that the process is being started again after
+	// Engine.Restart. It carries no Line or Err; the line and completion
+	// events of the new instance follow.
+	IsRestart bool
+
+	// Reason is why the process is restarted, on a restart event caused by
+	// a change of its watched files (e.g., "change: web/app.js"). It is
+	// empty for restarts requested with Engine.Restart.
+	Reason string
+
+	// ClearOutput indicates, on a restart event, that the output of the
+	// previous runs should be dropped (ProcessSpec.WatchClear).
+	ClearOutput bool
+
+	// IsSchedule indicates that a scheduled process (ProcessSpec.Schedule)
+	// waits for its next run, at NextRun. It is emitted when the run
+	// starts and after every scheduled time, and carries no Line or Err.
+	IsSchedule bool
+
+	// NextRun is the next scheduled run of the process, on a schedule
+	// event.
+	NextRun time.Time
+
+	// Skipped indicates, on a schedule event, that the previous scheduled
+	// run was skipped because the process was still running.
+	Skipped bool
+
+	// IsUpdate indicates that the process was added (Engine.Add) or that
+	// its spec was replaced (Engine.Replace), and is about to start with
+	// Spec. For a replaced process, it follows the completion event of the
+	// previous instance and precedes the restart event (IsRestart=true).
+	IsUpdate bool
+
+	// IsRemoved indicates that the process was removed from the run
+	// (Engine.Remove). For a running process, the completion event of the
+	// stopped instance follows, unless it exited on its own just before;
+	// no event follows for a process that had already exited.
+	IsRemoved bool
+
+	// Canceled indicates, on a completion event, that the process did not
+	// finish on its own: it was shut down, or never started, because the
+	// run's context was cancelled. Stopping a process with Engine.Stop does
+	// not count as a cancellation.
+	Canceled bool
 }
 
 // ProcessSpec describes a subprocess to run.
@@ -91,7 +174,9 @@ type ProcessLine struct {
 //	}
 type ProcessSpec struct {
 	// Name is a logical label for the subprocess, used in output headers and logs.
-	// If empty, a default name like "proc-0" will be generated.
+	// Names must be non-empty and unique among the specs of a run (see
+	// Engine.Validate); renderers fall back to "proc-0", "proc-1", ... for
+	// an empty name.
 	Name string
 
 	// Command is the executable to run (e.g., "sh", "bash", "go", "npm").
@@ -125,6 +210,114 @@ type ProcessSpec struct {
 	// Example: MaxLines=1000 and MaxBytes=100000 means keep at most 1000 lines
 	// AND at most 100KB, whichever constraint is reached first.
 	MaxBytes int
+
+	// Color is a display hint for renderers: a color name ("cyan",
+	// "bright-magenta") or a 256-color palette index ("208").
+	// If empty, renderers pick a stable color from their palette.
+	// The engine itself ignores this field.
+	Color string
+
+	// Env holds extra environment variables for the process, as
+	// "KEY=value" entries added to the environment of the parent process.
+	// If a key appears more than once, the last value wins, so later
+	// entries override earlier ones and the parent's environment.
+	//
+	// Example:
+	//   Env: []string{"PORT=5000", "RAILS_ENV=development"}
+	Env []string
+
+	// Dir is the working directory of the process. If empty, the process
+	// runs in the working directory of the parent process. A relative
+	// Command containing a path separator is resolved against Dir.
+	Dir string
+
+	// Group is the name of the group the process belongs to, if any, for
+	// selecting processes (e.g., "backend"). The engine itself ignores
+	// this field.
+	Group string
+
+	// Tags label the process for selecting processes (e.g., "go",
+	// "slow"). The engine itself ignores this field.
+	Tags []string
+
+	// DependsOn names the processes that must finish first (e.g., "build"
+	// for "test"): Run starts the process once they have all exited
+	// successfully, and does not start it if one of them fails (see
+	// ErrDependencyFailed). Once the process has started, restarts do not
+	// wait. Selecting the process with config.Select also selects them.
+	// Validate reports unknown names and dependency cycles.
+	DependsOn []string
+
+	// Watch lists glob patterns of files that restart the process when they
+	// are created, changed or deleted during Run (e.g., "*.go",
+	// "web/src/**/*.ts"). Patterns are matched against slash-separated
+	// paths relative to Dir (or the working directory); "**" matches any
+	// number of directories, and a pattern without "/" matches the base
+	// name at any depth. The process is stopped with its usual shutdown
+	// sequence and started again, and a run with watched processes lasts
+	// until its context is cancelled.
+	Watch []string
+
+	// WatchIgnore lists glob patterns, matched like Watch, of files and
+	// directories whose changes are ignored (e.g., "*_test.go",
+	// "node_modules"). ".git" directories are always ignored.
+	WatchIgnore []string
+
+	// WatchDebounce is how long changes must settle before the process is
+	// restarted, so that saving many files at once restarts it only once.
+	// If zero, DefaultWatchDebounce is used.
+	WatchDebounce time.Duration
+
+	// WatchClear marks the restart events caused by changes with
+	// ClearOutput, so that renderers drop the output of the previous runs
+	// instead of keeping it above a separator.
+	WatchClear bool
+
+	// Schedule runs the process again periodically instead of the
+	// "while sleep" loop of a wrapper script: an interval ("30s",
+	// "@every 5m"), a shorthand ("@hourly") or a cron expression
+	// ("*/5 * * * *"); see ParseSchedule. The process runs when Run starts,
+	// then at every scheduled time, and a run with scheduled processes
+	// lasts until its context is cancelled. Run ignores an invalid
+	// Schedule, which Validate reports.
+	Schedule string
+
+	// ScheduleOverlap decides what happens when the process is still
+	// running at a scheduled time: OverlapSkip (the default) skips that
+	// run, OverlapQueue runs the process again as soon as it exits.
+	ScheduleOverlap ScheduleOverlap
+
+	// RunHistory is the number of run results renderers keep for the
+	// process (renderer.ProcessState.Runs). If zero, a default is used.
+	// The engine itself ignores this field.
+	RunHistory int
+}
+
+// CommandLine returns the command and arguments of the process as a shell
+// command line, quoting the words that need it for a POSIX shell.
+//
+// Example:
+//
+//	ProcessSpec{Command: "sh", Args: []string{"-c", "echo $PORT"}}.CommandLine() // "sh -c 'echo $PORT'"
+func (s ProcessSpec) CommandLine() string {
+	words := make([]string, 0, len(s.Args)+1)
+	for _, w := range append([]string{s.Command}, s.Args...) {
+		words = append(words, shellQuote(w))
+	}
+	return strings.Join(words, " ")
+}
+
+// shellQuote quotes s for a POSIX shell if it contains anything but
+// letters, digits and "_@%+=:,./-".
+func shellQuote(s string) string {
+	safe := s != "" && strings.IndexFunc(s, func(c rune) bool {
+		isAlnum := c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+		return !isAlnum && !strings.ContainsRune("_@%+=:,./-", c)
+	}) < 0
+	if safe {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
 }
 
 // Command is an abstraction over os/exec.Cmd to enable testing and alternative
@@ -0,0 +1,127 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// SpecError is a problem with one of the specs of an Engine, reported by
+// Validate.
+type SpecError struct {
+	// Index is the position of the spec in Engine.Specs.
+	Index int
+
+	// Name is the name of the spec, possibly empty.
+	Name string
+
+	// Err describes the problem.
+	Err error
+}
+
+// Error returns the problem prefixed with the process, e.g.,
+// `process "api": command "gooo" not found`.
+func (e *SpecError) Error() string {
+	if e.Name == "" {
+		return "process " + strconv.Itoa(e.Index) + ": " + e.Err.Error()
+	}
+	return "process " + strconv.Quote(e.Name) + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying problem.
+func (e *SpecError) Unwrap() error { return e.Err }
+
+// Validate checks the specs before anything is started, so that a typo in
+// one process does not leave the others running on their own. It reports:
+//   - empty and duplicate names
+//   - empty commands, and commands not found with exec.LookPath (only
+//     with the default command factory, as other factories may run
+//     commands elsewhere)
+//   - working directories (ProcessSpec.Dir) that do not exist
+//   - negative MaxLines and MaxBytes
+//   - DependsOn entries that name no other process, and dependency cycles
+//   - invalid Watch and WatchIgnore patterns, and a negative WatchDebounce
+//   - invalid or impossible schedules, invalid ScheduleOverlap and a
+//     negative RunHistory
+//
+// Every problem found is returned as a *SpecError, joined with errors.Join;
+// nil means the specs are valid.
+//
+// Example:
+//
+//	eng := engine.New(specs, 5*time.Second)
+//	if err := eng.Validate(); err != nil {
+//	    log.Fatal(err) // one line per problem
+//	}
+//	eng.Run(ctx, output)
+func (e *Engine) Validate() error {
+	var errs []error
+	seen := map[string]bool{}
+	for i, spec := range e.Specs {
+		var problems []error
+		switch {
+		case spec.Name == "":
+			problems = append(problems, errors.New("empty name"))
+		case seen[spec.Name]:
+			problems = append(problems, errors.New("duplicate name"))
+		}
+		seen[spec.Name] = true
+
+		if spec.Dir != "" {
+			if info, err := os.Stat(spec.Dir); err != nil {
+				problems = append(problems, fmt.Errorf("working directory: %w", err))
+			} else if !info.IsDir() {
+				problems = append(problems, fmt.Errorf("working directory %s is not a directory", spec.Dir))
+			}
+		}
+		if err := e.checkCommand(spec); err != nil {
+			problems = append(problems, err)
+		}
+		if spec.MaxLines < 0 {
+			problems = append(problems, fmt.Errorf("negative MaxLines %d", spec.MaxLines))
+		}
+		if spec.MaxBytes < 0 {
+			problems = append(problems, fmt.Errorf("negative MaxBytes %d", spec.MaxBytes))
+		}
+		problems = append(problems, checkDependsOn(e.Specs, i)...)
+		problems = append(problems, checkWatch(spec)...)
+		problems = append(problems, checkSchedule(spec)...)
+
+		for _, p := range problems {
+			errs = append(errs, &SpecError{Index: i, Name: spec.Name, Err: p})
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// checkCommand reports an empty command or, with the default command
+// factory, one that cannot be found. A relative path is resolved against
+// the spec's Dir, as exec.Cmd does.
+func (e *Engine) checkCommand(spec ProcessSpec) error {
+	if strings.TrimSpace(spec.Command) == "" {
+		return errors.New("empty command")
+	}
+	if e.CommandFactory != nil {
+		return nil
+	}
+
+	path := spec.Command
+	if spec.Dir != "" && strings.ContainsRune(path, filepath.Separator) && !filepath.IsAbs(path) {
+		path = filepath.Join(spec.Dir, path)
+	}
+	if _, err := exec.LookPath(path); err != nil {
+		var execErr *exec.Error
+		if errors.As(err, &execErr) {
+			err = execErr.Err
+		}
+		if errors.Is(err, exec.ErrNotFound) {
+			return fmt.Errorf("command %q not found in PATH", spec.Command)
+		}
+		return fmt.Errorf("command %q: %w", spec.Command, err)
+	}
+	return nil
+}
@@ -0,0 +1,301 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultWatchDebounce is how long changes of watched files must settle
+// before the process is restarted, when ProcessSpec.WatchDebounce is zero.
+const DefaultWatchDebounce = 200 * time.Millisecond
+
+const (
+	// watchPollInterval is how often the watched files are scanned when
+	// file system notifications are not available.
+	watchPollInterval = 500 * time.Millisecond
+
+	// watchChangesBuffer is the number of changes buffered between the
+	// file watcher and the debounce loop.
+	watchChangesBuffer = 64
+)
+
+// checkWatch reports invalid Watch and WatchIgnore patterns and a negative
+// WatchDebounce.
+func checkWatch(spec ProcessSpec) []error {
+	var problems []error
+	for _, pattern := range slices.Concat(spec.Watch, spec.WatchIgnore) {
+		if err := checkWatchPattern(pattern); err != nil {
+			problems = append(problems, err)
+		}
+	}
+	if spec.WatchDebounce < 0 {
+		problems = append(problems, fmt.Errorf("negative WatchDebounce %s", spec.WatchDebounce))
+	}
+	return problems
+}
+
+// checkWatchPattern reports whether pattern is a valid watch pattern.
+func checkWatchPattern(pattern string) error {
+	if pattern == "" {
+		return errors.New("empty watch pattern")
+	}
+	for _, segment := range strings.Split(pattern, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return fmt.Errorf("watch pattern %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// watchSet selects the files watched for one process.
+type watchSet struct {
+	root    string
+	include []string
+	ignore  []string
+}
+
+// newWatchSet returns the watch set of spec, rooted at its working directory.
+func newWatchSet(spec ProcessSpec) watchSet {
+	root := spec.Dir
+	if root == "" {
+		root = "."
+	}
+	return watchSet{
+		root:    root,
+		include: cleanPatterns(spec.Watch),
+		ignore:  cleanPatterns(spec.WatchIgnore),
+	}
+}
+
+// cleanPatterns drops a leading "./" from every pattern.
+func cleanPatterns(patterns []string) []string {
+	cleaned := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		cleaned[i] = strings.TrimPrefix(pattern, "./")
+	}
+	return cleaned
+}
+
+// matches reports whether the file at rel, a slash-separated path relative
+// to the root, is watched.
+func (w watchSet) matches(rel string) bool {
+	return matchAnyGlob(w.include, rel) && !w.ignored(rel)
+}
+
+// ignored reports whether rel or one of its parent directories is ignored.
+func (w watchSet) ignored(rel string) bool {
+	for dir := rel; dir != "." && dir != "/" && dir != ""; dir = path.Dir(dir) {
+		if path.Base(dir) == ".git" || matchAnyGlob(w.ignore, dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// rel returns the slash-separated path of file relative to the root.
+func (w watchSet) rel(file string) string {
+	rel, err := filepath.Rel(w.root, file)
+	if err != nil {
+		return filepath.ToSlash(file)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// walkDirs calls visit with the path, relative to the root, of dir and of
+// every directory below it that is not ignored. Directories that cannot be
+// read are skipped; an error of visit ends the walk and is returned.
+func (w watchSet) walkDirs(dir string, visit func(rel string) error) error {
+	return filepath.WalkDir(filepath.Join(w.root, dir), func(file string, d fs.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		rel := w.rel(file)
+		if rel != "." && w.ignored(rel) {
+			return filepath.SkipDir
+		}
+		return visit(rel)
+	})
+}
+
+// scan returns the modification time and size of every watched file, by
+// path relative to the root.
+func (w watchSet) scan() map[string]string {
+	files := map[string]string{}
+	_ = filepath.WalkDir(w.root, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel := w.rel(file)
+		if d.IsDir() {
+			if rel != "." && w.ignored(rel) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !w.matches(rel) {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			files[rel] = info.ModTime().String() + " " + strconv.FormatInt(info.Size(), 10)
+		}
+		return nil
+	})
+	return files
+}
+
+// pollTree sends the watched files of w that are created, changed or
+// deleted to changes, by scanning the tree every watchPollInterval, until
+// done is closed.
+func pollTree(done <-chan struct{}, w watchSet, changes chan<- string) {
+	ticker := time.NewTicker(watchPollInterval)
+	defer ticker.Stop()
+
+	prev := w.scan()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		next := w.scan()
+		for rel, stamp := range next {
+			if prev[rel] != stamp && !sendChange(done, changes, rel) {
+				return
+			}
+		}
+		for rel := range prev {
+			if _, ok := next[rel]; !ok && !sendChange(done, changes, rel) {
+				return
+			}
+		}
+		prev = next
+	}
+}
+
+// sendChange sends rel to changes, unless done is closed first.
+func sendChange(done <-chan struct{}, changes chan<- string, rel string) bool {
+	select {
+	case changes <- rel:
+		return true
+	case <-done:
+		return false
+	}
+}
+
+// matchAnyGlob reports whether name matches one of patterns.
+func matchAnyGlob(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if matchGlob(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob reports whether the slash-separated path name matches pattern.
+// A pattern without "/" matches the base name; otherwise each segment is
+// matched with path.Match, and a "**" segment matches any number of
+// segments.
+func matchGlob(pattern, name string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(name))
+		return ok
+	}
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// matchSegments matches the segments of a path against those of a pattern.
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := range len(name) + 1 {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+// watch restarts process index once changes of its watched files have
+// settled for debounce, until detach is closed or the run is cancelled.
+func (eng *Engine) watch(run *runState, index int, w watchSet, debounce time.Duration, detach <-chan struct{}) {
+	done := make(chan struct{})
+	defer func() {
+		close(done)
+		eng.mu.Lock()
+		run.release()
+		eng.mu.Unlock()
+	}()
+
+	changes := make(chan string, watchChangesBuffer)
+	go watchTree(done, w, changes)
+
+	settled := time.NewTimer(debounce)
+	settled.Stop()
+	changed := ""
+	for {
+		select {
+		case <-run.ctx.Done():
+			return
+		case <-detach:
+			return
+		case rel := <-changes:
+			if changed == "" {
+				changed = rel
+			}
+			settled.Reset(debounce)
+		case <-settled.C:
+			eng.restartForChange(run, index, changed)
+			changed = ""
+		}
+	}
+}
+
+// restartForChange restarts process index because the watched file rel
+// changed, unless the process was removed or the run is being cancelled.
+func (eng *Engine) restartForChange(run *runState, index int, rel string) {
+	reason := "change: " + rel
+
+	eng.mu.Lock()
+	if run.ctx.Err() != nil || run.procs[index].removed {
+		eng.mu.Unlock()
+		return
+	}
+	clearOutput := run.specs[index].WatchClear
+	eng.mu.Unlock()
+
+	if !clearOutput {
+		// The output of the previous run is kept: separate it from the next.
+		run.output <- ProcessLine{
+			Index:  index,
+			Line:   "[restart due to " + reason + "]",
+			Stream: StreamSystem,
+			Time:   time.Now(),
+		}
+	}
+
+	eng.mu.Lock()
+	defer eng.mu.Unlock()
+	if run.ctx.Err() != nil || run.procs[index].removed {
+		return
+	}
+	run.procs[index].reason = reason
+	eng.restart(run, index)
+}
@@ -0,0 +1,93 @@
+//go:build linux
+
+package engine
+
+import (
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	// inotifyMask selects the inotify events that may change a watched file.
+	inotifyMask = syscall.IN_CREATE | syscall.IN_MODIFY | syscall.IN_CLOSE_WRITE |
+		syscall.IN_DELETE | syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO
+
+	// inotifyBufferSize is the size of the buffer inotify events are read into.
+	inotifyBufferSize = 64 * 1024
+)
+
+// watchTree sends the watched files of w that are created, changed or
+// deleted to changes until done is closed, using inotify, or by polling if
+// inotify is not available (e.g., when the watch limit is reached).
+func watchTree(done <-chan struct{}, w watchSet, changes chan<- string) {
+	if err := inotifyTree(done, w, changes); err != nil {
+		pollTree(done, w, changes)
+	}
+}
+
+// inotifyTree watches every directory of the tree of w with inotify,
+// including directories created later. It returns an error if the tree
+// cannot be watched.
+func inotifyTree(done <-chan struct{}, w watchSet, changes chan<- string) error {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return err
+	}
+	// A non-blocking descriptor is pollable, so Close interrupts Read.
+	events := os.NewFile(uintptr(fd), "inotify")
+
+	dirs := map[int32]string{}
+	add := func(rel string) error {
+		wd, err := syscall.InotifyAddWatch(fd, filepath.Join(w.root, filepath.FromSlash(rel)), inotifyMask)
+		if err != nil {
+			return err
+		}
+		dirs[int32(wd)] = rel //nolint:gosec // watch descriptors are int32 in inotify_event
+		return nil
+	}
+	if err = w.walkDirs(".", add); err != nil {
+		_ = events.Close()
+		return err
+	}
+	go func() {
+		<-done
+		_ = events.Close()
+	}()
+
+	buf := make([]byte, inotifyBufferSize)
+	for {
+		n, err := events.Read(buf)
+		if err != nil {
+			return nil
+		}
+		for off := 0; off+syscall.SizeofInotifyEvent <= n; {
+			ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[off])) //nolint:gosec // inotify events are read as raw structs
+			name := buf[off+syscall.SizeofInotifyEvent : off+syscall.SizeofInotifyEvent+int(ev.Len)]
+			off += syscall.SizeofInotifyEvent + int(ev.Len)
+
+			dir, ok := dirs[ev.Wd]
+			if ev.Mask&syscall.IN_IGNORED != 0 {
+				delete(dirs, ev.Wd)
+			}
+			if !ok || ev.Len == 0 {
+				continue
+			}
+			rel := path.Join(dir, string(bytes.TrimRight(name, "\x00")))
+			if ev.Mask&syscall.IN_ISDIR != 0 {
+				if ev.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 && !w.ignored(rel) {
+					// Files created in the new directory before it is
+					// watched are missed.
+					_ = w.walkDirs(rel, add)
+				}
+				continue
+			}
+			if w.matches(rel) && !sendChange(done, changes, rel) {
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package engine
+
+// watchTree sends the watched files of w that are created, changed or
+// deleted to changes until done is closed, by polling.
+func watchTree(done <-chan struct{}, w watchSet, changes chan<- string) {
+	pollTree(done, w, changes)
+}
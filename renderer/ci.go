@@ -0,0 +1,226 @@
+package renderer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// CIPlatform selects the log markup GroupedRenderer emits for a CI system.
+type CIPlatform int
+
+const (
+	// CINone emits no CI markup. It is the zero value, so that embedding
+	// programs do not change their output when run in CI.
+	CINone CIPlatform = iota
+
+	// CIAuto detects the platform from the environment (see DetectCIPlatform).
+	CIAuto
+
+	// CIGitHub emits GitHub Actions workflow commands: ::group:: folding
+	// and ::error:: annotations for failures.
+	CIGitHub
+
+	// CIGitLab emits GitLab CI collapsible section markers.
+	CIGitLab
+)
+
+// stepSummaryMode is the permission of a step summary file created by
+// FinalState (the CI runner normally creates it beforehand).
+const stepSummaryMode = 0o600
+
+// String returns the flag spelling of the platform ("auto", "none",
+// "github", "gitlab").
+func (p CIPlatform) String() string {
+	switch p {
+	case CINone:
+		return "none"
+	case CIAuto:
+		return "auto"
+	case CIGitHub:
+		return "github"
+	case CIGitLab:
+		return "gitlab"
+	default:
+		return fmt.Sprintf("CIPlatform(%d)", int(p))
+	}
+}
+
+// ParseCIPlatform parses "auto", "none", "github" or "gitlab"
+// (case-insensitive).
+//
+// Example:
+//
+//	platform, err := renderer.ParseCIPlatform(*ciFlag)
+func ParseCIPlatform(s string) (CIPlatform, error) {
+	switch strings.ToLower(s) {
+	case "", "auto":
+		return CIAuto, nil
+	case "none":
+		return CINone, nil
+	case "github":
+		return CIGitHub, nil
+	case "gitlab":
+		return CIGitLab, nil
+	default:
+		return CIAuto, fmt.Errorf("invalid CI platform %q (want auto, none, github or gitlab)", s)
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler with the flag spelling.
+func (p CIPlatform) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler with ParseCIPlatform, so the
+// platform can be read from configuration files.
+func (p *CIPlatform) UnmarshalText(text []byte) error {
+	v, err := ParseCIPlatform(string(text))
+	if err != nil {
+		return err
+	}
+	*p = v
+	return nil
+}
+
+// DetectCIPlatform detects the CI system from the variables its runners set:
+// GITHUB_ACTIONS=true for GitHub Actions, GITLAB_CI=true for GitLab CI.
+// It returns CINone outside of CI.
+func DetectCIPlatform() CIPlatform {
+	switch {
+	case os.Getenv("GITHUB_ACTIONS") == "true":
+		return CIGitHub
+	case os.Getenv("GITLAB_CI") == "true":
+		return CIGitLab
+	default:
+		return CINone
+	}
+}
+
+// ResolveCIPlatform resolves CIAuto with DetectCIPlatform; other values are
+// returned as is.
+func ResolveCIPlatform(p CIPlatform) CIPlatform {
+	if p == CIAuto {
+		return DetectCIPlatform()
+	}
+	return p
+}
+
+// ciFrame returns the lines printed before and after the output of b for
+// the platform, or false for CINone.
+//
+// GitHub Actions:
+//
+//	::group::build: ok in 3.2s
+//	...
+//	::endgroup::
+//	::error title=test::test: exit code 1 after 12.4s
+//
+// GitLab CI (successful sections are collapsed):
+//
+//	\e[0Ksection_start:1700000000:proc_0_build[collapsed=true]\r\e[0Kbuild: ok in 3.2s
+//	...
+//	\e[0Ksection_end:1700000003:proc_0_build\r\e[0K
+func (r *GroupedRenderer) ciFrame(b groupBlock) (string, string, bool) {
+	title := b.title + ": " + b.status
+	switch r.CI {
+	case CIGitHub:
+		footer := "::endgroup::"
+		if b.failed {
+			footer += fmt.Sprintf("\n::error title=%s::%s", escapeGitHubProperty(b.name), escapeGitHubData(b.name+": "+b.status))
+		}
+		return "::group::" + escapeGitHubData(title), footer, true
+
+	case CIGitLab:
+		id := gitlabSectionID(b)
+		options := ""
+		if !b.failed {
+			options = "[collapsed=true]"
+		}
+		header := fmt.Sprintf("\x1b[0Ksection_start:%d:%s%s\r\x1b[0K%s",
+			eventTime(b.start).Unix(), id, options, r.colorize(b.index, title))
+		footer := fmt.Sprintf("\x1b[0Ksection_end:%d:%s\r\x1b[0K", eventTime(b.end).Unix(), id)
+		return header, footer, true
+
+	case CIAuto, CINone:
+	}
+	return "", "", false
+}
+
+// gitlabSectionID returns a unique section name for b. GitLab only accepts
+// letters, digits, "_", "." and "-".
+func gitlabSectionID(b groupBlock) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_', r == '.', r == '-':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + ('a' - 'A')
+		default:
+			return '_'
+		}
+	}, b.name)
+	if b.restarts > 0 {
+		return fmt.Sprintf("proc_%d_%s_%d", b.index, name, b.restarts)
+	}
+	return fmt.Sprintf("proc_%d_%s", b.index, name)
+}
+
+// escapeGitHubData escapes the message of a GitHub workflow command.
+func escapeGitHubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+// escapeGitHubProperty escapes a property value of a GitHub workflow command.
+func escapeGitHubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+// WriteMarkdownSummaryTo writes the results of a run as a Markdown table,
+// the format of GitHub's job summaries ($GITHUB_STEP_SUMMARY):
+//
+//	### multiproc results
+//
+//	| | Process | Status | Duration |
+//	|---|---|---|---|
+//	| ✅ | build | ok | 3.2s |
+//	| ❌ | test | exit code 1 | 12.4s |
+func WriteMarkdownSummaryTo(w io.Writer, states []ProcessState) error {
+	var b strings.Builder
+	b.WriteString("### multiproc results\n\n| | Process | Status | Duration |\n|---|---|---|---|\n")
+	for i := range states {
+		ps := &states[i]
+		mark, status, duration := "⏳", "incomplete", "—"
+		if ps.Done {
+			mark, status = "✅", FormatExitError(ps.Err)
+			if ps.Err != nil {
+				mark = "❌"
+			}
+			if ps.Removed {
+				mark, status = "➖", statusRemoved
+			}
+			if !ps.StartedAt.IsZero() && !ps.FinishedAt.IsZero() {
+				duration = formatDuration(ps.FinishedAt.Sub(ps.StartedAt))
+			}
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", mark, escapeMarkdownCell(ps.Name), escapeMarkdownCell(status), duration)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// escapeMarkdownCell keeps s within one table cell.
+func escapeMarkdownCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\r", " ", "\n", " ").Replace(s)
+}
+
+// writeStepSummary appends the Markdown summary to the file at path.
+func writeStepSummary(path string, states []ProcessState) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, stepSummaryMode)
+	if err != nil {
+		return fmt.Errorf("step summary: %w", err)
+	}
+	return errors.Join(WriteMarkdownSummaryTo(f, states), f.Close())
+}
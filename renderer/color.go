@@ -0,0 +1,150 @@
+package renderer
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ColorMode controls whether renderers emit ANSI colors.
+type ColorMode int
+
+const (
+	// ColorAuto enables colors when writing to a TTY, honoring the
+	// NO_COLOR and FORCE_COLOR environment variables.
+	ColorAuto ColorMode = iota
+
+	// ColorAlways enables colors unconditionally.
+	ColorAlways
+
+	// ColorNever disables colors unconditionally.
+	ColorNever
+)
+
+// sgrReset resets all SGR attributes.
+const sgrReset = "\x1b[0m"
+
+// String returns the flag spelling of the mode ("auto", "always", "never").
+func (m ColorMode) String() string {
+	switch m {
+	case ColorAlways:
+		return "always"
+	case ColorNever:
+		return "never"
+	case ColorAuto:
+		return "auto"
+	default:
+		return fmt.Sprintf("ColorMode(%d)", int(m))
+	}
+}
+
+// ParseColorMode parses "auto", "always" or "never" (case-insensitive).
+//
+// Example:
+//
+//	mode, err := renderer.ParseColorMode(*colorFlag)
+func ParseColorMode(s string) (ColorMode, error) {
+	switch strings.ToLower(s) {
+	case "", "auto":
+		return ColorAuto, nil
+	case "always":
+		return ColorAlways, nil
+	case "never":
+		return ColorNever, nil
+	default:
+		return ColorAuto, fmt.Errorf("invalid color mode %q (want auto, always or never)", s)
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler with the flag spelling.
+func (m ColorMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler with ParseColorMode, so the
+// mode can be read from configuration files.
+func (m *ColorMode) UnmarshalText(text []byte) error {
+	v, err := ParseColorMode(string(text))
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// ColorEnabled resolves mode to a yes/no decision.
+//
+// Resolution order:
+//  1. ColorAlways / ColorNever (explicit flag) win
+//  2. FORCE_COLOR set to anything but "" or "0" enables colors
+//  3. NO_COLOR set to anything but "" disables colors (https://no-color.org)
+//  4. Otherwise colors are enabled iff isTTY
+func ColorEnabled(mode ColorMode, isTTY bool) bool {
+	switch mode {
+	case ColorAlways:
+		return true
+	case ColorNever:
+		return false
+	case ColorAuto:
+	}
+
+	if force := os.Getenv("FORCE_COLOR"); force != "" && force != "0" {
+		return true
+	}
+	if os.Getenv("NO_COLOR") != "" {
+		return false
+	}
+	return isTTY
+}
+
+// paletteSGR returns the SGR foreground sequence for palette slot i.
+// The palette skips black/white (invisible on some themes) and red
+// (reserved for failures), and cycles when there are more processes.
+func paletteSGR(i int) string {
+	codes := [...]int{36, 33, 32, 35, 34, 96, 93, 92, 95, 94}
+	return fmt.Sprintf("\x1b[%dm", codes[i%len(codes)])
+}
+
+// ColorSGR returns the SGR foreground sequence for a ProcessSpec.Color value.
+//
+// Accepted values:
+//   - Names: black, red, green, yellow, blue, magenta, cyan, white
+//   - Bright names: the above prefixed with "bright-" (e.g., "bright-cyan")
+//   - 256-color palette indexes: "0" through "255"
+//
+// Returns false for empty or unrecognized values.
+func ColorSGR(name string) (string, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return "", false
+	}
+
+	if n, err := strconv.Atoi(name); err == nil {
+		if n < 0 || n > 255 {
+			return "", false
+		}
+		return fmt.Sprintf("\x1b[38;5;%dm", n), true
+	}
+
+	base := 30
+	if rest, ok := strings.CutPrefix(name, "bright-"); ok {
+		base = 90
+		name = rest
+	}
+	for i, c := range [...]string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"} {
+		if c == name {
+			return fmt.Sprintf("\x1b[%dm", base+i), true
+		}
+	}
+	return "", false
+}
+
+// processColor returns the SGR sequence for process i: the spec's Color if
+// valid, otherwise a stable palette color derived from the process index.
+func processColor(color string, i int) string {
+	if sgr, ok := ColorSGR(color); ok {
+		return sgr
+	}
+	return paletteSGR(i)
+}
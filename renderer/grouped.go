@@ -0,0 +1,425 @@
+package renderer
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/a2y-d5l/multiproc/engine"
+)
+
+// OutputMode controls how line-oriented output from several processes is
+// arranged.
+type OutputMode int
+
+const (
+	// OutputInterleaved prints every line as it arrives, prefixed with the
+	// process name (IncrementalRenderer).
+	OutputInterleaved OutputMode = iota
+
+	// OutputGrouped buffers the output of each process and prints it as
+	// one contiguous block when the process exits (GroupedRenderer).
+	OutputGrouped
+)
+
+// DefaultSpillThreshold is the number of bytes of output GroupedRenderer
+// buffers in memory per process before spilling to a temporary file.
+const DefaultSpillThreshold = 1 << 20
+
+// String returns the flag spelling of the mode ("interleaved", "grouped").
+func (m OutputMode) String() string {
+	switch m {
+	case OutputInterleaved:
+		return "interleaved"
+	case OutputGrouped:
+		return "grouped"
+	default:
+		return fmt.Sprintf("OutputMode(%d)", int(m))
+	}
+}
+
+// ParseOutputMode parses "interleaved" or "grouped" (case-insensitive).
+//
+// Example:
+//
+//	mode, err := renderer.ParseOutputMode(*outputFlag)
+func ParseOutputMode(s string) (OutputMode, error) {
+	switch strings.ToLower(s) {
+	case "", "interleaved":
+		return OutputInterleaved, nil
+	case "grouped":
+		return OutputGrouped, nil
+	default:
+		return OutputInterleaved, fmt.Errorf("invalid output mode %q (want interleaved or grouped)", s)
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler with the flag spelling.
+func (m OutputMode) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler with ParseOutputMode, so the
+// mode can be read from configuration files.
+func (m *OutputMode) UnmarshalText(text []byte) error {
+	v, err := ParseOutputMode(string(text))
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+// GroupedRenderer buffers the output of each process and prints it as one
+// contiguous block when the process exits, like GNU parallel --group. It
+// keeps CI logs readable when many processes run at once.
+//
+// Output format:
+//
+//	=== build ===
+//	compiling...
+//	done
+//	=== build: ok in 3.2s ===
+//	=== test ===
+//	FAIL: TestParse
+//	=== test: exit code 1 after 12.4s ===
+//
+// Blocks are printed in completion order. With FailedLast, blocks of failed
+// processes are held back and printed after all others, on Finish, so the
+// failures end up at the bottom of the log. A restarted process prints one
+// block per instance.
+//
+// Output is kept in memory up to SpillThreshold bytes per process, then
+// moved to a temporary file that is removed once the block is printed.
+// Unlike ProcessState.Lines, the buffer is not subject to MaxLines or
+// MaxBytes: every line is printed.
+//
+// In CI, set CI to fold each block natively (GitHub Actions groups with
+// error annotations for failures, GitLab CI collapsible sections), and
+// StepSummary to publish a Markdown results table.
+//
+// Write errors do not interrupt the run; the first one is returned from
+// FinalState.
+//
+// Example:
+//
+//	r := renderer.NewGroupedRenderer(os.Stdout)
+//	r.FailedLast = true
+//	cfg.Renderers = []renderer.Renderer{r}
+type GroupedRenderer struct {
+	// Out receives the output blocks.
+	Out io.Writer
+
+	// SummaryOut receives the final summary. If nil, no summary is written.
+	SummaryOut io.Writer
+
+	// Summary configures the summary written to SummaryOut.
+	Summary SummaryOptions
+
+	err   error
+	specs []engine.ProcessSpec
+
+	// bufs holds the output of the current instance of each process;
+	// nil once its block has been printed.
+	bufs []*spillBuffer
+
+	// held holds the blocks of failed processes when FailedLast is set.
+	held []groupBlock
+
+	// TempDir is the directory for spilled output. If empty, os.TempDir is used.
+	TempDir string
+
+	// StepSummary is a file FinalState appends a Markdown table of the
+	// results to, typically $GITHUB_STEP_SUMMARY. If empty, none is written.
+	StepSummary string
+
+	// SpillThreshold is the number of bytes buffered in memory per process
+	// before spilling to a temporary file. If zero, DefaultSpillThreshold
+	// is used; if negative, output is never spilled.
+	SpillThreshold int
+
+	// ShowTimestamps prefixes each line with the RFC3339 UTC time it was
+	// received, which is otherwise lost by buffering.
+	ShowTimestamps bool
+
+	// Color wraps block headers and footers in the process's color.
+	Color bool
+
+	// FailedLast prints the blocks of failed processes after all others.
+	FailedLast bool
+
+	// CI replaces the block headers and footers with the log folding markup
+	// of a CI platform (see CIPlatform). CIAuto and CINone emit none; use
+	// ResolveCIPlatform to detect the platform.
+	CI CIPlatform
+}
+
+// groupBlock is a finished block waiting to be printed.
+type groupBlock struct {
+	start    time.Time
+	end      time.Time
+	buf      *spillBuffer
+	name     string
+	title    string // name, with the restart count if any
+	status   string
+	index    int
+	restarts int
+	failed   bool
+}
+
+// NewGroupedRenderer creates a GroupedRenderer writing to out.
+// The final summary is disabled until SummaryOut is set.
+func NewGroupedRenderer(out io.Writer) *GroupedRenderer {
+	return &GroupedRenderer{Out: out}
+}
+
+// Start implements Renderer. It opens a buffer for every process.
+func (r *GroupedRenderer) Start(specs []engine.ProcessSpec, _ []ProcessState) {
+	r.specs = specs
+	r.bufs = make([]*spillBuffer, len(specs))
+	for i := range r.bufs {
+		r.bufs[i] = r.newBuffer()
+	}
+}
+
+// Event implements Renderer. Lines are buffered; a completed process has
+// its block printed (or held, see FailedLast).
+func (r *GroupedRenderer) Event(ev Event, states []ProcessState) {
+	switch e := ev.(type) {
+	case LineEvent:
+		if buf := r.buffer(e.Index); buf != nil {
+			line := strings.TrimRight(e.Line, "\r\n")
+			if r.Color {
+				line = SanitizeLine(line)
+			} else {
+				line = StripANSI(line)
+			}
+			if r.ShowTimestamps {
+				t := e.Time
+				if t.IsZero() {
+					t = time.Now()
+				}
+				line = "[" + t.UTC().Format(time.RFC3339) + "] " + line
+			}
+			r.setErr(buf.writeLine(line))
+		}
+	case DoneEvent:
+		if e.Index < 0 || e.Index >= len(r.bufs) || r.bufs[e.Index] == nil {
+			return
+		}
+		b := r.block(e.Index, states)
+		r.bufs[e.Index] = nil
+		if b.failed && r.FailedLast {
+			r.held = append(r.held, b)
+			return
+		}
+		r.print(b)
+	case RestartEvent:
+		if e.Index >= 0 && e.Index < len(r.bufs) {
+			r.bufs[e.Index] = r.newBuffer()
+		}
+	case SpecEvent:
+		if e.Index == len(r.bufs) {
+			r.bufs = append(r.bufs, r.newBuffer())
+		}
+		r.specs = withSpec(r.specs, e)
+	}
+}
+
+// Tick implements Renderer. Grouped output has nothing to redraw.
+func (r *GroupedRenderer) Tick([]ProcessState) {}
+
+// Finish implements Renderer. It prints the blocks of processes that did
+// not report an exit, then the held blocks of failed processes.
+func (r *GroupedRenderer) Finish(states []ProcessState) {
+	for i, buf := range r.bufs {
+		if buf != nil {
+			r.print(r.block(i, states))
+			r.bufs[i] = nil
+		}
+	}
+	for _, b := range r.held {
+		r.print(b)
+	}
+	r.held = nil
+}
+
+// FinalState implements Renderer. It writes the summary to SummaryOut and
+// the Markdown summary to StepSummary, if set, and returns the first write
+// error.
+func (r *GroupedRenderer) FinalState(states []ProcessState) error {
+	if r.SummaryOut != nil {
+		WriteSummaryTo(r.SummaryOut, states, r.Summary)
+	}
+	if r.StepSummary != "" {
+		r.setErr(writeStepSummary(r.StepSummary, states))
+	}
+	return r.err
+}
+
+// buffer returns the buffer of process i, or nil if there is none.
+func (r *GroupedRenderer) buffer(i int) *spillBuffer {
+	if i < 0 || i >= len(r.bufs) {
+		return nil
+	}
+	return r.bufs[i]
+}
+
+// newBuffer creates an empty output buffer with the configured threshold.
+func (r *GroupedRenderer) newBuffer() *spillBuffer {
+	threshold := r.SpillThreshold
+	if threshold == 0 {
+		threshold = DefaultSpillThreshold
+	}
+	return &spillBuffer{dir: r.TempDir, threshold: threshold}
+}
+
+// block completes the block of process i with its status.
+func (r *GroupedRenderer) block(i int, states []ProcessState) groupBlock {
+	b := groupBlock{buf: r.bufs[i], index: i, name: processName(r.specs, i), status: "incomplete"}
+	b.title = b.name
+	if i < len(states) {
+		ps := &states[i]
+		b.start, b.end, b.restarts = ps.StartedAt, ps.FinishedAt, ps.Restarts
+		if ps.Restarts > 0 {
+			b.title = fmt.Sprintf("%s (restart %d)", b.name, ps.Restarts)
+		}
+		if ps.Done {
+			b.status, b.failed = finalStatus(ps), ps.Err != nil && !ps.Removed
+		}
+	}
+	return b
+}
+
+// frame returns the lines printed before and after the output of b: the
+// markers of the CI platform, or a header and a status footer, e.g.,
+// "=== build: exit code 1 after 12.4s ===".
+func (r *GroupedRenderer) frame(b groupBlock) (string, string) {
+	if header, footer, ok := r.ciFrame(b); ok {
+		return header, footer
+	}
+	return r.colorize(b.index, "=== "+b.title+" ==="), r.colorize(b.index, "=== "+b.name+": "+b.status+" ===")
+}
+
+// colorize wraps s in the color of process i when Color is set.
+func (r *GroupedRenderer) colorize(i int, s string) string {
+	if !r.Color || i >= len(r.specs) {
+		return s
+	}
+	return processColor(r.specs[i].Color, i) + s + sgrReset
+}
+
+// print writes a block to Out and releases its buffer.
+func (r *GroupedRenderer) print(b groupBlock) {
+	header, footer := r.frame(b)
+	_, err := fmt.Fprintln(r.Out, header)
+	r.setErr(err)
+	r.setErr(b.buf.writeTo(r.Out))
+	_, err = fmt.Fprintln(r.Out, footer)
+	r.setErr(err)
+	r.setErr(b.buf.close())
+}
+
+// setErr records err if it is the first error.
+func (r *GroupedRenderer) setErr(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+// spillBuffer buffers output in memory up to threshold bytes, then in a
+// temporary file in dir. A negative threshold never spills.
+type spillBuffer struct {
+	mem       bytes.Buffer
+	file      *os.File
+	dir       string
+	threshold int
+}
+
+// writeLine appends line and a newline. If the buffer cannot spill, it
+// keeps the output in memory from then on and returns the error.
+func (b *spillBuffer) writeLine(line string) error {
+	if b.file == nil && b.threshold >= 0 && b.mem.Len()+len(line)+1 > b.threshold {
+		if err := b.spill(); err != nil {
+			b.threshold = -1
+			b.mem.WriteString(line + "\n")
+			return err
+		}
+	}
+	if b.file != nil {
+		_, err := io.WriteString(b.file, line+"\n")
+		return err
+	}
+	b.mem.WriteString(line + "\n")
+	return nil
+}
+
+// spill moves the buffered output to a new temporary file.
+func (b *spillBuffer) spill() error {
+	f, err := os.CreateTemp(b.dir, "multiproc-*.log")
+	if err != nil {
+		return fmt.Errorf("spill output: %w", err)
+	}
+	if _, err = f.Write(b.mem.Bytes()); err != nil {
+		return errors.Join(fmt.Errorf("spill output: %w", err), f.Close(), os.Remove(f.Name()))
+	}
+	b.mem.Reset()
+	b.file = f
+	return nil
+}
+
+// writeTo copies the buffered output to w.
+func (b *spillBuffer) writeTo(w io.Writer) error {
+	if b.file == nil {
+		_, err := b.mem.WriteTo(w)
+		return err
+	}
+	if _, err := b.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	_, err := io.Copy(w, b.file)
+	return err
+}
+
+// eachLine calls fn with every buffered line, without its newline, and
+// stops at the first error.
+func (b *spillBuffer) eachLine(fn func(line string) error) error {
+	var src io.Reader = bytes.NewReader(b.mem.Bytes())
+	if b.file != nil {
+		if _, err := b.file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		src = b.file
+	}
+	br := bufio.NewReader(src)
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" {
+			if fnErr := fn(strings.TrimSuffix(line, "\n")); fnErr != nil {
+				return fnErr
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// close releases the buffer, removing its temporary file if any.
+func (b *spillBuffer) close() error {
+	b.mem.Reset()
+	if b.file == nil {
+		return nil
+	}
+	f := b.file
+	b.file = nil
+	return errors.Join(f.Close(), os.Remove(f.Name()))
+}
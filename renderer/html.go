@@ -0,0 +1,526 @@
+package renderer
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/a2y-d5l/multiproc/engine"
+)
+
+// DefaultHTMLTitle is the page title HTMLRenderer uses when Title is empty.
+const DefaultHTMLTitle = "multiproc report"
+
+// Line tags of the HTML log buffers: the first byte of every buffered line
+// records its stream.
+const (
+	htmlTagStdout = 'o'
+	htmlTagStderr = 'e'
+	htmlTagSystem = 's'
+)
+
+// HTMLRenderer writes a self-contained HTML report of the run: a single
+// static page with no external assets, for browsing the results of a large
+// run after the fact. Nothing is written until FinalState.
+//
+// The page contains:
+//   - A table of every process with its status, duration and exit code
+//   - The full log of each process in a collapsible section; sections of
+//     failed processes are expanded
+//   - Stderr lines highlighted, engine status lines dimmed
+//   - ANSI colors and text attributes converted to HTML
+//   - A search box that filters log lines across all processes
+//
+// Like GroupedRenderer, logs are kept in memory up to SpillThreshold bytes
+// per process, then in a temporary file removed by FinalState. They are
+// not subject to MaxLines or MaxBytes: the report holds every line.
+//
+// Write errors are returned from FinalState.
+//
+// Example:
+//
+//	f, _ := os.Create("report.html")
+//	cfg.Renderers = append(cfg.Renderers, renderer.NewHTMLRenderer(f))
+type HTMLRenderer struct {
+	// Out receives the report.
+	Out io.Writer
+
+	err   error
+	specs []engine.ProcessSpec
+
+	// logs holds the output of each process across restarts, one tagged
+	// line per line (see htmlTagStdout).
+	logs []*spillBuffer
+
+	// Title is the page title. If empty, DefaultHTMLTitle is used.
+	Title string
+
+	// TempDir is the directory for spilled logs. If empty, os.TempDir is used.
+	TempDir string
+
+	// SpillThreshold is the number of bytes buffered in memory per process
+	// before spilling to a temporary file. If zero, DefaultSpillThreshold
+	// is used; if negative, logs are never spilled.
+	SpillThreshold int
+}
+
+// NewHTMLRenderer creates an HTMLRenderer writing the report to out.
+func NewHTMLRenderer(out io.Writer) *HTMLRenderer {
+	return &HTMLRenderer{Out: out}
+}
+
+// Start implements Renderer. It opens a log buffer for every process.
+func (r *HTMLRenderer) Start(specs []engine.ProcessSpec, _ []ProcessState) {
+	r.specs = specs
+	r.logs = make([]*spillBuffer, len(specs))
+	for i := range r.logs {
+		r.logs[i] = r.newLog()
+	}
+}
+
+// newLog creates an empty log buffer with the configured threshold.
+func (r *HTMLRenderer) newLog() *spillBuffer {
+	threshold := r.SpillThreshold
+	if threshold == 0 {
+		threshold = DefaultSpillThreshold
+	}
+	return &spillBuffer{dir: r.TempDir, threshold: threshold}
+}
+
+// Event implements Renderer. It records output lines, and marks restarts
+// and removals in the log.
+func (r *HTMLRenderer) Event(ev Event, states []ProcessState) {
+	switch e := ev.(type) {
+	case LineEvent:
+		if e.Index < 0 || e.Index >= len(r.logs) {
+			return
+		}
+		tag := byte(htmlTagSystem)
+		switch e.Stream {
+		case engine.StreamStdout:
+			tag = htmlTagStdout
+		case engine.StreamStderr:
+			tag = htmlTagStderr
+		case engine.StreamSystem:
+		}
+		r.setErr(r.logs[e.Index].writeLine(string(tag) + strings.TrimRight(e.Line, "\r\n")))
+	case RestartEvent:
+		if e.Index < 0 || e.Index >= len(r.logs) || e.Index >= len(states) {
+			return
+		}
+		separator := fmt.Sprintf("%c--- restart %d ---", htmlTagSystem, states[e.Index].Restarts)
+		if e.Reason != "" {
+			separator = fmt.Sprintf("%c--- restart %d (%s) ---", htmlTagSystem, states[e.Index].Restarts, e.Reason)
+		}
+		r.setErr(r.logs[e.Index].writeLine(separator))
+	case SpecEvent:
+		if e.Index == len(r.logs) {
+			r.logs = append(r.logs, r.newLog())
+		}
+		r.specs = withSpec(r.specs, e)
+	case RemoveEvent:
+		if e.Index >= 0 && e.Index < len(r.logs) {
+			r.setErr(r.logs[e.Index].writeLine(fmt.Sprintf("%c--- removed ---", htmlTagSystem)))
+		}
+	}
+}
+
+// Tick implements Renderer. The report has nothing to redraw.
+func (r *HTMLRenderer) Tick([]ProcessState) {}
+
+// Finish implements Renderer. The report needs no teardown.
+func (r *HTMLRenderer) Finish([]ProcessState) {}
+
+// FinalState implements Renderer. It writes the report, releases the log
+// buffers and returns the first error.
+func (r *HTMLRenderer) FinalState(states []ProcessState) error {
+	title := r.Title
+	if title == "" {
+		title = DefaultHTMLTitle
+	}
+
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n")
+	fmt.Fprintf(&b, "<title>%s</title>\n<style>%s</style>\n</head>\n<body>\n", html.EscapeString(title), htmlStyle)
+	fmt.Fprintf(&b, "<h1>%s</h1>\n", html.EscapeString(title))
+	writeHTMLOverview(&b, states)
+	writeHTMLTable(&b, r.specs, states)
+	b.WriteString("<input id=\"search\" type=\"search\" placeholder=\"Search logs\" autocomplete=\"off\"> <span id=\"matches\"></span>\n")
+	r.write(b.String())
+
+	for i := range states {
+		r.writeLog(i, &states[i])
+	}
+	r.write("<script>" + htmlScript + "</script>\n</body>\n</html>\n")
+	return r.err
+}
+
+// writeLog writes the collapsible log section of process i and releases
+// its buffer.
+func (r *HTMLRenderer) writeLog(i int, ps *ProcessState) {
+	class, _ := htmlStatus(ps)
+	open := ""
+	if class == "fail" {
+		open = " open"
+	}
+	r.write(fmt.Sprintf("<details id=\"p%d\" class=\"%s\"%s>\n<summary>%s <span class=\"status\">%s</span></summary>\n<div class=\"log\">",
+		i, class, open, html.EscapeString(r.name(i, ps)), html.EscapeString(finalStatusOrIncomplete(ps))))
+
+	if i < len(r.logs) {
+		var conv ansiHTML
+		var b strings.Builder
+		r.setErr(r.logs[i].eachLine(func(line string) error {
+			if line == "" {
+				return nil
+			}
+			b.Reset()
+			switch line[0] {
+			case htmlTagStdout:
+				b.WriteString("<div class=\"line\">")
+			case htmlTagStderr:
+				b.WriteString("<div class=\"line err\">")
+			default:
+				b.WriteString("<div class=\"line sys\">")
+			}
+			conv.convert(&b, line[1:])
+			b.WriteString("</div>\n")
+			_, err := io.WriteString(r.Out, b.String())
+			return err
+		}))
+		r.setErr(r.logs[i].close())
+	}
+	r.write("</div>\n</details>\n")
+}
+
+// name returns the display name of process i.
+func (r *HTMLRenderer) name(i int, ps *ProcessState) string {
+	if name := processName(r.specs, i); name != "" {
+		return name
+	}
+	return ps.Name
+}
+
+// write writes s to Out, recording the first error.
+func (r *HTMLRenderer) write(s string) {
+	_, err := io.WriteString(r.Out, s)
+	r.setErr(err)
+}
+
+// setErr records err if it is the first error.
+func (r *HTMLRenderer) setErr(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+// writeHTMLOverview writes the one-line result of the run.
+func writeHTMLOverview(b *strings.Builder, states []ProcessState) {
+	var failed int
+	var start, end time.Time
+	for i := range states {
+		ps := &states[i]
+		if ps.Err != nil && !ps.Removed {
+			failed++
+		}
+		if !ps.StartedAt.IsZero() && (start.IsZero() || ps.StartedAt.Before(start)) {
+			start = ps.StartedAt
+		}
+		if ps.FinishedAt.After(end) {
+			end = ps.FinishedAt
+		}
+	}
+	fmt.Fprintf(b, "<p class=\"overview\">%d processes, %d failed, exit code %d", len(states), failed, ExitCodeFromStates(states))
+	if !start.IsZero() {
+		fmt.Fprintf(b, " &middot; started %s", start.Format(time.RFC3339))
+		if end.After(start) {
+			fmt.Fprintf(b, " &middot; took %s", formatDuration(end.Sub(start)))
+		}
+	}
+	b.WriteString("</p>\n")
+}
+
+// writeHTMLTable writes the process table, linking each row to its log.
+func writeHTMLTable(b *strings.Builder, specs []engine.ProcessSpec, states []ProcessState) {
+	b.WriteString("<table>\n<thead><tr><th>Process</th><th>Status</th><th>Duration</th><th>Exit code</th></tr></thead>\n<tbody>\n")
+	for i := range states {
+		ps := &states[i]
+		name := processName(specs, i)
+		if name == "" {
+			name = ps.Name
+		}
+		class, status := htmlStatus(ps)
+		duration, code := "&mdash;", "&mdash;"
+		if !ps.StartedAt.IsZero() && ps.Done {
+			duration = formatDuration(ps.Duration())
+		}
+		if ps.Done && !ps.Canceled {
+			if c := ExitCode(ps.Err); c >= 0 {
+				code = strconv.Itoa(c)
+			}
+		}
+		fmt.Fprintf(b, "<tr class=\"%s\"><td><a href=\"#p%d\">%s</a></td><td>%s</td><td>%s</td><td>%s</td></tr>\n",
+			class, i, html.EscapeString(name), html.EscapeString(status), duration, code)
+	}
+	b.WriteString("</tbody>\n</table>\n")
+}
+
+// htmlStatus returns the CSS class ("ok", "fail" or "skip") and the status
+// text of a process.
+func htmlStatus(ps *ProcessState) (string, string) {
+	switch {
+	case ps.Removed:
+		return "skip", statusRemoved
+	case !ps.Done:
+		return "skip", "incomplete"
+	case ps.Canceled:
+		return "skip", "cancelled: " + FormatExitError(ps.Err)
+	case ps.Err != nil:
+		return "fail", FormatExitError(ps.Err)
+	default:
+		return "ok", "ok"
+	}
+}
+
+// finalStatusOrIncomplete returns finalStatus for a finished process and
+// "incomplete" otherwise.
+func finalStatusOrIncomplete(ps *ProcessState) string {
+	if !ps.Done && !ps.Removed {
+		return "incomplete"
+	}
+	return finalStatus(ps)
+}
+
+// ansiHTML converts text with ANSI escape sequences to HTML. SGR
+// attributes carry over from one line to the next, like on a terminal;
+// other escape sequences and control characters are dropped.
+type ansiHTML struct {
+	fg, bg                       string
+	bold, dim, italic, underline bool
+}
+
+// convert appends the HTML of line to b.
+func (a *ansiHTML) convert(b *strings.Builder, line string) {
+	open := a.openSpan(b)
+	text := 0 // start of the text not yet written
+	for i := 0; i < len(line); {
+		kind, n, _ := nextToken(line[i:])
+		if kind == tokenText || kind == tokenTab {
+			i += n
+			continue
+		}
+		b.WriteString(html.EscapeString(line[text:i]))
+		if kind == tokenSGR {
+			if open {
+				b.WriteString("</span>")
+			}
+			a.apply(sgrParams(line[i : i+n]))
+			open = a.openSpan(b)
+		}
+		i += n
+		text = i
+	}
+	b.WriteString(html.EscapeString(line[text:]))
+	if open {
+		b.WriteString("</span>")
+	}
+}
+
+// openSpan writes a <span> with the current attributes, if any, and
+// reports whether it did.
+func (a *ansiHTML) openSpan(b *strings.Builder) bool {
+	var style []string
+	if a.fg != "" {
+		style = append(style, "color:"+a.fg)
+	}
+	if a.bg != "" {
+		style = append(style, "background:"+a.bg)
+	}
+	if a.bold {
+		style = append(style, "font-weight:bold")
+	}
+	if a.dim {
+		style = append(style, "opacity:.6")
+	}
+	if a.italic {
+		style = append(style, "font-style:italic")
+	}
+	if a.underline {
+		style = append(style, "text-decoration:underline")
+	}
+	if len(style) == 0 {
+		return false
+	}
+	b.WriteString("<span style=\"" + strings.Join(style, ";") + "\">")
+	return true
+}
+
+// SGR parameters understood by ansiHTML.
+const (
+	sgrParamReset       = 0
+	sgrParamBold        = 1
+	sgrParamDim         = 2
+	sgrParamItalic      = 3
+	sgrParamUnderline   = 4
+	sgrParamNormal      = 22
+	sgrParamNoItalic    = 23
+	sgrParamNoUnderline = 24
+	sgrParamFg          = 30 // 30–37: basic foreground colors
+	sgrParamFgExtended  = 38
+	sgrParamFgDefault   = 39
+	sgrParamBg          = 40 // 40–47: basic background colors
+	sgrParamBgExtended  = 48
+	sgrParamBgDefault   = 49
+	sgrParamFgBright    = 90  // 90–97: bright foreground colors
+	sgrParamBgBright    = 100 // 100–107: bright background colors
+
+	// sgrColors is the number of basic (and of bright) colors.
+	sgrColors = 8
+
+	// sgrPalette and sgrTrueColor select the form of an extended color.
+	sgrPalette   = 5
+	sgrTrueColor = 2
+)
+
+// apply applies the parameters of an SGR sequence.
+func (a *ansiHTML) apply(params []int) {
+	if len(params) == 0 {
+		params = []int{sgrParamReset}
+	}
+	for i := 0; i < len(params); i++ {
+		p := params[i]
+		switch {
+		case p == sgrParamReset:
+			*a = ansiHTML{}
+		case p == sgrParamBold:
+			a.bold = true
+		case p == sgrParamDim:
+			a.dim = true
+		case p == sgrParamItalic:
+			a.italic = true
+		case p == sgrParamUnderline:
+			a.underline = true
+		case p == sgrParamNormal:
+			a.bold, a.dim = false, false
+		case p == sgrParamNoItalic:
+			a.italic = false
+		case p == sgrParamNoUnderline:
+			a.underline = false
+		case p >= sgrParamFg && p < sgrParamFg+sgrColors:
+			a.fg = ansiColor(p - sgrParamFg)
+		case p >= sgrParamFgBright && p < sgrParamFgBright+sgrColors:
+			a.fg = ansiColor(p - sgrParamFgBright + sgrColors)
+		case p == sgrParamFgDefault:
+			a.fg = ""
+		case p >= sgrParamBg && p < sgrParamBg+sgrColors:
+			a.bg = ansiColor(p - sgrParamBg)
+		case p >= sgrParamBgBright && p < sgrParamBgBright+sgrColors:
+			a.bg = ansiColor(p - sgrParamBgBright + sgrColors)
+		case p == sgrParamBgDefault:
+			a.bg = ""
+		case p == sgrParamFgExtended, p == sgrParamBgExtended:
+			color, n := extendedColor(params[i+1:])
+			i += n
+			if p == sgrParamFgExtended {
+				a.fg = color
+			} else {
+				a.bg = color
+			}
+		}
+	}
+}
+
+// extendedColor parses the arguments of an extended color (38/48): "5;n"
+// for the 256-color palette or "2;r;g;b" for true color. It returns the CSS
+// color, or "" if invalid, and the number of parameters consumed.
+func extendedColor(params []int) (string, int) {
+	const (
+		paletteArgs   = 2
+		trueColorArgs = 4
+		paletteSize   = 256
+	)
+	switch {
+	case len(params) >= paletteArgs && params[0] == sgrPalette:
+		if params[1] < 0 || params[1] >= paletteSize {
+			return "", paletteArgs
+		}
+		return ansiColor(params[1]), paletteArgs
+	case len(params) >= trueColorArgs && params[0] == sgrTrueColor:
+		return fmt.Sprintf("#%02x%02x%02x", byte(params[1]), byte(params[2]), byte(params[3])), trueColorArgs
+	default:
+		return "", len(params)
+	}
+}
+
+// ansiColor returns the CSS color of entry n of the xterm 256-color palette:
+// 16 basic colors, a 6×6×6 color cube, then 24 shades of gray.
+func ansiColor(n int) string {
+	basic := [...]string{
+		"#000000", "#cd3131", "#0dbc79", "#e5e510", "#2472c8", "#bc3fbc", "#11a8cd", "#e5e5e5",
+		"#666666", "#f14c4c", "#23d18b", "#f5f543", "#3b8eea", "#d670d6", "#29b8db", "#ffffff",
+	}
+	const (
+		cubeStart = 16
+		cubeSize  = 6
+		grayStart = 232
+		grayBase  = 8
+		grayStep  = 10
+	)
+	switch {
+	case n < cubeStart:
+		return basic[n]
+	case n < grayStart:
+		levels := [cubeSize]int{0, 95, 135, 175, 215, 255}
+		n -= cubeStart
+		return fmt.Sprintf("#%02x%02x%02x", levels[n/(cubeSize*cubeSize)], levels[n/cubeSize%cubeSize], levels[n%cubeSize])
+	default:
+		gray := grayBase + (n-grayStart)*grayStep
+		return fmt.Sprintf("#%02x%02x%02x", gray, gray, gray)
+	}
+}
+
+// htmlStyle is the stylesheet of the HTML report.
+const htmlStyle = `
+body{font-family:system-ui,sans-serif;margin:2em;color:#1f2328;background:#fff}
+table{border-collapse:collapse;margin:1em 0}
+th,td{padding:.3em .8em;border-bottom:1px solid #d0d7de;text-align:left}
+tr.ok td:nth-child(2){color:#1a7f37}
+tr.fail td:nth-child(2){color:#cf222e;font-weight:bold}
+tr.skip td:nth-child(2){color:#9a6700}
+#search{width:24em;padding:.3em;margin:1em 0}
+details{border:1px solid #d0d7de;border-radius:6px;margin:.5em 0}
+details.fail{border-color:#cf222e}
+summary{cursor:pointer;padding:.4em .8em;font-weight:bold}
+summary .status{font-weight:normal;color:#656d76}
+details.fail summary .status{color:#cf222e}
+.log{font-family:ui-monospace,monospace;font-size:13px;background:#0d1117;color:#e6edf3;padding:.5em .8em;overflow-x:auto}
+.line{white-space:pre-wrap;word-break:break-all;min-height:1.2em}
+.line.err{background:#3d1418;border-left:3px solid #f85149;padding-left:.4em}
+.line.sys{color:#8b949e;font-style:italic}
+.line.hidden{display:none}
+`
+
+// htmlScript filters log lines by the search box: sections with a match
+// are expanded, others collapsed, and the match count is shown.
+const htmlScript = `
+(function(){
+  var input=document.getElementById("search"),count=document.getElementById("matches");
+  var sections=document.querySelectorAll("details");
+  input.addEventListener("input",function(){
+    var q=input.value.toLowerCase(),total=0;
+    sections.forEach(function(d){
+      var n=0;
+      d.querySelectorAll(".line").forEach(function(l){
+        var hit=!q||l.textContent.toLowerCase().indexOf(q)>=0;
+        l.classList.toggle("hidden",!hit);
+        if(q&&hit)n++;
+      });
+      if(q)d.open=n>0;else d.open=d.classList.contains("fail");
+      total+=n;
+    });
+    count.textContent=q?total+" matching lines":"";
+  });
+})();
+`
@@ -2,6 +2,8 @@ package renderer
 
 import (
 	"fmt"
+	"io"
+	"os"
 	"strings"
 	"time"
 
@@ -9,8 +11,10 @@ import (
 )
 
 // RenderIncremental renders events directly to standard output without
-// clearing the screen or buffering. This is the primary renderer for
-// non-TTY environments such as CI/CD pipelines, log files, and piped output.
+// clearing the screen or buffering. It is the primary renderer for non-TTY
+// environments such as CI/CD pipelines, log files, and piped output, and a
+// convenience wrapper around IncrementalRenderer, which callers that drive
+// rendering themselves use directly.
 //
 // Behavior:
 //   - Processes events as they arrive (no buffering)
@@ -20,8 +24,12 @@ import (
 //   - No screen clearing or cursor manipulation
 //
 // Event handling:
-//   - lineEvent: Print line with prefix and optional timestamp
-//   - doneEvent: Print completion status with prefix
+//   - LineEvent: Print line with prefix and optional timestamp
+//   - DoneEvent: Print completion status with prefix
+//   - RestartEvent: Print "restarting..." with prefix
+//   - SpecEvent: Print "starting..." for an added process, "configuration
+//     changed" for a changed one
+//   - RemoveEvent: Print "removed" with prefix
 //
 // Output format (without timestamps):
 //
@@ -42,7 +50,7 @@ import (
 //   - ">>> %s >>>": >>> ProcessName >>> line
 //
 // Parameters:
-//   - ev: Event to render (lineEvent or doneEvent)
+//   - ev: Event to render (see Event handling above)
 //   - specs: Process specifications (for name lookup)
 //   - states: Process states (reserved for future use)
 //   - showTimestamps: If true, prefix lines with RFC3339 timestamp
@@ -53,7 +61,8 @@ import (
 //   - Easily parseable by log aggregators
 //   - Works with grep, awk, and other text tools
 //   - Timestamps enable timing analysis
-//   - No ANSI escape codes (clean logs)
+//   - No ANSI escape codes (clean logs); IncrementalRenderer keeps colors
+//     when Color is set
 //
 // Example usage:
 //
@@ -61,56 +70,186 @@ import (
 //	    renderer.RenderIncremental(ev, specs, states, true, "[%s]")
 //	}
 func RenderIncremental(ev Event, specs []engine.ProcessSpec, _ []ProcessState, showTimestamps bool, logPrefix string) {
-	// Default prefix format if not specified
-	if logPrefix == "" {
-		logPrefix = "[%s]"
+	r := NewIncrementalRenderer(os.Stdout, showTimestamps, logPrefix)
+	r.specs = specs
+	r.render(ev)
+}
+
+// IncrementalRenderer is the Renderer implementation behind RenderIncremental.
+// It writes one line per event to Out and never moves the cursor, which makes
+// it suitable for CI logs, files and pipes.
+//
+// Example:
+//
+//	r := renderer.NewIncrementalRenderer(os.Stdout, true, "[%s]")
+//	r.SummaryOut = os.Stderr
+//	cfg.Renderers = []renderer.Renderer{r}
+type IncrementalRenderer struct {
+	// Out receives the prefixed output lines.
+	Out io.Writer
+
+	// SummaryOut receives the final summary. If nil, no summary is written.
+	SummaryOut io.Writer
+
+	// Summary configures the summary written to SummaryOut.
+	Summary SummaryOptions
+
+	// LogPrefix is the format string for the process name (must include "%s").
+	// If empty, defaults to "[%s]".
+	LogPrefix string
+
+	specs []engine.ProcessSpec
+
+	// prefixes caches the formatted (padded, colored) prefix per process.
+	// Built by Start; nil when the renderer is driven without Start.
+	prefixes []string
+
+	// ShowTimestamps prefixes each line with an RFC3339 UTC timestamp.
+	ShowTimestamps bool
+
+	// Color wraps each prefix in the process's color (ProcessSpec.Color,
+	// or a stable palette color). Use ColorEnabled to derive it from a
+	// ColorMode and the NO_COLOR/FORCE_COLOR environment variables.
+	Color bool
+
+	// Align pads every prefix to the width of the longest one, so output
+	// from processes with different name lengths lines up.
+	Align bool
+
+	// AnnounceStart makes Start print a "starting..." line for every
+	// process. runner.DefaultRenderer sets it for non-TTY output, where no
+	// screen shows the processes that have not written anything yet.
+	AnnounceStart bool
+}
+
+// NewIncrementalRenderer creates an IncrementalRenderer writing to out.
+// The final summary is disabled until SummaryOut is set.
+func NewIncrementalRenderer(out io.Writer, showTimestamps bool, logPrefix string) *IncrementalRenderer {
+	return &IncrementalRenderer{
+		Out:            out,
+		LogPrefix:      logPrefix,
+		ShowTimestamps: showTimestamps,
+	}
+}
+
+// Start implements Renderer. It prints a "starting..." line for every
+// process if AnnounceStart is set.
+func (r *IncrementalRenderer) Start(specs []engine.ProcessSpec, _ []ProcessState) {
+	r.specs = specs
+	r.prefixes = r.buildPrefixes()
+	if !r.AnnounceStart {
+		return
+	}
+	for i := range specs {
+		r.printLine(i, "starting...")
+	}
+}
+
+// Event implements Renderer. It prints the line or completion status.
+func (r *IncrementalRenderer) Event(ev Event, _ []ProcessState) {
+	r.render(ev)
+}
+
+// Tick implements Renderer. Incremental output has nothing to redraw.
+func (r *IncrementalRenderer) Tick([]ProcessState) {}
+
+// Finish implements Renderer. Incremental output needs no teardown.
+func (r *IncrementalRenderer) Finish([]ProcessState) {}
+
+// FinalState implements Renderer. It writes the summary to SummaryOut, if set.
+func (r *IncrementalRenderer) FinalState(states []ProcessState) error {
+	if r.SummaryOut != nil {
+		WriteSummaryTo(r.SummaryOut, states, r.Summary)
 	}
+	return nil
+}
 
+// render prints a single event.
+func (r *IncrementalRenderer) render(ev Event) {
 	switch e := ev.(type) {
-	case lineEvent:
-		if e.Index < 0 || e.Index >= len(specs) {
-			return
+	case LineEvent:
+		r.printLine(e.Index, r.clean(strings.TrimRight(e.Line, "\r\n")))
+	case DoneEvent:
+		r.printLine(e.Index, FormatExitError(e.Err))
+	case RestartEvent:
+		r.printLine(e.Index, "restarting...")
+	case SpecEvent:
+		added := e.Index == len(r.specs)
+		r.specs = withSpec(r.specs, e)
+		if r.prefixes != nil {
+			r.prefixes = r.buildPrefixes()
 		}
-		name := specs[e.Index].Name
-		if name == "" {
-			name = fmt.Sprintf("proc-%d", e.Index)
-		}
-		line := strings.TrimRight(e.Line, "\r\n")
-
-		// Build the output line with optional timestamp and configurable prefix
-		var output string
-		if showTimestamps {
-			timestamp := time.Now().UTC().Format(time.RFC3339)
-			prefix := fmt.Sprintf(logPrefix, name)
-			output = fmt.Sprintf("[%s] %s %s", timestamp, prefix, line)
+		if added {
+			r.printLine(e.Index, "starting...")
 		} else {
-			prefix := fmt.Sprintf(logPrefix, name)
-			output = fmt.Sprintf("%s %s", prefix, line)
+			r.printLine(e.Index, "configuration changed")
 		}
-		fmt.Println(output)
+	case RemoveEvent:
+		r.printLine(e.Index, "removed")
+	}
+}
 
-	case doneEvent:
-		if e.Index < 0 || e.Index >= len(specs) {
-			return
-		}
-		name := specs[e.Index].Name
-		if name == "" {
-			name = fmt.Sprintf("proc-%d", e.Index)
+// printLine writes text for process idx with the configured prefix and
+// optional timestamp. Out-of-range indices are ignored.
+func (r *IncrementalRenderer) printLine(idx int, text string) {
+	if idx < 0 || idx >= len(r.specs) {
+		return
+	}
+	var prefix string
+	if idx < len(r.prefixes) {
+		prefix = r.prefixes[idx]
+	} else {
+		prefix = r.plainPrefix(idx)
+	}
+
+	if r.ShowTimestamps {
+		timestamp := time.Now().UTC().Format(time.RFC3339)
+		fmt.Fprintf(r.Out, "[%s] %s %s\n", timestamp, prefix, text)
+		return
+	}
+	fmt.Fprintf(r.Out, "%s %s\n", prefix, text)
+}
+
+// clean removes the escape sequences of an output line that could corrupt
+// the log: all of them without Color, all but colors with it.
+func (r *IncrementalRenderer) clean(line string) string {
+	if r.Color {
+		return SanitizeLine(line)
+	}
+	return StripANSI(line)
+}
+
+// plainPrefix formats the uncolored, unpadded prefix for process idx.
+func (r *IncrementalRenderer) plainPrefix(idx int) string {
+	logPrefix := r.LogPrefix
+	if logPrefix == "" {
+		logPrefix = "[%s]"
+	}
+	return fmt.Sprintf(logPrefix, processName(r.specs, idx))
+}
+
+// buildPrefixes formats the prefix of every process, padded to a common
+// width when Align is set and colored when Color is set. Padding goes
+// outside the color so that the escape codes do not count toward the width.
+func (r *IncrementalRenderer) buildPrefixes() []string {
+	prefixes := make([]string, len(r.specs))
+	width := 0
+	for i := range r.specs {
+		prefixes[i] = r.plainPrefix(i)
+		width = max(width, DisplayWidth(prefixes[i]))
+	}
+
+	for i, p := range prefixes {
+		pad := ""
+		if r.Align {
+			pad = strings.Repeat(" ", width-DisplayWidth(p))
 		}
-		status := FormatExitError(e.Err)
-
-		// Build the completion message with optional timestamp
-		var output string
-		if showTimestamps {
-			timestamp := time.Now().UTC().Format(time.RFC3339)
-			prefix := fmt.Sprintf(logPrefix, name)
-			output = fmt.Sprintf("[%s] %s %s", timestamp, prefix, status)
-		} else {
-			prefix := fmt.Sprintf(logPrefix, name)
-			output = fmt.Sprintf("%s %s", prefix, status)
+		if r.Color {
+			p = processColor(r.specs[i].Color, i) + p + sgrReset
 		}
-		fmt.Println(output)
+		prefixes[i] = p + pad
 	}
+	return prefixes
 }
 
 // RenderRequest is a signal type used to trigger rendering in full-screen mode.
@@ -0,0 +1,415 @@
+package renderer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// inputBufferSize is the size of the buffer for one terminal read.
+const inputBufferSize = 256
+
+// helpLines returns the help overlay of the interactive view.
+func helpLines() []string {
+	return []string{
+		"Keys",
+		"",
+		"  ↑ k / ↓ j     select a process (scroll when expanded)",
+		"  Enter         expand or collapse the selected process",
+		"  Tab           select the next process",
+		"  PgUp / PgDn   scroll a page (also Ctrl+B / Ctrl+F)",
+		"  Home g / End G  oldest / newest output",
+		"  /             search the selected process",
+		"  n / N         older / newer match",
+		"  r             restart the selected process",
+		"  s             stop the selected process",
+		"  c             collapse finished processes",
+		"  ?             toggle this help",
+		"  q, Ctrl+C     cancel the run",
+		"  Esc           back",
+	}
+}
+
+// screenView is the state of the interactive full-screen view.
+// It is only accessed from the renderer's hooks, never concurrently.
+type screenView struct {
+	// status is a message shown in the footer until the next key press.
+	status string
+
+	// query is the active search query; input is the query being typed.
+	query string
+	input string
+
+	// seen counts the lines appended per process since Start, so that a
+	// line keeps its absolute number when older lines are evicted.
+	seen []int
+
+	// selected is the index of the selected process.
+	selected int
+
+	// scroll is how many rows the expanded view is scrolled back from the tail.
+	scroll int
+
+	// match is the absolute line number of the current search match, -1 if none.
+	match int
+
+	// expanded shows the selected process alone, at full height.
+	expanded bool
+
+	// collapse hides the output of finished processes.
+	collapse bool
+
+	// help shows the help overlay.
+	help bool
+
+	// searching is true while a query is being typed.
+	searching bool
+}
+
+// newScreenView creates the view state for states.
+func newScreenView(states []ProcessState) *screenView {
+	v := &screenView{match: -1, seen: make([]int, len(states))}
+	for i := range states {
+		v.seen[i] = len(states[i].Lines)
+	}
+	return v
+}
+
+// clamp keeps the selection within n processes.
+func (v *screenView) clamp(n int) {
+	v.selected = max(0, min(v.selected, n-1))
+}
+
+// lineBase returns the absolute line number of ps.Lines[0] for process i.
+func (v *screenView) lineBase(i int, ps *ProcessState) int {
+	if i >= len(v.seen) {
+		return 0
+	}
+	return v.seen[i] - len(ps.Lines)
+}
+
+// observe updates the view for an event, after it was applied to states,
+// and tracks processes added during the run.
+// While the expanded view is scrolled back, it stays anchored on the same
+// lines as new output arrives.
+func (v *screenView) observe(ev Event, l screenLayout) {
+	if s, ok := ev.(SpecEvent); ok && s.Index == len(v.seen) {
+		v.seen = append(v.seen, 0)
+		return
+	}
+	e, ok := ev.(LineEvent)
+	if !ok || e.Index < 0 || e.Index >= len(v.seen) {
+		return
+	}
+	v.seen[e.Index]++
+	if v.expanded && v.scroll > 0 && e.Index == v.selected {
+		v.scroll += len(l.bodyLines(e.Line))
+	}
+}
+
+// handle applies a key press to the view. ctl may be nil, in which case
+// process actions report that they are not available.
+func (v *screenView) handle(k key, states []ProcessState, ctl Controller, l screenLayout) {
+	if len(states) == 0 {
+		return
+	}
+	v.clamp(len(states))
+	v.status = ""
+
+	if v.searching {
+		v.handleSearchKey(k, states, l)
+		return
+	}
+	if v.help {
+		if k.code == keyEsc || (k.code == keyRune && k.r == '?') {
+			v.help = false
+		}
+		return
+	}
+
+	page := max(l.height-3, 1) // body rows minus one, for context
+	switch k.code {
+	case keyUp:
+		v.move(-1, len(states))
+	case keyDown:
+		v.move(1, len(states))
+	case keyPageUp:
+		if v.expanded {
+			v.scroll += page
+		}
+	case keyPageDown:
+		if v.expanded {
+			v.scroll = max(v.scroll-page, 0)
+		}
+	case keyHome:
+		v.home()
+	case keyEnd:
+		v.end(len(states))
+	case keyEnter:
+		v.expanded = !v.expanded
+		v.scroll = 0
+	case keyTab:
+		v.selectProcess((v.selected + 1) % len(states))
+	case keyEsc:
+		if v.expanded {
+			v.expanded = false
+			v.scroll = 0
+		} else {
+			v.query, v.match = "", -1
+		}
+	case keyBackspace:
+	case keyRune:
+		v.handleRune(k.r, states, ctl, l)
+	}
+}
+
+// handleRune applies a printable key press to the view.
+func (v *screenView) handleRune(r rune, states []ProcessState, ctl Controller, l screenLayout) {
+	name := states[v.selected].Name
+	switch r {
+	case 'k':
+		v.move(-1, len(states))
+	case 'j':
+		v.move(1, len(states))
+	case 'g':
+		v.home()
+	case 'G':
+		v.end(len(states))
+	case '/':
+		v.searching, v.input = true, ""
+	case 'n':
+		v.find(states, l, -1)
+	case 'N':
+		v.find(states, l, 1)
+	case 'c':
+		v.collapse = !v.collapse
+	case '?':
+		v.help = true
+	case 'r':
+		v.act(ctl, "restart", "restarting", name, func(c Controller) error { return c.Restart(v.selected) })
+	case 's':
+		v.act(ctl, "stop", "stopping", name, func(c Controller) error { return c.Stop(v.selected) })
+	case 'q':
+		if ctl == nil {
+			v.status = "cancel: not available"
+			return
+		}
+		ctl.Cancel()
+		v.status = "cancelling..."
+	}
+}
+
+// act runs a process action through ctl and reports the outcome in the
+// footer, e.g., "restarting build..." or "stop build: process is not running".
+func (v *screenView) act(ctl Controller, verb, progress, name string, action func(Controller) error) {
+	if ctl == nil {
+		v.status = verb + ": not available"
+		return
+	}
+	if err := action(ctl); err != nil {
+		v.status = fmt.Sprintf("%s %s: %v", verb, name, err)
+		return
+	}
+	v.status = fmt.Sprintf("%s %s...", progress, name)
+}
+
+// handleSearchKey edits the query being typed; Enter runs the search.
+func (v *screenView) handleSearchKey(k key, states []ProcessState, l screenLayout) {
+	switch k.code {
+	case keyEnter:
+		v.searching = false
+		v.query, v.match = v.input, -1
+		v.find(states, l, -1)
+	case keyEsc:
+		v.searching = false
+	case keyBackspace:
+		if r := []rune(v.input); len(r) > 0 {
+			v.input = string(r[:len(r)-1])
+		}
+	case keyRune:
+		v.input += string(k.r)
+	case keyUp, keyDown, keyPageUp, keyPageDown, keyHome, keyEnd, keyTab:
+	}
+}
+
+// move moves the selection, or scrolls the expanded view, by d.
+// Negative values move up (towards older output).
+func (v *screenView) move(d, n int) {
+	if v.expanded {
+		v.scroll = max(v.scroll-d, 0)
+		return
+	}
+	v.selectProcess(max(0, min(v.selected+d, n-1)))
+}
+
+// home shows the oldest output, or selects the first process.
+func (v *screenView) home() {
+	if v.expanded {
+		v.scroll = math.MaxInt // clamped when the frame is laid out
+		return
+	}
+	v.selectProcess(0)
+}
+
+// end shows the newest output, or selects the last process.
+func (v *screenView) end(n int) {
+	if v.expanded {
+		v.scroll = 0
+		return
+	}
+	v.selectProcess(n - 1)
+}
+
+// selectProcess selects process i, resetting scroll and search position.
+func (v *screenView) selectProcess(i int) {
+	if i != v.selected {
+		v.selected, v.scroll, v.match = i, 0, -1
+	}
+}
+
+// find moves to the next match of the query in the selected process:
+// dir -1 searches towards older lines, 1 towards newer ones. Without a
+// current match, the search starts from the newest line. A match expands
+// the process and scrolls it into view.
+func (v *screenView) find(states []ProcessState, l screenLayout, dir int) {
+	if v.query == "" {
+		return
+	}
+	ps := &states[v.selected]
+	base := v.lineBase(v.selected, ps)
+	query := strings.ToLower(v.query)
+
+	j := len(ps.Lines) - 1
+	if v.match >= base {
+		j = v.match - base + dir
+	}
+	for ; j >= 0 && j < len(ps.Lines); j += dir {
+		if lineMatches(ps.Lines[j], query) {
+			break
+		}
+	}
+	if j < 0 || j >= len(ps.Lines) {
+		if v.match < 0 {
+			v.status = fmt.Sprintf("/%s: not found in %s", v.query, ps.Name)
+		} else {
+			v.status = fmt.Sprintf("/%s: no more matches", v.query)
+		}
+		return
+	}
+
+	v.match = base + j
+	v.expanded = true
+
+	// Center the match: count the rows below it.
+	below := 0
+	for _, line := range ps.Lines[j+1:] {
+		below += len(l.bodyLines(line))
+	}
+	v.scroll = max(below-max(l.height-2, 0)/2, 0)
+
+	pos, total := 0, 0
+	for k, line := range ps.Lines {
+		if lineMatches(line, query) {
+			total++
+			if k <= j {
+				pos++
+			}
+		}
+	}
+	v.status = fmt.Sprintf("/%s: match %d of %d · n older · N newer", v.query, pos, total)
+}
+
+// lineMatches reports whether line contains the lowercase query, ignoring
+// case and escape sequences (so colored words still match).
+func lineMatches(line, query string) bool {
+	return strings.Contains(strings.ToLower(StripANSI(line)), query)
+}
+
+// startInput starts reading keys from r.Input. If Input is an *os.File, it
+// must be a terminal; it is switched to raw mode until Restore. Otherwise
+// (not a terminal, or raw mode unsupported) the view stays display-only.
+func (r *ScreenRenderer) startInput(states []ProcessState) {
+	poll := false
+	if f, ok := r.Input.(*os.File); ok {
+		unraw, err := makeRaw(f)
+		if err != nil {
+			return
+		}
+		r.mu.Lock()
+		r.unraw = unraw
+		r.mu.Unlock()
+		poll = true
+	}
+
+	r.view = newScreenView(states)
+	r.stopInput = make(chan struct{})
+	r.inputDone = make(chan struct{})
+	go r.readInput(r.Input, poll, r.stopInput, r.inputDone)
+}
+
+// stopReading stops the input reader. For a terminal, it waits for the
+// reader to return (at most one read timeout) so that no read is pending
+// when the terminal mode is restored.
+func (r *ScreenRenderer) stopReading() {
+	if r.stopInput == nil {
+		return
+	}
+	close(r.stopInput)
+	r.stopInput = nil
+
+	r.mu.Lock()
+	raw := r.unraw != nil
+	r.mu.Unlock()
+	if raw {
+		<-r.inputDone
+	}
+}
+
+// readInput decodes key presses from in, queues them and asks for a
+// redraw. It returns when stop is closed or in fails. With poll set, in is
+// a raw terminal whose reads time out with io.EOF, which is not an error.
+func (r *ScreenRenderer) readInput(in io.Reader, poll bool, stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+
+	buf := make([]byte, inputBufferSize)
+	for {
+		n, err := in.Read(buf)
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		if keys := decodeKeys(buf[:n]); len(keys) > 0 {
+			r.keyMu.Lock()
+			r.keys = append(r.keys, keys...)
+			r.keyMu.Unlock()
+			if r.ctl != nil {
+				r.ctl.Redraw()
+			}
+		}
+		if err != nil && (!poll || !errors.Is(err, io.EOF)) {
+			return
+		}
+	}
+}
+
+// handleKeys applies the queued key presses to the view.
+func (r *ScreenRenderer) handleKeys(states []ProcessState) {
+	if r.view == nil {
+		return
+	}
+	r.keyMu.Lock()
+	keys := r.keys
+	r.keys = nil
+	r.keyMu.Unlock()
+
+	for _, k := range keys {
+		r.view.handle(k, states, r.ctl, r.layout())
+	}
+	if len(keys) > 0 {
+		r.redraw = true
+	}
+}
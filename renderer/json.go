@@ -0,0 +1,316 @@
+package renderer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/a2y-d5l/multiproc/engine"
+)
+
+// JSONSchemaVersion is the value of the "schema" field in every record
+// written by JSONRenderer. It is incremented whenever a field is removed or
+// changes meaning; adding new fields does not change the version.
+const JSONSchemaVersion = 1
+
+// JSON record types written by JSONRenderer.
+const (
+	// JSONTypeStart marks the start of a process.
+	JSONTypeStart = "start"
+
+	// JSONTypeLine marks a line of output (stdout, stderr or engine status).
+	JSONTypeLine = "line"
+
+	// JSONTypeExit marks the exit of a process.
+	JSONTypeExit = "exit"
+
+	// JSONTypeRestart marks the restart of a process. It follows the exit
+	// record of the previous instance, and has a reason when a watched file
+	// changed.
+	JSONTypeRestart = "restart"
+
+	// JSONTypeReload marks a change of the spec of a process, e.g., on a
+	// configuration reload. The restart record of the process follows.
+	// Processes added by a reload get a start record instead.
+	JSONTypeReload = "reload"
+
+	// JSONTypeRemove marks the removal of a process from the run. Its exit
+	// record follows if it was running.
+	JSONTypeRemove = "remove"
+
+	// JSONTypeSchedule marks a scheduled process waiting for its next run,
+	// at next_run.
+	JSONTypeSchedule = "schedule"
+
+	// JSONTypeSummary marks the final summary record, written once per run.
+	JSONTypeSummary = "summary"
+)
+
+// JSONRecord is a single NDJSON record written by JSONRenderer.
+// Fields that do not apply to a record type are omitted.
+//
+// Example records:
+//
+//	{"schema":1,"type":"start","time":"2024-11-20T15:30:45.1Z","index":0,"process":"build"}
+//	{"schema":1,"type":"line","time":"2024-11-20T15:30:45.2Z","index":0,"process":"build","stream":"stdout","line":"ok"}
+//	{"schema":1,"type":"exit","time":"2024-11-20T15:30:46.3Z","index":0,"process":"build","status":"ok","exit_code":0,"duration_ms":1200}
+type JSONRecord struct {
+	// Field order determines the key order of the encoded record.
+
+	// Schema is always JSONSchemaVersion.
+	Schema int `json:"schema"`
+
+	// Type is one of the JSONType* constants.
+	Type string `json:"type"`
+
+	// Time is when the event happened, in RFC3339 with nanoseconds (UTC).
+	Time time.Time `json:"time"`
+
+	// Index is the position of the process in the spec list.
+	Index *int `json:"index,omitempty"`
+
+	// Process is the process name.
+	Process string `json:"process,omitempty"`
+
+	// Stream is "stdout", "stderr" or "system" (line records).
+	Stream engine.Stream `json:"stream,omitempty"`
+
+	// Line is the output text (line records).
+	Line *string `json:"line,omitempty"`
+
+	// Reason is why the process was restarted, e.g., "change: src/app.go"
+	// when a watched file changed (restart records).
+	Reason string `json:"reason,omitempty"`
+
+	// NextRun is when a scheduled process runs next, and Skipped reports
+	// that its previous scheduled run was skipped (schedule records).
+	NextRun *time.Time `json:"next_run,omitempty"`
+	Skipped bool       `json:"skipped,omitempty"`
+
+	// Status is the human-readable exit status, as printed by FormatExitError.
+	Status string `json:"status,omitempty"`
+
+	// ExitCode is the process exit code (exit records) or the aggregate
+	// exit code of the run (summary records). -1 means the process did not
+	// produce an exit code (e.g., it could not be started).
+	ExitCode *int `json:"exit_code,omitempty"`
+
+	// Error is the raw exit error message, if any (exit records).
+	Error string `json:"error,omitempty"`
+
+	// DurationMS is the process run time in milliseconds (exit records).
+	DurationMS *int64 `json:"duration_ms,omitempty"`
+
+	// Processes holds one entry per process (summary records).
+	Processes []JSONProcessSummary `json:"processes,omitempty"`
+}
+
+// JSONProcessSummary is the per-process entry of a summary record.
+type JSONProcessSummary struct {
+	Index      int    `json:"index"`
+	Process    string `json:"process"`
+	Status     string `json:"status"`
+	ExitCode   int    `json:"exit_code"`
+	DurationMS int64  `json:"duration_ms"`
+	Lines      int    `json:"lines"`
+}
+
+// JSONRenderer writes one JSON object per line (NDJSON) for every line and
+// lifecycle event, followed by a summary record. It is intended for log
+// shippers and other machine consumers.
+//
+// Every record carries a "schema" field (JSONSchemaVersion) and a "type"
+// field (start, line, exit, restart, reload, remove, summary) so consumers can evolve independently.
+//
+// Write errors do not interrupt the run; the first one is returned from
+// FinalState.
+//
+// Example:
+//
+//	cfg.Renderers = []renderer.Renderer{renderer.NewJSONRenderer(os.Stdout)}
+type JSONRenderer struct {
+	err   error
+	enc   *json.Encoder
+	specs []engine.ProcessSpec
+	lines []int
+}
+
+// NewJSONRenderer creates a JSONRenderer writing NDJSON records to out.
+func NewJSONRenderer(out io.Writer) *JSONRenderer {
+	return &JSONRenderer{enc: json.NewEncoder(out)}
+}
+
+// Start implements Renderer. It writes a start record for every process.
+func (r *JSONRenderer) Start(specs []engine.ProcessSpec, states []ProcessState) {
+	r.specs = specs
+	r.lines = make([]int, len(specs))
+	for i := range specs {
+		started := time.Now()
+		if i < len(states) && !states[i].StartedAt.IsZero() {
+			started = states[i].StartedAt
+		}
+		r.write(JSONRecord{
+			Type:    JSONTypeStart,
+			Time:    started,
+			Index:   &i,
+			Process: processName(specs, i),
+		})
+	}
+}
+
+// Event implements Renderer. It writes a line, exit, restart, reload or
+// remove record, or the start record of an added process.
+func (r *JSONRenderer) Event(ev Event, states []ProcessState) {
+	switch e := ev.(type) {
+	case LineEvent:
+		if e.Index < 0 || e.Index >= len(r.specs) {
+			return
+		}
+		r.lines[e.Index]++
+		line := e.Line
+		r.write(JSONRecord{
+			Type:    JSONTypeLine,
+			Time:    eventTime(e.Time),
+			Index:   &e.Index,
+			Process: processName(r.specs, e.Index),
+			Stream:  e.Stream,
+			Line:    &line,
+		})
+
+	case DoneEvent:
+		if e.Index < 0 || e.Index >= len(r.specs) {
+			return
+		}
+		exitCode := ExitCode(e.Err)
+		rec := JSONRecord{
+			Type:     JSONTypeExit,
+			Time:     eventTime(e.Time),
+			Index:    &e.Index,
+			Process:  processName(r.specs, e.Index),
+			Status:   FormatExitError(e.Err),
+			ExitCode: &exitCode,
+		}
+		if e.Err != nil {
+			rec.Error = e.Err.Error()
+		}
+		if e.Index < len(states) {
+			durationMS := states[e.Index].Duration().Milliseconds()
+			rec.DurationMS = &durationMS
+		}
+		r.write(rec)
+
+	case RestartEvent:
+		r.writeLifecycle(JSONTypeRestart, e.Index, e.Time, e.Reason)
+
+	case SpecEvent:
+		if e.Index < 0 || e.Index > len(r.specs) {
+			return
+		}
+		recType := JSONTypeReload
+		if e.Index == len(r.specs) {
+			recType = JSONTypeStart
+			r.lines = append(r.lines, 0)
+		}
+		r.specs = withSpec(r.specs, e)
+		r.writeLifecycle(recType, e.Index, e.Time, "")
+
+	case RemoveEvent:
+		r.writeLifecycle(JSONTypeRemove, e.Index, e.Time, "")
+
+	case ScheduleEvent:
+		if e.Index < 0 || e.Index >= len(r.specs) {
+			return
+		}
+		next := e.Next.UTC()
+		r.write(JSONRecord{
+			Type:    JSONTypeSchedule,
+			Time:    eventTime(e.Time),
+			Index:   &e.Index,
+			Process: processName(r.specs, e.Index),
+			NextRun: &next,
+			Skipped: e.Skipped,
+		})
+	}
+}
+
+// writeLifecycle writes a record of recType for process index, with the
+// reason of a restart, if any.
+func (r *JSONRenderer) writeLifecycle(recType string, index int, t time.Time, reason string) {
+	if index < 0 || index >= len(r.specs) {
+		return
+	}
+	r.write(JSONRecord{
+		Type:    recType,
+		Time:    eventTime(t),
+		Index:   &index,
+		Process: processName(r.specs, index),
+		Reason:  reason,
+	})
+}
+
+// Tick implements Renderer. JSON output has nothing to redraw.
+func (r *JSONRenderer) Tick([]ProcessState) {}
+
+// Finish implements Renderer. JSON output needs no teardown.
+func (r *JSONRenderer) Finish([]ProcessState) {}
+
+// FinalState implements Renderer. It writes the summary record and returns
+// the first write error encountered during the run, if any.
+func (r *JSONRenderer) FinalState(states []ProcessState) error {
+	procs := make([]JSONProcessSummary, len(states))
+	for i := range states {
+		ps := &states[i]
+		lines := len(ps.Lines)
+		if i < len(r.lines) {
+			lines = r.lines[i]
+		}
+		name := processName(r.specs, i)
+		if name == "" {
+			name = ps.Name
+		}
+		status := FormatExitError(ps.Err)
+		if ps.Removed {
+			status = statusRemoved
+		}
+		procs[i] = JSONProcessSummary{
+			Index:      i,
+			Process:    name,
+			Status:     status,
+			ExitCode:   ExitCode(ps.Err),
+			DurationMS: ps.Duration().Milliseconds(),
+			Lines:      lines,
+		}
+	}
+	exitCode := ExitCodeFromStates(states)
+	r.write(JSONRecord{
+		Type:      JSONTypeSummary,
+		Time:      time.Now(),
+		ExitCode:  &exitCode,
+		Processes: procs,
+	})
+
+	if r.err != nil {
+		return fmt.Errorf("json: %w", r.err)
+	}
+	return nil
+}
+
+// write encodes rec, remembering the first error.
+func (r *JSONRenderer) write(rec JSONRecord) {
+	if r.err != nil {
+		return
+	}
+	rec.Schema = JSONSchemaVersion
+	rec.Time = rec.Time.UTC()
+	r.err = r.enc.Encode(rec)
+}
+
+// eventTime returns t, or the current time if t is zero
+// (events constructed without a timestamp).
+func eventTime(t time.Time) time.Time {
+	if t.IsZero() {
+		return time.Now()
+	}
+	return t
+}
@@ -0,0 +1,289 @@
+package renderer
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/a2y-d5l/multiproc/engine"
+)
+
+// DefaultJUnitSuite is the test suite name JUnitRenderer uses when Suite is empty.
+const DefaultJUnitSuite = "multiproc"
+
+// JUnitRenderer writes a JUnit XML report of the run, for CI systems that
+// show pass/fail per job. Nothing is written until FinalState.
+//
+// Every process is a testcase of a single test suite:
+//   - A process that exited with a non-zero code (or was killed by a
+//     signal) has a <failure> with its exit status
+//   - A process that could not be started has an <error>
+//   - A process that was cancelled, or never reported an exit, is <skipped>
+//   - The tail of its output goes to <system-out> (stdout) and
+//     <system-err> (stderr and engine status lines), without escape
+//     sequences (see StripANSI)
+//
+// Example report:
+//
+//	<testsuites name="multiproc" tests="2" failures="1" errors="0" skipped="0" time="12.400">
+//	  <testsuite name="multiproc" tests="2" failures="1" errors="0" skipped="0" time="12.400" timestamp="2024-11-20T15:30:45">
+//	    <testcase name="build" classname="multiproc" time="3.200">
+//	      <system-out><![CDATA[compiling...
+//	]]></system-out>
+//	    </testcase>
+//	    <testcase name="test" classname="multiproc" time="12.400">
+//	      <failure message="exit code 1" type="exit">exit status 1</failure>
+//	      <system-err><![CDATA[FAIL: TestParse
+//	]]></system-err>
+//	    </testcase>
+//	  </testsuite>
+//	</testsuites>
+//
+// Write errors are returned from FinalState.
+//
+// Example:
+//
+//	f, _ := os.Create("report.xml")
+//	cfg.Renderers = append(cfg.Renderers, renderer.NewJUnitRenderer(f))
+type JUnitRenderer struct {
+	// Out receives the report.
+	Out io.Writer
+
+	specs []engine.ProcessSpec
+
+	// tails holds the last output lines of each process, with their stream.
+	tails [][]streamLine
+
+	// Suite is the name of the test suite and the classname of every
+	// testcase. If empty, DefaultJUnitSuite is used.
+	Suite string
+
+	// MaxLines is the number of output lines kept per process (both
+	// streams together). If zero, the process's ProcessState.MaxLines is
+	// used, so the report holds the same tail as the retained output.
+	MaxLines int
+}
+
+// streamLine is an output line with its stream.
+type streamLine struct {
+	text   string
+	stream engine.Stream
+}
+
+// NewJUnitRenderer creates a JUnitRenderer writing the report to out.
+func NewJUnitRenderer(out io.Writer) *JUnitRenderer {
+	return &JUnitRenderer{Out: out}
+}
+
+// Start implements Renderer.
+func (r *JUnitRenderer) Start(specs []engine.ProcessSpec, _ []ProcessState) {
+	r.specs = specs
+	r.tails = make([][]streamLine, len(specs))
+}
+
+// Event implements Renderer. It keeps the tail of each process's output,
+// and the specs of processes added or changed during the run.
+func (r *JUnitRenderer) Event(ev Event, states []ProcessState) {
+	if s, ok := ev.(SpecEvent); ok {
+		if s.Index == len(r.tails) {
+			r.tails = append(r.tails, nil)
+		}
+		r.specs = withSpec(r.specs, s)
+		return
+	}
+	e, ok := ev.(LineEvent)
+	if !ok || e.Index < 0 || e.Index >= len(r.tails) {
+		return
+	}
+	limit := r.MaxLines
+	if limit == 0 && e.Index < len(states) {
+		limit = states[e.Index].MaxLines
+	}
+	tail := append(r.tails[e.Index], streamLine{text: e.Line, stream: e.Stream})
+	if limit > 0 && len(tail) > limit {
+		tail = tail[len(tail)-limit:]
+	}
+	r.tails[e.Index] = tail
+}
+
+// Tick implements Renderer. The report has nothing to redraw.
+func (r *JUnitRenderer) Tick([]ProcessState) {}
+
+// Finish implements Renderer. The report needs no teardown.
+func (r *JUnitRenderer) Finish([]ProcessState) {}
+
+// FinalState implements Renderer. It writes the report.
+func (r *JUnitRenderer) FinalState(states []ProcessState) error {
+	suite := r.Suite
+	if suite == "" {
+		suite = DefaultJUnitSuite
+	}
+
+	ts := junitSuite{Name: suite}
+	var start, end time.Time
+	for i := range states {
+		ps := &states[i]
+		tc := r.testcase(i, ps, suite)
+		ts.Cases = append(ts.Cases, tc)
+		ts.Tests++
+		switch {
+		case tc.Failure != nil:
+			ts.Failures++
+		case tc.Error != nil:
+			ts.Errors++
+		case tc.Skipped != nil:
+			ts.Skipped++
+		}
+		if !ps.StartedAt.IsZero() && (start.IsZero() || ps.StartedAt.Before(start)) {
+			start = ps.StartedAt
+		}
+		if ps.FinishedAt.After(end) {
+			end = ps.FinishedAt
+		}
+	}
+	if !start.IsZero() {
+		ts.Timestamp = start.UTC().Format("2006-01-02T15:04:05")
+		if end.After(start) {
+			ts.Time = junitSeconds(end.Sub(start))
+		}
+	}
+	if ts.Time == "" {
+		ts.Time = junitSeconds(0)
+	}
+
+	report := junitSuites{
+		Name:     suite,
+		Tests:    ts.Tests,
+		Failures: ts.Failures,
+		Errors:   ts.Errors,
+		Skipped:  ts.Skipped,
+		Time:     ts.Time,
+		Suites:   []junitSuite{ts},
+	}
+
+	out, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("junit: %w", err)
+	}
+	if _, err = io.WriteString(r.Out, xml.Header+string(out)+"\n"); err != nil {
+		return fmt.Errorf("junit: %w", err)
+	}
+	return nil
+}
+
+// testcase builds the testcase of process i.
+func (r *JUnitRenderer) testcase(i int, ps *ProcessState, suite string) junitCase {
+	name := processName(r.specs, i)
+	if name == "" {
+		name = ps.Name
+	}
+	tc := junitCase{Name: name, Classname: suite, Time: junitSeconds(ps.Duration())}
+
+	var exitErr *exec.ExitError
+	switch {
+	case ps.Removed:
+		tc.Skipped = &junitResult{Message: "removed"}
+	case !ps.Done:
+		tc.Skipped = &junitResult{Message: "incomplete"}
+	case ps.Canceled:
+		tc.Skipped = &junitResult{Message: "cancelled: " + FormatExitError(ps.Err)}
+	case ps.Err == nil:
+	case errors.As(ps.Err, &exitErr):
+		tc.Failure = &junitResult{Message: FormatExitError(ps.Err), Type: "exit", Text: ps.Err.Error()}
+	default:
+		tc.Error = &junitResult{Message: FormatExitError(ps.Err), Type: "start", Text: ps.Err.Error()}
+	}
+
+	if i < len(r.tails) {
+		var stdout, stderr strings.Builder
+		for _, l := range r.tails[i] {
+			b := &stderr
+			if l.stream == engine.StreamStdout {
+				b = &stdout
+			}
+			b.WriteString(StripANSI(l.text))
+			b.WriteByte('\n')
+		}
+		tc.SystemOut, tc.SystemErr = junitOutput(stdout.String()), junitOutput(stderr.String())
+	}
+	return tc
+}
+
+// junitOutput wraps output for a <system-out> or <system-err> element, or
+// returns nil if there is none. Characters XML does not allow (e.g., U+FFFE)
+// are replaced with U+FFFD.
+func junitOutput(s string) *junitText {
+	if s == "" {
+		return nil
+	}
+	s = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t' || r == '\n' || r == '\r':
+			return r
+		case r < ' ', r == utf8.RuneError, r == '\uFFFE', r == '\uFFFF':
+			return utf8.RuneError
+		default:
+			return r
+		}
+	}, s)
+	return &junitText{Text: s}
+}
+
+// junitSeconds formats d in seconds with millisecond precision.
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", max(d, 0).Seconds())
+}
+
+// junitSuites is the <testsuites> root element. Field order is the order
+// of attributes and elements in the report.
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Errors   int          `xml:"errors,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+// junitSuite is a <testsuite> element.
+type junitSuite struct {
+	Name      string      `xml:"name,attr"`
+	Tests     int         `xml:"tests,attr"`
+	Failures  int         `xml:"failures,attr"`
+	Errors    int         `xml:"errors,attr"`
+	Skipped   int         `xml:"skipped,attr"`
+	Time      string      `xml:"time,attr"`
+	Timestamp string      `xml:"timestamp,attr,omitempty"`
+	Cases     []junitCase `xml:"testcase"`
+}
+
+// junitCase is a <testcase> element.
+type junitCase struct {
+	Name      string       `xml:"name,attr"`
+	Classname string       `xml:"classname,attr"`
+	Time      string       `xml:"time,attr"`
+	Failure   *junitResult `xml:"failure,omitempty"`
+	Error     *junitResult `xml:"error,omitempty"`
+	Skipped   *junitResult `xml:"skipped,omitempty"`
+	SystemOut *junitText   `xml:"system-out,omitempty"`
+	SystemErr *junitText   `xml:"system-err,omitempty"`
+}
+
+// junitText is output kept verbatim in a CDATA section.
+type junitText struct {
+	Text string `xml:",cdata"`
+}
+
+// junitResult is a <failure>, <error> or <skipped> element.
+type junitResult struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
@@ -0,0 +1,121 @@
+package renderer
+
+import (
+	"unicode/utf8"
+)
+
+// keyCode identifies a key read from the terminal.
+type keyCode int
+
+const (
+	// keyRune is a printable character; key.r holds it.
+	keyRune keyCode = iota
+	keyUp
+	keyDown
+	keyPageUp
+	keyPageDown
+	keyHome
+	keyEnd
+	keyEnter
+	keyEsc
+	keyTab
+	keyBackspace
+)
+
+// Control characters with a key binding of their own.
+const (
+	ctrlB    = 0x02 // page up
+	ctrlF    = 0x06 // page down
+	asciiBS  = 0x08 // backspace on some terminals
+	asciiDEL = 0x7f // backspace on most terminals
+	escape   = 0x1b // starts escape sequences
+)
+
+// key is a decoded key press.
+type key struct {
+	code keyCode
+	r    rune
+}
+
+// escapeKeys maps the escape sequences sent by common terminals (xterm,
+// VT220, tmux/screen, in normal and application cursor mode) to keys.
+func escapeKeys(seq string) (keyCode, bool) {
+	switch seq {
+	case "[A", "OA":
+		return keyUp, true
+	case "[B", "OB":
+		return keyDown, true
+	case "[5~":
+		return keyPageUp, true
+	case "[6~":
+		return keyPageDown, true
+	case "[H", "OH", "[1~", "[7~":
+		return keyHome, true
+	case "[F", "OF", "[4~", "[8~":
+		return keyEnd, true
+	default:
+		return keyRune, false
+	}
+}
+
+// decodeKeys decodes the bytes of one terminal read into key presses.
+//
+// A lone ESC at the end of a read is the Esc key; an ESC starting a CSI
+// ("ESC [") or SS3 ("ESC O") sequence is decoded as a whole, and unknown
+// sequences are dropped. Other control characters without a binding and
+// invalid UTF-8 are ignored.
+func decodeKeys(b []byte) []key {
+	var keys []key
+	for len(b) > 0 {
+		c := b[0]
+		switch {
+		case c == escape:
+			n := escapeLen(b)
+			if n == 1 {
+				keys = append(keys, key{code: keyEsc})
+			} else if code, ok := escapeKeys(string(b[1:n])); ok {
+				keys = append(keys, key{code: code})
+			}
+			b = b[n:]
+			continue
+		case c == '\r' || c == '\n':
+			keys = append(keys, key{code: keyEnter})
+		case c == '\t':
+			keys = append(keys, key{code: keyTab})
+		case c == asciiBS || c == asciiDEL:
+			keys = append(keys, key{code: keyBackspace})
+		case c == ctrlB:
+			keys = append(keys, key{code: keyPageUp})
+		case c == ctrlF:
+			keys = append(keys, key{code: keyPageDown})
+		case c >= ' ':
+			r, size := utf8.DecodeRune(b)
+			if r != utf8.RuneError {
+				keys = append(keys, key{code: keyRune, r: r})
+			}
+			b = b[size:]
+			continue
+		}
+		b = b[1:]
+	}
+	return keys
+}
+
+// escapeLen returns the length of the escape sequence at the start of b
+// (which starts with ESC). It returns 1 for a lone ESC.
+func escapeLen(b []byte) int {
+	if len(b) < 2 || (b[1] != '[' && b[1] != 'O') {
+		return 1
+	}
+	if b[1] == 'O' {
+		// SS3: a single final character.
+		return min(3, len(b))
+	}
+	// CSI: parameter and intermediate bytes, then a final byte in 0x40–0x7e.
+	for i := 2; i < len(b); i++ {
+		if b[i] >= 0x40 && b[i] <= 0x7e {
+			return i + 1
+		}
+	}
+	return len(b)
+}
@@ -0,0 +1,492 @@
+package renderer
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// screenFooter is the hint of the display-only view, shown after the status bar.
+	screenFooter = "Press Ctrl+C to cancel."
+
+	// bodyIndent prefixes every output line in the full-screen view.
+	bodyIndent = "    "
+
+	// runningWeight is how many output rows a running process gets for every
+	// row given to a finished one when rows are scarce.
+	runningWeight = 3
+
+	// ellipsis marks truncated text.
+	ellipsis = "…"
+
+	// statusRemoved is the status of a process removed from the run.
+	statusRemoved = "removed"
+
+	// listFooter is the footer of the interactive view.
+	listFooter = "↑↓ select · Enter expand · / search · r restart · s stop · c collapse · ? help · q quit"
+
+	// helpFooter is the footer of the help overlay.
+	helpFooter = "Press ? or Esc to close help."
+
+	// sgrReverse starts reverse video, used to highlight rows.
+	sgrReverse = "\x1b[7m"
+
+	// spinnerStep is how long each spinner frame is shown.
+	spinnerStep = 100 * time.Millisecond
+
+	// preciseBelow is the duration below which finished durations are
+	// shown with tenths of a second.
+	preciseBelow = 10 * time.Second
+
+	// nextRunDateLayout formats the next run of a scheduled process that
+	// is not today.
+	nextRunDateLayout = "Jan 2 15:04"
+)
+
+// screenLayout describes the space available to the full-screen view.
+type screenLayout struct {
+	// width is the terminal width in cells. 0 disables truncation/wrapping.
+	width int
+
+	// height is the terminal height in rows. 0 means unbounded: every
+	// retained line is shown, separated by blank lines (legacy layout).
+	height int
+
+	// wrap wraps long lines onto several rows instead of truncating them.
+	wrap bool
+
+	// view is the state of the interactive view, nil when display-only.
+	// Only used with a known height.
+	view *screenView
+
+	// now is the time the frame is drawn at, for elapsed times and the
+	// spinner. Zero disables both.
+	now time.Time
+
+	// start is when the run started, for the overall elapsed time in the
+	// status bar. If zero, the earliest StartedAt of the states is used.
+	start time.Time
+}
+
+// frame lays out states as a list of screen rows, without trailing newlines.
+//
+// With a known height, the frame has at most height rows:
+//   - One header row per process (see header)
+//   - The tail of each process's output, indented
+//   - The footer as the last row: the status bar followed by a hint
+//
+// Output rows are shared among processes with runningWeight:1 priority for
+// running processes; processes never get more rows than they can fill, and
+// unused rows are redistributed. If there are more processes than rows,
+// trailing processes are collapsed into a "… N more" row.
+//
+// With an interactive view, the selected header is highlighted, finished
+// processes can be collapsed to their header, and the frame may instead
+// show the selected process expanded to full height, or the help overlay.
+func (l screenLayout) frame(states []ProcessState) []string {
+	if l.height <= 0 {
+		return l.unboundedFrame(states)
+	}
+
+	if v := l.view; v != nil && len(states) > 0 {
+		v.clamp(len(states))
+		switch {
+		case v.help:
+			return l.helpFrame()
+		case v.expanded:
+			return l.expandedFrame(states)
+		}
+	}
+
+	rows := l.height - 1 // reserve the footer
+	frame := make([]string, 0, l.height)
+
+	if len(states) > rows {
+		visible := max(rows-1, 0)
+		first := 0
+		if l.view != nil {
+			// Keep the selected process in view.
+			first = max(0, min(l.view.selected-visible+1, len(states)-visible))
+		}
+		for i := first; i < first+visible; i++ {
+			frame = append(frame, l.header(i, &states[i]))
+		}
+		if rows > 0 {
+			frame = append(frame, l.fit(fmt.Sprintf("%s %d more", ellipsis, len(states)-visible)))
+		}
+		return append(frame, l.footer(states, listFooter))
+	}
+
+	budget := rows - len(states) // one header row per process
+	needs := make([]int, len(states))
+	weights := make([]int, len(states))
+	for i := range states {
+		if l.view != nil && l.view.collapse && states[i].Done {
+			continue // collapsed: header only
+		}
+		needs[i] = l.bodyRows(states[i].Lines, budget)
+		weights[i] = 1
+		if !states[i].Done {
+			weights[i] = runningWeight
+		}
+	}
+	alloc := distributeRows(budget, needs, weights)
+
+	for i := range states {
+		frame = append(frame, l.header(i, &states[i]))
+		frame = append(frame, l.tail(states[i].Lines, alloc[i])...)
+	}
+	return append(frame, l.footer(states, listFooter))
+}
+
+// expandedFrame shows the selected process alone: its header, as many
+// output rows as fit (scrolled back by view.scroll rows from the tail, with
+// the current search match highlighted) and the footer.
+func (l screenLayout) expandedFrame(states []ProcessState) []string {
+	v := l.view
+	ps := &states[v.selected]
+	body := max(l.height-2, 0) // header and footer
+
+	base := v.lineBase(v.selected, ps)
+	var rows []string
+	var rowLine []int // absolute line number of each row
+	for j, line := range ps.Lines {
+		for _, row := range l.bodyLines(line) {
+			rows = append(rows, row)
+			rowLine = append(rowLine, base+j)
+		}
+	}
+
+	v.scroll = min(v.scroll, max(len(rows)-body, 0))
+	end := len(rows) - v.scroll
+	start := max(end-body, 0)
+
+	frame := make([]string, 0, l.height)
+	frame = append(frame, l.header(v.selected, ps))
+	for k := start; k < end; k++ {
+		row := rows[k]
+		if rowLine[k] == v.match {
+			row = sgrReverse + row + sgrReset
+		}
+		frame = append(frame, row)
+	}
+	hint := fmt.Sprintf("%s %d/%d · ↑↓ PgUp PgDn scroll · / search · Esc back", ps.Name, end, len(rows))
+	return append(frame, l.footer(states, hint))
+}
+
+// helpFrame lists the key bindings of the interactive view.
+func (l screenLayout) helpFrame() []string {
+	frame := make([]string, 0, l.height)
+	for _, line := range helpLines() {
+		if len(frame) >= l.height-1 {
+			break
+		}
+		frame = append(frame, l.fit(line))
+	}
+	return append(frame, l.fit(helpFooter))
+}
+
+// header formats the header row of process i, highlighted if it is the
+// selected process of the interactive view.
+func (l screenLayout) header(i int, ps *ProcessState) string {
+	row := l.fit(l.headerText(ps))
+	if l.view != nil && l.view.selected == i {
+		return sgrReverse + row + sgrReset
+	}
+	return row
+}
+
+// headerText formats the header of a process block:
+//
+//	⠹ Running server… [running 12s]
+//	✓ build [ok in 3.2s]
+//	✗ test [exit code 1 after 1m05s]
+//	· lint [queued]
+//	✓ check [ok in 0.3s · next 12:00:30 · ✓✓✗✓]
+//
+// Scheduled processes also show their next run and the results of their
+// last runs (see scheduleText).
+//
+// The spinner and elapsed time are omitted when the layout has no clock
+// (now is zero); times are omitted for a process without StartedAt (or,
+// once done, FinishedAt).
+func (l screenLayout) headerText(ps *ProcessState) string {
+	timed := !l.now.IsZero() && !ps.StartedAt.IsZero()
+	switch {
+	case ps.Done:
+		mark := "✓"
+		switch {
+		case ps.Removed:
+			mark = "−"
+		case ps.Err != nil:
+			mark = "✗"
+		}
+		return fmt.Sprintf("%s %s [%s%s]", mark, ps.Name, finalStatus(ps), l.scheduleText(ps))
+
+	case ps.Running:
+		spinner, status := "", "running"
+		if !l.now.IsZero() {
+			spinner = spinnerFrame(l.now) + " "
+		}
+		if timed {
+			status += " " + formatElapsed(l.now.Sub(ps.StartedAt))
+		}
+		status += l.scheduleText(ps)
+		return fmt.Sprintf("%sRunning %s… [%s]", spinner, ps.Name, status)
+
+	default:
+		return fmt.Sprintf("· %s [queued]", ps.Name)
+	}
+}
+
+// scheduleText formats the schedule of a scheduled process for its header:
+// the next run (with the date unless it is today), the number of skipped
+// runs, if any, and the results of the last runs, oldest first, as ✓ (ok),
+// ✗ (failed) and − (cancelled). It is empty for other processes.
+//
+//	· next 12:00:30 · 2 skipped · ✓✓✗✓
+func (l screenLayout) scheduleText(ps *ProcessState) string {
+	if ps.NextRun.IsZero() || ps.Removed {
+		return ""
+	}
+	now := l.now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	layout := time.TimeOnly
+	if y, m, d := ps.NextRun.Date(); y != now.Year() || m != now.Month() || d != now.Day() {
+		layout = nextRunDateLayout
+	}
+	text := " · next " + ps.NextRun.Format(layout)
+	if ps.SkippedRuns > 0 {
+		text += fmt.Sprintf(" · %d skipped", ps.SkippedRuns)
+	}
+	if len(ps.Runs) > 0 {
+		var marks strings.Builder
+		for _, run := range ps.Runs {
+			switch {
+			case run.Canceled:
+				marks.WriteString("−")
+			case run.Err != nil:
+				marks.WriteString("✗")
+			default:
+				marks.WriteString("✓")
+			}
+		}
+		text += " · " + marks.String()
+	}
+	return text
+}
+
+// footer formats the last row: the status bar, then the hint of the
+// display-only view (screenFooter) or the interactive view. The search
+// prompt and status messages replace the whole row.
+func (l screenLayout) footer(states []ProcessState, hint string) string {
+	v := l.view
+	switch {
+	case v == nil:
+		hint = screenFooter
+	case v.searching:
+		return l.fit("/" + v.input + "_")
+	case v.status != "":
+		// Messages replace the status bar until the next key press, so that
+		// they are not cut off on narrow terminals.
+		return l.fit(v.status)
+	}
+	return l.fit(l.statusBar(states) + "  " + hint)
+}
+
+// statusBar summarizes the run: process counts by state and, with a clock,
+// the overall elapsed time.
+//
+//	queued 0 · running 2 · ok 1 · failed 0 · 1m12s
+func (l screenLayout) statusBar(states []ProcessState) string {
+	var queued, running, ok, failed int
+	start := l.start
+	for i := range states {
+		ps := &states[i]
+		switch {
+		case ps.Removed:
+			// Removed processes are no longer part of the run.
+		case ps.Done && ps.Err == nil:
+			ok++
+		case ps.Done:
+			failed++
+		case ps.Running:
+			running++
+		default:
+			queued++
+		}
+	}
+	if start.IsZero() {
+		start = earliestStart(states)
+	}
+
+	bar := fmt.Sprintf("queued %d · running %d · ok %d · failed %d", queued, running, ok, failed)
+	if !l.now.IsZero() && !start.IsZero() {
+		bar += " · " + formatElapsed(l.now.Sub(start))
+	}
+	return bar
+}
+
+// earliestStart returns the earliest non-zero StartedAt of states.
+func earliestStart(states []ProcessState) time.Time {
+	var start time.Time
+	for i := range states {
+		if t := states[i].StartedAt; !t.IsZero() && (start.IsZero() || t.Before(start)) {
+			start = t
+		}
+	}
+	return start
+}
+
+// unboundedFrame is the legacy layout used when the terminal height is
+// unknown: every retained line, blank line separators, footer.
+func (l screenLayout) unboundedFrame(states []ProcessState) []string {
+	var frame []string
+	for i := range states {
+		ps := &states[i]
+		frame = append(frame, l.fit(l.headerText(ps)))
+		for _, line := range ps.Lines {
+			frame = append(frame, l.bodyLines(line)...)
+		}
+		frame = append(frame, "")
+	}
+	return append(frame, l.footer(states, ""))
+}
+
+// spinnerFrame returns the spinner frame to show at time t.
+// Frames advance every spinnerStep regardless of how often the view is drawn.
+func spinnerFrame(t time.Time) string {
+	frames := [...]string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
+	return frames[int(t.UnixMilli()/spinnerStep.Milliseconds())%len(frames)]
+}
+
+// formatElapsed formats the running time of a process or run in whole
+// seconds: "12s", "1m05s", "2h03m".
+func formatElapsed(d time.Duration) string {
+	d = max(d, 0).Truncate(time.Second)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	default:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+}
+
+// finalStatus formats the exit status of a finished process with its
+// duration, if known: "ok in 3.2s", "exit code 1 after 1m05s".
+func finalStatus(ps *ProcessState) string {
+	if ps.Removed {
+		return statusRemoved
+	}
+	status := FormatExitError(ps.Err)
+	if ps.StartedAt.IsZero() || ps.FinishedAt.IsZero() {
+		return status
+	}
+	sep := " in "
+	if ps.Err != nil {
+		sep = " after "
+	}
+	return status + sep + formatDuration(ps.FinishedAt.Sub(ps.StartedAt))
+}
+
+// formatDuration formats the final duration of a process: like
+// formatElapsed, with tenths of a second below 10s ("3.2s").
+func formatDuration(d time.Duration) string {
+	if d < preciseBelow {
+		return fmt.Sprintf("%.1fs", max(d, 0).Seconds())
+	}
+	return formatElapsed(d)
+}
+
+// bodyLines renders one output line as one or more indented rows. Escape
+// sequences other than colors are removed (see SanitizeLine), and widths are
+// measured in terminal cells.
+func (l screenLayout) bodyLines(line string) []string {
+	line = SanitizeLine(line)
+	if strings.TrimSpace(StripANSI(line)) == "" {
+		return []string{""}
+	}
+	if l.width <= 0 {
+		return []string{bodyIndent + line}
+	}
+	if !l.wrap {
+		return []string{l.fit(bodyIndent + line)}
+	}
+
+	avail := l.width - DisplayWidth(bodyIndent)
+	if avail <= 0 {
+		return []string{l.fit(bodyIndent + line)}
+	}
+	rows := WrapWidth(line, avail)
+	for i := range rows {
+		rows[i] = bodyIndent + rows[i]
+	}
+	return rows
+}
+
+// bodyRows counts the rows needed to show lines, stopping at limit.
+func (l screenLayout) bodyRows(lines []string, limit int) int {
+	n := 0
+	for i := len(lines) - 1; i >= 0 && n < limit; i-- {
+		n += len(l.bodyLines(lines[i]))
+	}
+	return min(n, limit)
+}
+
+// tail renders the last rows rows of lines.
+func (l screenLayout) tail(lines []string, rows int) []string {
+	if rows <= 0 {
+		return nil
+	}
+	var out []string
+	for i := len(lines) - 1; i >= 0 && len(out) < rows; i-- {
+		// Prepend the rows of this line; a wrapped line may be cut at the top.
+		out = append(l.bodyLines(lines[i]), out...)
+	}
+	if len(out) > rows {
+		out = out[len(out)-rows:]
+	}
+	return out
+}
+
+// fit truncates s to the layout width in cells, marking the cut with an
+// ellipsis (see TruncateWidth).
+func (l screenLayout) fit(s string) string {
+	if l.width <= 0 {
+		return s
+	}
+	return TruncateWidth(s, l.width, ellipsis)
+}
+
+// distributeRows shares budget rows among blocks in proportion to weights,
+// never giving a block more than it needs. Rows a block cannot use are
+// redistributed to the others.
+//
+// Rows are handed out one at a time to the block with the lowest
+// allocation-to-weight ratio, which keeps the split proportional while
+// respecting each block's need. Ties go to the earlier block.
+func distributeRows(budget int, needs, weights []int) []int {
+	alloc := make([]int, len(needs))
+	for ; budget > 0; budget-- {
+		best := -1
+		for i := range needs {
+			if alloc[i] >= needs[i] {
+				continue
+			}
+			// Compare alloc[i]/weights[i] < alloc[best]/weights[best] without division.
+			if best < 0 || alloc[i]*weights[best] < alloc[best]*weights[i] {
+				best = i
+			}
+		}
+		if best < 0 {
+			break
+		}
+		alloc[best]++
+	}
+	return alloc
+}
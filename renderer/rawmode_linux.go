@@ -0,0 +1,11 @@
+//go:build linux
+
+package renderer
+
+import "syscall"
+
+// ioctl requests reading and writing terminal attributes.
+const (
+	ioctlGetTermios = syscall.TCGETS
+	ioctlSetTermios = syscall.TCSETS
+)
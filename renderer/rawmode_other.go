@@ -0,0 +1,14 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package renderer
+
+import (
+	"errors"
+	"os"
+)
+
+// makeRaw is not supported on this platform: keyboard input stays
+// disabled and the full-screen view is display-only.
+func makeRaw(*os.File) (func() error, error) {
+	return nil, errors.New("raw mode: not supported on this platform")
+}
@@ -0,0 +1,54 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package renderer
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// makeRaw puts the terminal attached to f into raw mode for keyboard input
+// and returns a function restoring the previous mode.
+//
+// Unlike cfmakeraw, ISIG is kept so that Ctrl+C still raises SIGINT and
+// takes the usual graceful shutdown path, and output processing is left
+// alone so that "\n" still moves to the start of the next line.
+//
+// Reads time out after 100ms (VMIN=0, VTIME=1) so that the reader can
+// notice when it should stop; a timed-out read returns io.EOF.
+func makeRaw(f *os.File) (func() error, error) {
+	fd := f.Fd()
+	var old syscall.Termios
+	if err := termiosIoctl(fd, ioctlGetTermios, &old); err != nil {
+		return nil, err
+	}
+
+	raw := old
+	raw.Iflag &^= syscall.BRKINT | syscall.ICRNL | syscall.INPCK | syscall.ISTRIP | syscall.IXON
+	raw.Lflag &^= syscall.ECHO | syscall.ICANON | syscall.IEXTEN
+	raw.Cflag |= syscall.CS8
+	raw.Cc[syscall.VMIN] = 0
+	raw.Cc[syscall.VTIME] = 1
+	if err := termiosIoctl(fd, ioctlSetTermios, &raw); err != nil {
+		return nil, err
+	}
+
+	return func() error {
+		return termiosIoctl(fd, ioctlSetTermios, &old)
+	}, nil
+}
+
+// termiosIoctl gets or sets the terminal attributes of fd.
+func termiosIoctl(fd uintptr, req uint, t *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(
+		syscall.SYS_IOCTL,
+		fd,
+		uintptr(req),
+		uintptr(unsafe.Pointer(t)), //nolint:gosec // ioctl requires a raw pointer to termios
+	)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
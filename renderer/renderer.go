@@ -0,0 +1,167 @@
+package renderer
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/a2y-d5l/multiproc/engine"
+)
+
+// Renderer is implemented by every output backend driven by runner.Run.
+// It decouples the runner's event loop from how output is presented, so
+// embedders can plug in their own formats (JSON logs, metrics, web UIs)
+// without re-implementing process orchestration.
+//
+// Hook sequence for a single run:
+//  1. Start is called once with the specs and initial states
+//  2. Event is called for every event, after it was applied to states
+//  3. Tick is called after each batch of queued events is delivered, and
+//     periodically in between (runner.Config.TickInterval), at most
+//     runner.Config.MaxFrameRate times per second
+//  4. Finish is called once after the last event
+//  5. FinalState is called once after every renderer has finished
+//
+// Finish and FinalState are separate so that renderers which own the
+// terminal (full-screen mode) can restore it before any renderer prints
+// end-of-run output such as summaries or reports.
+//
+// The hooks of one renderer are never called concurrently, so
+// implementations do not need to synchronize access to states. When several
+// renderers are configured, runner.Run gives each one its own goroutine and
+// its own copy of states, so renderers may freely mutate states (e.g., clear
+// Dirty flags) without affecting each other. Implementations must not retain
+// the states slice beyond the call.
+//
+// Processes can be added during a run (e.g., by a configuration reload):
+// states then grows, and the SpecEvent of the new process carries its
+// spec, which is not in the specs passed to Start.
+//
+// Example (counting lines):
+//
+//	type lineCounter struct {
+//	    renderer.NopRenderer
+//	    n int
+//	}
+//
+//	func (c *lineCounter) Event(ev renderer.Event, _ []renderer.ProcessState) {
+//	    if _, ok := ev.(renderer.LineEvent); ok {
+//	        c.n++
+//	    }
+//	}
+type Renderer interface {
+	// Start is called once before any events are delivered.
+	// states contains the initial (all running) state of every process.
+	Start(specs []engine.ProcessSpec, states []ProcessState)
+
+	// Event is called for every event, after ApplyEvent has updated states.
+	Event(ev Event, states []ProcessState)
+
+	// Tick is called after each batch of events has been delivered, and
+	// periodically even when no events arrive. Renderers that redraw the
+	// whole view (full-screen mode) draw here, which coalesces bursts of
+	// events into a single redraw and keeps clocks and spinners moving.
+	// The runner rate-limits Tick, so it is not called after every batch
+	// under heavy output; Finish is the place to draw the final frame.
+	Tick(states []ProcessState)
+
+	// Finish is called once after the last event has been delivered.
+	// Renderers should draw their final frame and release any terminal state.
+	Finish(states []ProcessState)
+
+	// FinalState is called once with the final process states, after
+	// Finish has been called on every renderer. Renderers write summaries
+	// and reports here. runner.Run reports a returned error and exits with
+	// a non-zero code, so that a lost report or log does not go unnoticed.
+	FinalState(states []ProcessState) error
+}
+
+// Restorer is implemented by renderers that change terminal state (the
+// alternate screen, a hidden cursor) and must undo it if the run is aborted.
+//
+// runner.Run calls Restore when a renderer or the event loop panics, before
+// the panic propagates. Restore must be safe to call from any goroutine,
+// concurrently with the renderer's hooks, and more than once.
+type Restorer interface {
+	Restore()
+}
+
+// Controller lets interactive renderers act on the run in progress.
+// runner.Run hands one to every renderer implementing Interactive.
+//
+// All methods are safe to call from any goroutine and never block:
+// Restart and Stop only request the action, whose effect arrives later as
+// events (DoneEvent, RestartEvent).
+type Controller interface {
+	// Restart restarts process index, stopping it first if it is running.
+	Restart(index int) error
+
+	// Stop gracefully stops process index.
+	Stop(index int) error
+
+	// Cancel cancels the whole run, like Ctrl+C.
+	Cancel()
+
+	// Redraw schedules a Tick of the renderer, e.g., after a key press.
+	Redraw()
+}
+
+// Interactive is implemented by renderers that accept user input and act
+// on processes. runner.Run calls SetController once, before Start.
+type Interactive interface {
+	SetController(c Controller)
+}
+
+// NopRenderer implements every Renderer hook as a no-op.
+// Embed it in custom renderers to implement only the hooks you need.
+//
+// Example:
+//
+//	type doneLogger struct{ renderer.NopRenderer }
+//
+//	func (doneLogger) Event(ev renderer.Event, _ []renderer.ProcessState) {
+//	    if d, ok := ev.(renderer.DoneEvent); ok {
+//	        log.Printf("process %d done: %v", d.Index, d.Err)
+//	    }
+//	}
+type NopRenderer struct{}
+
+// Start implements Renderer.
+func (NopRenderer) Start([]engine.ProcessSpec, []ProcessState) {}
+
+// Event implements Renderer.
+func (NopRenderer) Event(Event, []ProcessState) {}
+
+// Tick implements Renderer.
+func (NopRenderer) Tick([]ProcessState) {}
+
+// Finish implements Renderer.
+func (NopRenderer) Finish([]ProcessState) {}
+
+// FinalState implements Renderer.
+func (NopRenderer) FinalState([]ProcessState) error { return nil }
+
+// withSpec returns specs with the spec of a SpecEvent set, or appended for
+// an added process. specs is copied, as it is shared between renderers.
+func withSpec(specs []engine.ProcessSpec, e SpecEvent) []engine.ProcessSpec {
+	if e.Index < 0 || e.Index > len(specs) {
+		return specs
+	}
+	specs = slices.Clone(specs)
+	if e.Index == len(specs) {
+		return append(specs, e.Spec)
+	}
+	specs[e.Index] = e.Spec
+	return specs
+}
+
+// processName returns the display name for spec index i,
+// falling back to "proc-N" when the spec has no name.
+func processName(specs []engine.ProcessSpec, i int) string {
+	if i < 0 || i >= len(specs) {
+		return ""
+	}
+	if specs[i].Name == "" {
+		return fmt.Sprintf("proc-%d", i)
+	}
+	return specs[i].Name
+}
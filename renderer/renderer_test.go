@@ -1,10 +1,19 @@
 package renderer_test
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
 	"testing"
+	"time"
+	"unicode/utf8"
 
 	"github.com/a2y-d5l/multiproc/engine"
 	"github.com/a2y-d5l/multiproc/renderer"
@@ -632,3 +641,1282 @@ func TestApplyEventMultipleDoneEvents(t *testing.T) {
 		t.Error("Done should still be true")
 	}
 }
+
+// TestIncrementalRendererWritesToOut verifies the renderer writes to its writer.
+func TestIncrementalRendererWritesToOut(t *testing.T) {
+	specs := []engine.ProcessSpec{{Name: "build"}, {Name: ""}}
+	states := make([]renderer.ProcessState, len(specs))
+
+	var out, summary strings.Builder
+	r := renderer.NewIncrementalRenderer(&out, false, "%s:")
+	r.SummaryOut = &summary
+	r.AnnounceStart = true
+
+	r.Start(specs, states)
+	r.Event(renderer.ConvertProcessLineToEvent(engine.ProcessLine{Index: 0, Line: "hello\r"}), states)
+	r.Event(renderer.ConvertProcessLineToEvent(engine.ProcessLine{Index: 1, IsComplete: true}), states)
+	r.Finish(states)
+	if err := r.FinalState(states); err != nil {
+		t.Fatalf("FinalState returned error: %v", err)
+	}
+
+	want := "build: starting...\nproc-1: starting...\nbuild: hello\nproc-1: ok\n"
+	if out.String() != want {
+		t.Errorf("Expected output %q, got %q", want, out.String())
+	}
+	if !strings.Contains(summary.String(), "Summary:") {
+		t.Errorf("Expected summary output, got %q", summary.String())
+	}
+}
+
+// TestScreenRendererTickRendersDirtyStates verifies Tick only redraws dirty states.
+func TestScreenRendererTickRendersDirtyStates(t *testing.T) {
+	states := []renderer.ProcessState{{Name: "build", Lines: []string{"compiling"}, Running: true, Dirty: true}}
+
+	var out strings.Builder
+	r := renderer.NewScreenRenderer(&out)
+
+	r.Tick(states)
+	if !strings.Contains(out.String(), "Running build… [running]") {
+		t.Errorf("Expected header in output, got %q", out.String())
+	}
+	if !strings.Contains(out.String(), "    compiling") {
+		t.Errorf("Expected indented line in output, got %q", out.String())
+	}
+
+	out.Reset()
+	r.Tick(states)
+	if out.Len() != 0 {
+		t.Errorf("Expected no output for clean states, got %q", out.String())
+	}
+
+	// Without SummaryOut, FinalState writes nothing.
+	if err := r.FinalState(states); err != nil {
+		t.Fatalf("FinalState returned error: %v", err)
+	}
+	if out.Len() != 0 {
+		t.Errorf("Expected no summary output, got %q", out.String())
+	}
+}
+
+// TestJSONRendererRecords verifies NDJSON records for lifecycle and line events.
+func TestJSONRendererRecords(t *testing.T) {
+	specs := []engine.ProcessSpec{{Name: "build"}}
+	states := []renderer.ProcessState{{Name: "build", Running: true}}
+
+	var out strings.Builder
+	r := renderer.NewJSONRenderer(&out)
+
+	r.Start(specs, states)
+	for _, pl := range []engine.ProcessLine{
+		{Index: 0, Line: "compiling", Stream: engine.StreamStderr},
+		{Index: 0, IsComplete: true, Err: errors.New("boom")},
+	} {
+		ev := renderer.ConvertProcessLineToEvent(pl)
+		renderer.ApplyEvent(states, ev)
+		r.Event(ev, states)
+	}
+	r.Finish(states)
+	if err := r.FinalState(states); err != nil {
+		t.Fatalf("FinalState returned error: %v", err)
+	}
+
+	var records []renderer.JSONRecord
+	for line := range strings.SplitSeq(strings.TrimSpace(out.String()), "\n") {
+		var rec renderer.JSONRecord
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("Invalid JSON line %q: %v", line, err)
+		}
+		if rec.Schema != renderer.JSONSchemaVersion {
+			t.Errorf("Expected schema %d, got %d", renderer.JSONSchemaVersion, rec.Schema)
+		}
+		records = append(records, rec)
+	}
+
+	wantTypes := []string{
+		renderer.JSONTypeStart,
+		renderer.JSONTypeLine,
+		renderer.JSONTypeExit,
+		renderer.JSONTypeSummary,
+	}
+	if len(records) != len(wantTypes) {
+		t.Fatalf("Expected %d records, got %d: %s", len(wantTypes), len(records), out.String())
+	}
+	for i, want := range wantTypes {
+		if records[i].Type != want {
+			t.Errorf("Record %d: expected type %q, got %q", i, want, records[i].Type)
+		}
+	}
+
+	if line := records[1]; line.Stream != engine.StreamStderr || line.Line == nil || *line.Line != "compiling" {
+		t.Errorf("Unexpected line record: %+v", line)
+	}
+	if exit := records[2]; exit.Process != "build" || exit.ExitCode == nil || *exit.ExitCode != -1 || exit.Error != "boom" {
+		t.Errorf("Unexpected exit record: %+v", exit)
+	}
+	if summary := records[3]; len(summary.Processes) != 1 || summary.Processes[0].Lines != 1 {
+		t.Errorf("Unexpected summary record: %+v", summary)
+	}
+}
+
+// TestColorEnabled verifies flag and environment precedence.
+func TestColorEnabled(t *testing.T) {
+	testCases := []struct {
+		name       string
+		noColor    string
+		forceColor string
+		mode       renderer.ColorMode
+		isTTY      bool
+		want       bool
+	}{
+		{name: "auto tty", mode: renderer.ColorAuto, isTTY: true, want: true},
+		{name: "auto pipe", mode: renderer.ColorAuto, isTTY: false, want: false},
+		{name: "no color", mode: renderer.ColorAuto, isTTY: true, noColor: "1", want: false},
+		{name: "force color", mode: renderer.ColorAuto, isTTY: false, forceColor: "1", want: true},
+		{name: "force color zero", mode: renderer.ColorAuto, isTTY: false, forceColor: "0", want: false},
+		{name: "always beats no color", mode: renderer.ColorAlways, noColor: "1", want: true},
+		{name: "never beats force color", mode: renderer.ColorNever, isTTY: true, forceColor: "1", want: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("NO_COLOR", tc.noColor)
+			t.Setenv("FORCE_COLOR", tc.forceColor)
+			if got := renderer.ColorEnabled(tc.mode, tc.isTTY); got != tc.want {
+				t.Errorf("ColorEnabled(%v, %v) = %v, want %v", tc.mode, tc.isTTY, got, tc.want)
+			}
+		})
+	}
+
+	if _, err := renderer.ParseColorMode("sometimes"); err == nil {
+		t.Error("Expected error for invalid color mode")
+	}
+}
+
+// TestIncrementalRendererAlignsAndColorsPrefixes verifies padded, colored prefixes.
+func TestIncrementalRendererAlignsAndColorsPrefixes(t *testing.T) {
+	specs := []engine.ProcessSpec{{Name: "db"}, {Name: "frontend", Color: "bright-magenta"}}
+	states := make([]renderer.ProcessState, len(specs))
+
+	var out strings.Builder
+	r := renderer.NewIncrementalRenderer(&out, false, "[%s]")
+	r.Align = true
+	r.AnnounceStart = true
+	r.Start(specs, states)
+
+	want := "[db]       starting...\n[frontend] starting...\n"
+	if out.String() != want {
+		t.Errorf("Expected aligned output %q, got %q", want, out.String())
+	}
+
+	out.Reset()
+	r.Color = true
+	r.Start(specs, states)
+
+	lines := strings.Split(out.String(), "\n")
+	if !strings.HasPrefix(lines[1], "\x1b[95m[frontend]\x1b[0m starting...") {
+		t.Errorf("Expected spec color for frontend, got %q", lines[1])
+	}
+	if !strings.HasPrefix(lines[0], "\x1b[") || !strings.Contains(lines[0], "[db]\x1b[0m       starting...") {
+		t.Errorf("Expected palette color and padding outside color for db, got %q", lines[0])
+	}
+}
+
+// virtualScreen interprets the subset of ANSI escape sequences written by
+// ScreenRenderer (cursor moves, erases, private modes), so tests can check
+// what the terminal shows rather than the exact bytes written.
+type virtualScreen struct {
+	rows      [][]rune
+	row, col  int
+	altScreen bool
+	hidden    bool
+}
+
+func newVirtualScreen(height int) *virtualScreen {
+	return &virtualScreen{rows: make([][]rune, height)}
+}
+
+func (v *virtualScreen) Write(p []byte) (int, error) {
+	s := []rune(string(p))
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '\x1b':
+			// CSI: ESC [ params final
+			j := i + 2
+			for j < len(s) && (s[j] < 0x40 || s[j] > 0x7e) {
+				j++
+			}
+			v.csi(string(s[i+2:j]), s[j])
+			i = j
+		case '\n':
+			v.row++
+			v.col = 0
+		default:
+			if v.row >= len(v.rows) {
+				continue // off screen
+			}
+			line := v.rows[v.row]
+			for len(line) <= v.col {
+				line = append(line, ' ')
+			}
+			line[v.col] = s[i]
+			v.rows[v.row] = line
+			v.col++
+		}
+	}
+	return len(p), nil
+}
+
+func (v *virtualScreen) csi(params string, final rune) {
+	switch {
+	case final == 'H':
+		v.row, v.col = 0, 0
+		if r, c, ok := strings.Cut(params, ";"); ok {
+			var row, col int
+			fmt.Sscan(r, &row)
+			fmt.Sscan(c, &col)
+			v.row, v.col = row-1, col-1
+		}
+	case final == 'J' && params == "2":
+		clear(v.rows)
+	case final == 'K' && params == "2":
+		if v.row < len(v.rows) {
+			v.rows[v.row] = nil
+		}
+	case params == "?1049":
+		v.altScreen = final == 'h'
+	case params == "?25":
+		v.hidden = final == 'l'
+	}
+}
+
+func (v *virtualScreen) lines() []string {
+	out := make([]string, len(v.rows))
+	for i, r := range v.rows {
+		out[i] = strings.TrimRight(string(r), " ")
+	}
+	return out
+}
+
+// unspin replaces the spinner of running processes (which depends on the
+// time of the render) with "*".
+func unspin(rows []string) []string {
+	out := make([]string, len(rows))
+	for i, row := range rows {
+		if r, size := utf8.DecodeRuneInString(row); r >= '\u2800' && r <= '\u28ff' {
+			row = "*" + row[size:]
+		}
+		out[i] = row
+	}
+	return out
+}
+
+// TestScreenRendererFitsTerminal verifies the sized layout shares rows and truncates lines.
+func TestScreenRendererFitsTerminal(t *testing.T) {
+	lines := make([]string, 20)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %02d with some extra text", i)
+	}
+	states := []renderer.ProcessState{
+		{Name: "server", Lines: lines, Running: true, Dirty: true},
+		{Name: "build", Lines: lines, Done: true, Dirty: true},
+	}
+
+	screen := newVirtualScreen(10)
+	r := renderer.NewScreenRenderer(screen)
+	r.Width, r.Height = 24, 10
+	r.Tick(states)
+
+	rows := unspin(screen.lines())
+	for _, row := range rows {
+		if n := len([]rune(row)); n > r.Width {
+			t.Errorf("Row exceeds width %d: %q", r.Width, row)
+		}
+	}
+
+	// 7 output rows shared 3:1 in favor of the running process: 5 and 2,
+	// each showing the tail of its output.
+	want := []string{
+		"* Running server… [runn…",
+		"    line 15 with some e…",
+		"    line 16 with some e…",
+		"    line 17 with some e…",
+		"    line 18 with some e…",
+		"    line 19 with some e…",
+		"✓ build [ok]",
+		"    line 18 with some e…",
+		"    line 19 with some e…",
+		"queued 0 · running 1 · …",
+	}
+	if strings.Join(rows, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unexpected frame:\n%s\nwant:\n%s", strings.Join(rows, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Wrapping shows the tail of the last line across several rows.
+	states[0].Dirty = true
+	r.Wrap = true
+	r.Tick(states)
+	if !strings.Contains(strings.Join(screen.lines(), "\n"), "    line 19 with some ex\n    tra text\n✓ build [ok]") {
+		t.Errorf("Expected wrapped last line, got %q", screen.lines())
+	}
+}
+
+// TestScreenRendererDiffsFrames verifies only changed rows are rewritten.
+func TestScreenRendererDiffsFrames(t *testing.T) {
+	states := []renderer.ProcessState{
+		{Name: "server", Lines: []string{"listening"}, Done: true, Dirty: true},
+		{Name: "build", Lines: []string{"compiling"}, Running: true, Dirty: true},
+	}
+
+	var out strings.Builder
+	r := renderer.NewScreenRenderer(&out)
+	r.Width, r.Height = 40, 6
+	r.Tick(states)
+	if !strings.HasPrefix(out.String(), "\x1b[H\x1b[2J") {
+		t.Errorf("Expected the first frame to clear the screen, got %q", out.String())
+	}
+
+	// Only the build block and the status bar change.
+	out.Reset()
+	states[1].Lines = []string{"done"}
+	states[1].Done, states[1].Running, states[1].Dirty = true, false, true
+	r.Tick(states)
+	got := out.String()
+	if strings.Contains(got, "\x1b[2J") {
+		t.Errorf("Expected no screen clear on update, got %q", got)
+	}
+	if strings.Contains(got, "server") || strings.Contains(got, "listening") {
+		t.Errorf("Expected unchanged rows to be skipped, got %q", got)
+	}
+	want := "\x1b[3;1H\x1b[2K✓ build [ok]\x1b[4;1H\x1b[2K    done\x1b[5;1H\x1b[2Kqueued 0 · running 0 · ok 2 · failed 0 …"
+	if got != want {
+		t.Errorf("Unexpected diff:\n got %q\nwant %q", got, want)
+	}
+
+	// Nothing changed, nothing written.
+	out.Reset()
+	r.Tick(states)
+	if out.Len() != 0 {
+		t.Errorf("Expected no output without changes, got %q", out.String())
+	}
+}
+
+// TestScreenRendererAltScreen verifies the alternate screen is entered and
+// restored, and that the final frame is left on the main screen.
+func TestScreenRendererAltScreen(t *testing.T) {
+	states := []renderer.ProcessState{
+		{Name: "build", Lines: []string{"compiling"}, Running: true, Dirty: true},
+	}
+
+	screen := newVirtualScreen(5)
+	var raw strings.Builder
+	r := renderer.NewScreenRenderer(io.MultiWriter(screen, &raw))
+	r.Width, r.Height = 40, 5
+	r.AltScreen = true
+
+	r.Start(nil, states)
+	if !screen.altScreen || !screen.hidden {
+		t.Fatal("Expected Start to enter the alternate screen and hide the cursor")
+	}
+
+	states[0].Done, states[0].Running, states[0].Dirty = true, false, true
+	r.Finish(states)
+	if screen.altScreen || screen.hidden {
+		t.Error("Expected Finish to leave the alternate screen and show the cursor")
+	}
+	_, mainScreen, _ := strings.Cut(raw.String(), "\x1b[?1049l")
+	if !strings.HasPrefix(mainScreen, "✓ build [ok]\n    compiling\n") {
+		t.Errorf("Expected the final frame on the main screen, got %q", mainScreen)
+	}
+
+	// Restore after Finish is a no-op.
+	var out strings.Builder
+	r.Out = &out
+	r.Restore()
+	if out.Len() != 0 {
+		t.Errorf("Expected Restore to be idempotent, got %q", out.String())
+	}
+}
+
+// TestScreenRendererRestore verifies Restore leaves the alternate screen mid-run.
+func TestScreenRendererRestore(t *testing.T) {
+	var out strings.Builder
+	r := renderer.NewScreenRenderer(&out)
+	r.Width, r.Height = 40, 5
+	r.AltScreen = true
+	r.Start(nil, []renderer.ProcessState{{Name: "build", Running: true, Dirty: true}})
+
+	out.Reset()
+	r.Restore()
+	if out.String() != "\x1b[?25h\x1b[?1049l" {
+		t.Errorf("Expected cursor and main screen to be restored, got %q", out.String())
+	}
+}
+
+// TestScreenRendererShowsTimes verifies the process headers and the status bar.
+func TestScreenRendererShowsTimes(t *testing.T) {
+	start := time.Now().Add(-75*time.Second - 200*time.Millisecond)
+	states := []renderer.ProcessState{
+		{Name: "server", Running: true, StartedAt: start},
+		{Name: "build", Done: true, StartedAt: start, FinishedAt: start.Add(3200 * time.Millisecond)},
+		{Name: "test", Done: true, Err: errors.New("exit status 1"), StartedAt: start, FinishedAt: start.Add(65 * time.Second)},
+		{Name: "lint"},
+	}
+
+	screen := newVirtualScreen(5)
+	r := renderer.NewScreenRenderer(screen)
+	r.Width, r.Height = 80, 5
+	r.Start(nil, states)
+
+	want := []string{
+		"* Running server… [running 1m15s]",
+		"✓ build [ok in 3.2s]",
+		"✗ test [error: exit status 1 after 1m05s]",
+		"· lint [queued]",
+		"queued 1 · running 1 · ok 1 · failed 1 · 1m15s  Press Ctrl+C to cancel.",
+	}
+	if rows := unspin(screen.lines()); strings.Join(rows, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unexpected frame:\n%s\nwant:\n%s", strings.Join(rows, "\n"), strings.Join(want, "\n"))
+	}
+
+	// Times are redrawn on Tick without any event or dirty state.
+	var out strings.Builder
+	r.Out = &out
+	states[0].StartedAt = start.Add(-time.Second)
+	r.Tick(states)
+	if !strings.Contains(out.String(), "running 1m16s") {
+		t.Errorf("Expected the elapsed time to advance on Tick, got %q", out.String())
+	}
+}
+
+// fakeController records the actions of an interactive renderer.
+type fakeController struct {
+	redraw   chan struct{}
+	mu       sync.Mutex
+	actions  []string
+	stopErr  error
+	canceled bool
+}
+
+func (c *fakeController) record(action string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.actions = append(c.actions, action)
+}
+
+func (c *fakeController) Restart(i int) error { c.record(fmt.Sprintf("restart %d", i)); return nil }
+func (c *fakeController) Stop(i int) error    { c.record(fmt.Sprintf("stop %d", i)); return c.stopErr }
+func (c *fakeController) Cancel()             { c.record("cancel") }
+func (c *fakeController) Redraw()             { c.redraw <- struct{}{} }
+
+// TestScreenRendererInteractive drives the interactive view with key presses.
+func TestScreenRendererInteractive(t *testing.T) {
+	lines := make([]string, 30)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("build line %d", i)
+	}
+	states := []renderer.ProcessState{
+		{Name: "server", Lines: []string{"listening"}, Running: true, Dirty: true},
+		{Name: "build", Lines: lines, Running: true, Dirty: true},
+	}
+
+	keys, input := io.Pipe()
+	defer input.Close()
+	ctl := &fakeController{redraw: make(chan struct{}, 16), stopErr: errors.New("process is not running")}
+	screen := newVirtualScreen(8)
+	r := renderer.NewScreenRenderer(screen)
+	r.Width, r.Height = 60, 8
+	r.Input = keys
+	r.SetController(ctl)
+	r.Start(nil, states)
+	defer r.Finish(states)
+
+	// press sends keys and redraws once they have been read.
+	press := func(s string) []string {
+		t.Helper()
+		if _, err := io.WriteString(input, s); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case <-ctl.redraw:
+		case <-time.After(2 * time.Second):
+			t.Fatalf("No redraw after %q", s)
+		}
+		r.Tick(states)
+		return screen.lines()
+	}
+
+	if rows := screen.lines(); !strings.HasPrefix(rows[7], "queued 0 · running 2 · ok 0 · failed 0 · ") ||
+		!strings.Contains(rows[7], "↑↓ select") {
+		t.Errorf("Expected the status bar and the interactive footer, got %q", rows[7])
+	}
+
+	// Select build (down arrow) and expand it to full height.
+	rows := unspin(press("\x1b[B\r"))
+	if rows[0] != "* Running build… [running]" || rows[6] != "    build line 29" {
+		t.Errorf("Expected build expanded to full height, got %q", rows)
+	}
+
+	// Scroll back a page: the view moves towards older lines.
+	rows = press("\x1b[5~")
+	if rows[6] != "    build line 24" {
+		t.Errorf("Expected the view scrolled back by a page, got %q", rows)
+	}
+
+	// Search for an older line.
+	rows = press("/line 3\r")
+	if !strings.Contains(strings.Join(rows, "\n"), "    build line 3\n") {
+		t.Errorf("Expected the match in view, got %q", rows)
+	}
+	if rows[7] != "/line 3: match 1 of 1 · n older · N newer" {
+		t.Errorf("Expected the match position in the footer, got %q", rows[7])
+	}
+
+	// Actions go to the controller; errors are reported in the footer.
+	rows = press("r")
+	if rows[7] != "restarting build..." {
+		t.Errorf("Expected restart status, got %q", rows[7])
+	}
+	rows = press("s")
+	if rows[7] != "stop build: process is not running" {
+		t.Errorf("Expected stop error, got %q", rows[7])
+	}
+	press("q")
+
+	// Help overlay.
+	rows = press("?")
+	if !strings.Contains(strings.Join(rows, "\n"), "select a process (scroll when expanded)") {
+		t.Errorf("Expected the help overlay, got %q", rows)
+	}
+	press("\x1b")
+
+	// Collapse finished processes: back in the list, build keeps its rows.
+	states[0].Done, states[0].Running = true, false
+	rows = unspin(press("\x1b" + "c"))
+	if rows[0] != "✓ server [ok]" || rows[1] != "* Running build… [running]" {
+		t.Errorf("Expected server collapsed to its header, got %q", rows)
+	}
+
+	ctl.mu.Lock()
+	defer ctl.mu.Unlock()
+	if got := strings.Join(ctl.actions, ","); got != "restart 1,stop 1,cancel" {
+		t.Errorf("Unexpected controller actions: %s", got)
+	}
+}
+
+// deliver applies events to states and passes them to r, like runner.Run.
+func deliver(r renderer.Renderer, states []renderer.ProcessState, events ...renderer.Event) {
+	for _, ev := range events {
+		renderer.ApplyEvent(states, ev)
+		r.Event(ev, states)
+	}
+}
+
+// TestGroupedRenderer verifies blocks are contiguous and failures can be printed last.
+func TestGroupedRenderer(t *testing.T) {
+	specs := []engine.ProcessSpec{{Name: "test"}, {Name: "build"}, {Name: "lint"}}
+	states := []renderer.ProcessState{{Name: "test"}, {Name: "build"}, {Name: "lint"}}
+
+	var out strings.Builder
+	r := renderer.NewGroupedRenderer(&out)
+	r.FailedLast = true
+	r.Start(specs, states)
+
+	deliver(r, states,
+		renderer.LineEvent{Index: 0, Line: "test 1"},
+		renderer.LineEvent{Index: 1, Line: "build 1"},
+		renderer.LineEvent{Index: 0, Line: "test 2\r"},
+		renderer.LineEvent{Index: 2, Line: "lint 1"},
+		renderer.LineEvent{Index: 1, Line: "build 2"},
+		renderer.DoneEvent{Index: 0, Err: errors.New("exit status 1")},
+		renderer.DoneEvent{Index: 1},
+	)
+	if out.String() != "=== build ===\nbuild 1\nbuild 2\n=== build: ok ===\n" {
+		t.Errorf("Expected only the successful block so far, got %q", out.String())
+	}
+
+	// lint never reports an exit; its block is printed on Finish, before
+	// the held failure.
+	r.Finish(states)
+	want := "=== build ===\nbuild 1\nbuild 2\n=== build: ok ===\n" +
+		"=== lint ===\nlint 1\n=== lint: incomplete ===\n" +
+		"=== test ===\ntest 1\ntest 2\n=== test: error: exit status 1 ===\n"
+	if out.String() != want {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", out.String(), want)
+	}
+	if err := r.FinalState(states); err != nil {
+		t.Errorf("FinalState returned error: %v", err)
+	}
+}
+
+// TestGroupedRendererSpills verifies large output moves to a temporary file.
+func TestGroupedRendererSpills(t *testing.T) {
+	dir := t.TempDir()
+	specs := []engine.ProcessSpec{{Name: "chatty"}}
+	states := []renderer.ProcessState{{Name: "chatty"}}
+
+	var out strings.Builder
+	r := renderer.NewGroupedRenderer(&out)
+	r.TempDir = dir
+	r.SpillThreshold = 64
+	r.Start(specs, states)
+
+	var want strings.Builder
+	want.WriteString("=== chatty ===\n")
+	for i := range 20 {
+		line := fmt.Sprintf("line %02d", i)
+		want.WriteString(line + "\n")
+		deliver(r, states, renderer.LineEvent{Index: 0, Line: line})
+	}
+	want.WriteString("=== chatty: ok ===\n")
+
+	if files, _ := filepath.Glob(filepath.Join(dir, "*")); len(files) != 1 {
+		t.Fatalf("Expected one spill file, got %v", files)
+	}
+
+	deliver(r, states, renderer.DoneEvent{Index: 0})
+	if out.String() != want.String() {
+		t.Errorf("Unexpected output:\n%s\nwant:\n%s", out.String(), want.String())
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*")); len(files) != 0 {
+		t.Errorf("Expected the spill file to be removed, got %v", files)
+	}
+
+	r.Finish(states)
+	if err := r.FinalState(states); err != nil {
+		t.Errorf("FinalState returned error: %v", err)
+	}
+
+	if _, err := renderer.ParseOutputMode("sorted"); err == nil {
+		t.Error("Expected error for invalid output mode")
+	}
+}
+
+// TestGroupedRendererCI verifies the GitHub and GitLab log folding markup.
+func TestGroupedRendererCI(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	run := func(platform renderer.CIPlatform) string {
+		specs := []engine.ProcessSpec{{Name: "build"}, {Name: "unit test"}}
+		states := []renderer.ProcessState{{Name: "build", StartedAt: start}, {Name: "unit test", StartedAt: start}}
+		var out strings.Builder
+		r := renderer.NewGroupedRenderer(&out)
+		r.CI = platform
+		r.Start(specs, states)
+		deliver(r, states,
+			renderer.LineEvent{Index: 0, Line: "compiling"},
+			renderer.DoneEvent{Index: 0, Time: start.Add(3200 * time.Millisecond)},
+			renderer.LineEvent{Index: 1, Line: "FAIL"},
+			renderer.DoneEvent{Index: 1, Time: start.Add(2 * time.Second), Err: errors.New("exit status 1")},
+		)
+		r.Finish(states)
+		return out.String()
+	}
+
+	want := "::group::build: ok in 3.2s\ncompiling\n::endgroup::\n" +
+		"::group::unit test: error: exit status 1 after 2.0s\nFAIL\n::endgroup::\n" +
+		"::error title=unit test::unit test: error: exit status 1 after 2.0s\n"
+	if got := run(renderer.CIGitHub); got != want {
+		t.Errorf("Unexpected GitHub output:\n%s\nwant:\n%s", got, want)
+	}
+
+	want = "\x1b[0Ksection_start:1700000000:proc_0_build[collapsed=true]\r\x1b[0Kbuild: ok in 3.2s\ncompiling\n" +
+		"\x1b[0Ksection_end:1700000003:proc_0_build\r\x1b[0K\n" +
+		"\x1b[0Ksection_start:1700000000:proc_1_unit_test\r\x1b[0Kunit test: error: exit status 1 after 2.0s\nFAIL\n" +
+		"\x1b[0Ksection_end:1700000002:proc_1_unit_test\r\x1b[0K\n"
+	if got := run(renderer.CIGitLab); got != want {
+		t.Errorf("Unexpected GitLab output:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+// TestWriteMarkdownSummary verifies the step summary table.
+func TestWriteMarkdownSummary(t *testing.T) {
+	start := time.Now()
+	states := []renderer.ProcessState{
+		{Name: "build", Done: true, StartedAt: start, FinishedAt: start.Add(3200 * time.Millisecond)},
+		{Name: "a|b", Done: true, Err: errors.New("exit status 1")},
+		{Name: "lint"},
+	}
+
+	var out strings.Builder
+	if err := renderer.WriteMarkdownSummaryTo(&out, states); err != nil {
+		t.Fatal(err)
+	}
+	want := "### multiproc results\n\n| | Process | Status | Duration |\n|---|---|---|---|\n" +
+		"| ✅ | build | ok | 3.2s |\n" +
+		"| ❌ | a\\|b | error: exit status 1 | — |\n" +
+		"| ⏳ | lint | incomplete | — |\n"
+	if out.String() != want {
+		t.Errorf("Unexpected summary:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+// TestDetectCIPlatform verifies detection from the CI environment variables.
+func TestDetectCIPlatform(t *testing.T) {
+	testCases := []struct {
+		name   string
+		github string
+		gitlab string
+		want   renderer.CIPlatform
+	}{
+		{name: "none", want: renderer.CINone},
+		{name: "github", github: "true", want: renderer.CIGitHub},
+		{name: "gitlab", gitlab: "true", want: renderer.CIGitLab},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("GITHUB_ACTIONS", tc.github)
+			t.Setenv("GITLAB_CI", tc.gitlab)
+			if got := renderer.ResolveCIPlatform(renderer.CIAuto); got != tc.want {
+				t.Errorf("ResolveCIPlatform(CIAuto) = %v, want %v", got, tc.want)
+			}
+			if got := renderer.ResolveCIPlatform(renderer.CIGitLab); got != renderer.CIGitLab {
+				t.Errorf("Expected an explicit platform to win, got %v", got)
+			}
+		})
+	}
+
+	if _, err := renderer.ParseCIPlatform("jenkins"); err == nil {
+		t.Error("Expected error for invalid CI platform")
+	}
+}
+
+// TestJUnitRenderer verifies testcases, results and output streams.
+func TestJUnitRenderer(t *testing.T) {
+	exitErr := exec.Command("sh", "-c", "exit 3").Run()
+	if exitErr == nil {
+		t.Skip("sh unavailable")
+	}
+	start := time.Date(2024, 11, 20, 15, 30, 45, 0, time.UTC)
+	specs := []engine.ProcessSpec{{Name: "build"}, {Name: "test"}, {Name: "deploy"}, {Name: "lint"}}
+	states := []renderer.ProcessState{
+		{Name: "build", StartedAt: start, MaxLines: 2},
+		{Name: "test", StartedAt: start},
+		{Name: "deploy", StartedAt: start},
+		{Name: "lint", StartedAt: start},
+	}
+
+	var out strings.Builder
+	r := renderer.NewJUnitRenderer(&out)
+	r.Start(specs, states)
+	deliver(r, states,
+		renderer.LineEvent{Index: 0, Line: "evicted", Stream: engine.StreamStdout},
+		renderer.LineEvent{Index: 0, Line: "compiling <main>", Stream: engine.StreamStdout},
+		renderer.LineEvent{Index: 0, Line: "warning\x1b[31m\uFFFE", Stream: engine.StreamStderr},
+		renderer.DoneEvent{Index: 0, Time: start.Add(3200 * time.Millisecond)},
+		renderer.LineEvent{Index: 1, Line: "FAIL", Stream: engine.StreamStderr},
+		renderer.DoneEvent{Index: 1, Time: start.Add(12400 * time.Millisecond), Err: exitErr},
+		renderer.DoneEvent{Index: 2, Time: start.Add(time.Second), Err: errors.New("start: no such file")},
+		renderer.DoneEvent{Index: 3, Time: start.Add(time.Second), Err: errors.New("signal: terminated"), Canceled: true},
+	)
+	r.Finish(states)
+	if err := r.FinalState(states); err != nil {
+		t.Fatalf("FinalState returned error: %v", err)
+	}
+
+	want := `<?xml version="1.0" encoding="UTF-8"?>
+<testsuites name="multiproc" tests="4" failures="1" errors="1" skipped="1" time="12.400">
+  <testsuite name="multiproc" tests="4" failures="1" errors="1" skipped="1" time="12.400" timestamp="2024-11-20T15:30:45">
+    <testcase name="build" classname="multiproc" time="3.200">
+      <system-out><![CDATA[compiling <main>
+]]></system-out>
+      <system-err><![CDATA[warning` + "\ufffd" + `
+]]></system-err>
+    </testcase>
+    <testcase name="test" classname="multiproc" time="12.400">
+      <failure message="exit code 3" type="exit">exit status 3</failure>
+      <system-err><![CDATA[FAIL
+]]></system-err>
+    </testcase>
+    <testcase name="deploy" classname="multiproc" time="1.000">
+      <error message="error: start: no such file" type="start">start: no such file</error>
+    </testcase>
+    <testcase name="lint" classname="multiproc" time="1.000">
+      <skipped message="cancelled: error: signal: terminated"></skipped>
+    </testcase>
+  </testsuite>
+</testsuites>
+`
+	if out.String() != want {
+		t.Errorf("Unexpected report:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+// TestHTMLRenderer verifies the report lists every process and converts its full log.
+func TestHTMLRenderer(t *testing.T) {
+	exitErr := exec.Command("sh", "-c", "exit 2").Run()
+	if exitErr == nil {
+		t.Skip("sh unavailable")
+	}
+	start := time.Date(2024, 11, 20, 15, 30, 45, 0, time.UTC)
+	specs := []engine.ProcessSpec{{Name: "build"}, {Name: "test <unit>"}, {Name: "lint"}}
+	states := []renderer.ProcessState{
+		{Name: "build", StartedAt: start, MaxLines: 1},
+		{Name: "test <unit>", StartedAt: start},
+		{Name: "lint", StartedAt: start},
+	}
+
+	dir := t.TempDir()
+	var out strings.Builder
+	r := renderer.NewHTMLRenderer(&out)
+	r.TempDir = dir
+	r.SpillThreshold = 16
+	r.Start(specs, states)
+	deliver(r, states,
+		renderer.LineEvent{Index: 0, Line: "compiling", Stream: engine.StreamStdout},
+		renderer.LineEvent{Index: 0, Line: "\x1b[1;32mdone\x1b[0m & linked", Stream: engine.StreamStdout},
+		renderer.DoneEvent{Index: 0, Time: start.Add(3200 * time.Millisecond)},
+		renderer.LineEvent{Index: 1, Line: "\x1b[38;5;196mFAIL\x1b[39m <TestParse>", Stream: engine.StreamStderr},
+		renderer.LineEvent{Index: 1, Line: "\x1b[48;2;1;2;3mbg\x1b[2K", Stream: engine.StreamStdout},
+		renderer.DoneEvent{Index: 1, Time: start.Add(12400 * time.Millisecond), Err: exitErr},
+	)
+	r.Finish(states)
+	if err := r.FinalState(states); err != nil {
+		t.Fatalf("FinalState returned error: %v", err)
+	}
+	report := out.String()
+
+	for _, want := range []string{
+		"<title>multiproc report</title>",
+		`3 processes, 1 failed, exit code 1`,
+		`<tr class="ok"><td><a href="#p0">build</a></td><td>ok</td><td>3.2s</td><td>0</td></tr>`,
+		`<tr class="fail"><td><a href="#p1">test &lt;unit&gt;</a></td><td>exit code 2</td><td>12s</td><td>2</td></tr>`,
+		`<tr class="skip"><td><a href="#p2">lint</a></td><td>incomplete</td><td>&mdash;</td><td>&mdash;</td></tr>`,
+		// Logs are complete despite MaxLines and the spill threshold.
+		"<details id=\"p0\" class=\"ok\">\n<summary>build <span class=\"status\">ok in 3.2s</span></summary>\n" +
+			"<div class=\"log\"><div class=\"line\">compiling</div>\n" +
+			"<div class=\"line\"><span style=\"color:#0dbc79;font-weight:bold\">done</span> &amp; linked</div>\n</div>\n</details>",
+		// Failures are expanded and stderr is highlighted.
+		`<details id="p1" class="fail" open>`,
+		`<div class="line err"><span style="color:#ff0000">FAIL</span> &lt;TestParse&gt;</div>`,
+		`<div class="line"><span style="background:#010203">bg</span></div>`,
+		`<details id="p2" class="skip">`,
+		`<input id="search"`,
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("Expected %q in report:\n%s", want, report)
+		}
+	}
+	for _, external := range []string{`src="http`, `href="http`, "<link"} {
+		if strings.Contains(report, external) {
+			t.Errorf("Report references an external asset (%q)", external)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected spilled logs to be removed, found %d files", len(entries))
+	}
+}
+
+// TestApplyEventCountsLines verifies received and evicted lines are counted.
+func TestApplyEventCountsLines(t *testing.T) {
+	states := []renderer.ProcessState{{MaxLines: 2}}
+	for _, line := range []string{"a", "b", "c", "d"} {
+		renderer.ApplyEvent(states, renderer.LineEvent{Index: 0, Line: line})
+	}
+	ps := states[0]
+	if ps.LineCount != 4 || ps.Evicted != 2 || len(ps.Lines) != 2 {
+		t.Errorf("Expected 4 lines with 2 evicted and 2 retained, got %d, %d and %d", ps.LineCount, ps.Evicted, len(ps.Lines))
+	}
+}
+
+// TestApplyEventReload verifies added, changed and removed processes update
+// the states.
+func TestApplyEventReload(t *testing.T) {
+	states := []renderer.ProcessState{{Name: "api", Running: true}, {Name: "db", Running: true}}
+	events := []renderer.Event{
+		renderer.ConvertProcessLineToEvent(engine.ProcessLine{Index: 2, IsUpdate: true, Spec: engine.ProcessSpec{Name: "worker", MaxLines: 5}}),
+		renderer.ConvertProcessLineToEvent(engine.ProcessLine{Index: 0, IsUpdate: true, Spec: engine.ProcessSpec{Name: "api", MaxBytes: 10}}),
+		renderer.ConvertProcessLineToEvent(engine.ProcessLine{Index: 1, IsRemoved: true}),
+		renderer.DoneEvent{Index: 1, Err: errors.New("signal: terminated")},
+	}
+	for _, ev := range events {
+		states = renderer.GrowStates(states, ev)
+		renderer.ApplyEvent(states, ev)
+	}
+
+	if len(states) != 3 || states[2].Name != "worker" || !states[2].Running || states[2].MaxLines != 5 {
+		t.Fatalf("Expected a running state for the added process, got %+v", states)
+	}
+	if states[0].MaxBytes != 10 {
+		t.Errorf("Expected the changed spec to set MaxBytes, got %d", states[0].MaxBytes)
+	}
+	if !states[1].Removed || !states[1].Canceled {
+		t.Errorf("Expected the removed process to be removed and cancelled, got %+v", states[1])
+	}
+	if code := renderer.ExitCodeFromStates(states); code != 0 {
+		t.Errorf("Expected removed processes not to fail the run, got exit code %d", code)
+	}
+
+	var out strings.Builder
+	r := renderer.NewIncrementalRenderer(&out, false, "%s:")
+	r.Start([]engine.ProcessSpec{{Name: "api"}, {Name: "db"}}, states[:2])
+	for _, ev := range events[:3] {
+		r.Event(ev, states)
+	}
+	// Without AnnounceStart, only the added process is announced.
+	want := "worker: starting...\napi: configuration changed\ndb: removed\n"
+	if out.String() != want {
+		t.Errorf("Expected output %q, got %q", want, out.String())
+	}
+}
+
+// TestApplyEventRestartClear verifies a restart caused by a change keeps or
+// clears the output of the previous run, and reports its reason.
+func TestApplyEventRestartClear(t *testing.T) {
+	states := []renderer.ProcessState{{Name: "build", Lines: []string{"ok"}, ByteSize: 2, LineCount: 1}}
+	keep := renderer.ConvertProcessLineToEvent(engine.ProcessLine{Index: 0, IsRestart: true, Reason: "change: main.go"})
+	renderer.ApplyEvent(states, keep)
+	if len(states[0].Lines) != 1 || states[0].Restarts != 1 {
+		t.Fatalf("Expected the output to be kept, got %+v", states[0])
+	}
+
+	cleared := renderer.ConvertProcessLineToEvent(engine.ProcessLine{Index: 0, IsRestart: true, Reason: "change: main.go", ClearOutput: true})
+	renderer.ApplyEvent(states, cleared)
+	ps := states[0]
+	if len(ps.Lines) != 0 || ps.ByteSize != 0 || ps.Evicted != 1 || ps.LineCount != 1 {
+		t.Errorf("Expected the output to be cleared and counted as evicted, got %+v", ps)
+	}
+
+	var out strings.Builder
+	r := renderer.NewJSONRenderer(&out)
+	r.Start([]engine.ProcessSpec{{Name: "build"}}, states)
+	r.Event(keep, states)
+	if !strings.Contains(out.String(), `"type":"restart"`) || !strings.Contains(out.String(), `"reason":"change: main.go"`) {
+		t.Errorf("Expected a restart record with the reason, got %s", out.String())
+	}
+}
+
+// TestApplyEventSchedule verifies scheduled processes keep their last run
+// results and show their next run.
+func TestApplyEventSchedule(t *testing.T) {
+	states := []renderer.ProcessState{{Name: "check", Running: true, RunHistory: 3}}
+	next := time.Now().Add(30 * time.Second).Truncate(time.Second)
+	events := []renderer.Event{
+		renderer.ConvertProcessLineToEvent(engine.ProcessLine{Index: 0, IsSchedule: true, NextRun: next}),
+		renderer.DoneEvent{Index: 0, Err: errors.New("exit status 1")},
+		renderer.RestartEvent{Index: 0, Reason: "schedule"},
+		renderer.DoneEvent{Index: 0},
+		renderer.RestartEvent{Index: 0, Reason: "schedule"},
+		renderer.ConvertProcessLineToEvent(engine.ProcessLine{Index: 0, IsSchedule: true, NextRun: next, Skipped: true}),
+		renderer.DoneEvent{Index: 0},
+		renderer.RestartEvent{Index: 0, Reason: "schedule"},
+		renderer.DoneEvent{Index: 0},
+	}
+	for _, ev := range events {
+		renderer.ApplyEvent(states, ev)
+	}
+
+	ps := states[0]
+	if !ps.NextRun.Equal(next) || ps.SkippedRuns != 1 {
+		t.Errorf("Expected the next run and 1 skipped run, got %v and %d", ps.NextRun, ps.SkippedRuns)
+	}
+	if len(ps.Runs) != 3 || ps.Runs[0].Err != nil {
+		t.Fatalf("Expected the last 3 runs, all ok, got %+v", ps.Runs)
+	}
+
+	screen := newVirtualScreen(2)
+	r := renderer.NewScreenRenderer(screen)
+	r.Width, r.Height = 80, 2
+	states[0].Runs[1].Err = errors.New("exit status 1")
+	r.Start(nil, states)
+	layout := time.TimeOnly
+	if next.Day() != time.Now().Day() {
+		layout = "Jan 2 15:04"
+	}
+	want := "· next " + next.Format(layout) + " · 1 skipped · ✓✗✓]"
+	if rows := screen.lines(); len(rows) == 0 || !strings.HasSuffix(rows[0], want) {
+		t.Errorf("Expected the header to end with %q, got %q", want, rows)
+	}
+
+	var out strings.Builder
+	renderer.WriteSummaryTo(&out, states, renderer.SummaryOptions{})
+	if !strings.Contains(out.String(), ", last 3 runs: 2 ok, 1 failed\n") {
+		t.Errorf("Expected the last runs in the summary, got %q", out.String())
+	}
+}
+
+// TestWriteSummaryTo verifies the summary layouts, sort orders and failure tails.
+func TestWriteSummaryTo(t *testing.T) {
+	exitErr := exec.Command("sh", "-c", "exit 1").Run()
+	if exitErr == nil {
+		t.Skip("sh unavailable")
+	}
+	start := time.Date(2024, 11, 20, 15, 30, 45, 0, time.UTC)
+	states := []renderer.ProcessState{
+		{Name: "build", Done: true, StartedAt: start, FinishedAt: start.Add(3200 * time.Millisecond), LineCount: 120, Lines: []string{"done"}},
+		{
+			Name: "test", Done: true, Err: exitErr, StartedAt: start, FinishedAt: start.Add(12 * time.Second),
+			LineCount: 4210, Evicted: 4207, Lines: []string{"ok  pkg/a", "--- FAIL: TestParse", "FAIL"},
+		},
+		{Name: "lint", Done: true, Err: errors.New("signal: terminated"), Canceled: true, StartedAt: start, FinishedAt: start.Add(time.Second), LineCount: 1, Lines: []string{"linting"}},
+		{Name: "deploy"},
+	}
+
+	tests := []struct {
+		name string
+		opts renderer.SummaryOptions
+		want string
+	}{
+		{
+			name: "full",
+			opts: renderer.SummaryOptions{TailLines: 2},
+			want: `
+Summary:
+  - build: ok in 3.2s, 120 lines
+  - test: exit code 1 after 12s, 4210 lines (4207 evicted)
+    | --- FAIL: TestParse
+    | FAIL
+  - lint: error: signal: terminated after 1.0s, 1 line
+  - deploy: incomplete, 0 lines
+`,
+		},
+		{
+			name: "by status without tails",
+			opts: renderer.SummaryOptions{Sort: renderer.SummaryByStatus, TailLines: -1},
+			want: `
+Summary:
+  - test: exit code 1 after 12s, 4210 lines (4207 evicted)
+  - lint: error: signal: terminated after 1.0s, 1 line
+  - deploy: incomplete, 0 lines
+  - build: ok in 3.2s, 120 lines
+`,
+		},
+		{
+			name: "compact by duration",
+			opts: renderer.SummaryOptions{Sort: renderer.SummaryByDuration, Layout: renderer.SummaryCompact},
+			want: `
+Summary:
+  PROCESS  STATUS                     DURATION  LINES  EVICTED
+  test     exit code 1                12s       4210   4207
+  build    ok                         3.2s      120    0
+  lint     error: signal: terminated  1.0s      1      0
+  deploy   incomplete                 -         0      0
+
+test (last 3 lines):
+  ok  pkg/a
+  --- FAIL: TestParse
+  FAIL
+`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			renderer.WriteSummaryTo(&out, states, tt.opts)
+			if out.String() != tt.want {
+				t.Errorf("Unexpected summary:\n%s\nwant:\n%s", out.String(), tt.want)
+			}
+		})
+	}
+
+	// Many processes switch to the compact table.
+	many := make([]renderer.ProcessState, renderer.DefaultSummaryCompactAbove+1)
+	for i := range many {
+		many[i].Name = fmt.Sprintf("p%d", i)
+	}
+	var out strings.Builder
+	renderer.WriteSummaryTo(&out, many, renderer.SummaryOptions{})
+	if !strings.Contains(out.String(), "PROCESS") {
+		t.Errorf("Expected the compact table for %d processes, got:\n%s", len(many), out.String())
+	}
+}
+
+// TestParseSummaryOptions verifies the flag spellings round-trip.
+func TestParseSummaryOptions(t *testing.T) {
+	for _, s := range []renderer.SummarySort{renderer.SummaryByOrder, renderer.SummaryByDuration, renderer.SummaryByStatus} {
+		if got, err := renderer.ParseSummarySort(s.String()); err != nil || got != s {
+			t.Errorf("ParseSummarySort(%q) = %v, %v", s, got, err)
+		}
+	}
+	for _, l := range []renderer.SummaryLayout{renderer.SummaryAuto, renderer.SummaryFull, renderer.SummaryCompact} {
+		if got, err := renderer.ParseSummaryLayout(l.String()); err != nil || got != l {
+			t.Errorf("ParseSummaryLayout(%q) = %v, %v", l, got, err)
+		}
+	}
+	if _, err := renderer.ParseSummarySort("size"); err == nil {
+		t.Error("Expected an error for an unknown sort order")
+	}
+	if _, err := renderer.ParseSummaryLayout("wide"); err == nil {
+		t.Error("Expected an error for an unknown layout")
+	}
+}
+
+// TestDisplayWidth verifies widths in terminal cells.
+func TestDisplayWidth(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"", 0},
+		{"hello", 5},
+		{"日本語", 6},
+		{"ｈｉ", 4},
+		{"e\u0301te\u0301", 3},      // combining acute accents
+		{"👍", 2},                    // emoji
+		{"\U0001F44D\U0001F3FD", 2}, // skin tone modifier
+		{"\U0001F469\u200d\U0001F469\u200d\U0001F467", 2}, // ZWJ family
+		{"\u2764\ufe0f", 2},                           // VS16 emoji presentation
+		{"🇫🇷🇯🇵", 4},                                   // flags
+		{"\x1b[1;31mred\x1b[0m", 3},                   // SGR
+		{"\x1b]0;title\x07ok", 2},                     // OSC
+		{"\x1b[2K\r\x1b[3Aup", 2},                     // erase, CR, cursor up
+		{"a\tb", tabWidthCells + 1},                   // tab stop
+		{"12345678\tx", 2*tabWidthCells + 1},          // tab at a stop
+		{"\x00\x07\x7f\u0085visible", len("visible")}, // C0, DEL, C1
+	}
+	for _, tt := range tests {
+		if got := renderer.DisplayWidth(tt.in); got != tt.want {
+			t.Errorf("DisplayWidth(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+// tabWidthCells is the tab stop distance of the renderer package.
+const tabWidthCells = 8
+
+// TestSanitizeLine verifies which escape sequences survive.
+func TestSanitizeLine(t *testing.T) {
+	tests := []struct {
+		in, sanitized, stripped string
+	}{
+		{"plain", "plain", "plain"},
+		{"\x1b[32mok\x1b[0m", "\x1b[32mok\x1b[0m", "ok"},
+		{"\x1b[32mok", "\x1b[32mok\x1b[0m", "ok"},
+		{"\x1b[2K\r\x1b[1Gprogress 50%", "progress 50%", "progress 50%"},
+		{"\x1b[2J\x1b[H\x1b[?25lcleared", "cleared", "cleared"},
+		{"\x1b]0;window title\x1b\\text", "text", "text"},
+		{"\x1b]8;;https://example.com\x07link\x1b]8;;\x07", "link", "link"},
+		{"a\tb", "a       b", "a       b"},
+		{"bell\x07 and\x08 backspace", "bell and backspace", "bell and backspace"},
+		{"日本\x1b[1m語", "日本\x1b[1m語\x1b[0m", "日本語"},
+		{"truncated escape\x1b[", "truncated escape", "truncated escape"},
+	}
+	for _, tt := range tests {
+		if got := renderer.SanitizeLine(tt.in); got != tt.sanitized {
+			t.Errorf("SanitizeLine(%q) = %q, want %q", tt.in, got, tt.sanitized)
+		}
+		if got := renderer.StripANSI(tt.in); got != tt.stripped {
+			t.Errorf("StripANSI(%q) = %q, want %q", tt.in, got, tt.stripped)
+		}
+	}
+}
+
+// TestTruncateWidth verifies truncation never splits characters or escapes.
+func TestTruncateWidth(t *testing.T) {
+	tests := []struct {
+		in    string
+		width int
+		want  string
+	}{
+		{"short", 10, "short"},
+		{"exactly10!", 10, "exactly10!"},
+		{"too long for it", 8, "too lon…"},
+		{"日本語テキスト", 6, "日本…"},
+		{"日本語テキスト", 7, "日本語…"},
+		{"\x1b[31m日本語\x1b[0m", 5, "\x1b[31m日本\x1b[0m…"},
+		{"\x1b[1mbold\x1b[0m text", 6, "\x1b[1mbold\x1b[0m …"},
+		{"\U0001F469\u200d\U0001F467 family", 4, "\U0001F469\u200d\U0001F467 …"},
+		{"e\u0301e\u0301e\u0301e\u0301", 3, "e\u0301e\u0301…"},
+		{"\x1b[2Kabcdef", 4, "abc…"},
+		{"abc", 0, "…"},
+	}
+	for _, tt := range tests {
+		got := renderer.TruncateWidth(tt.in, tt.width, "…")
+		if got != tt.want {
+			t.Errorf("TruncateWidth(%q, %d) = %q, want %q", tt.in, tt.width, got, tt.want)
+		}
+		if w := renderer.DisplayWidth(got); w > max(tt.width, 1) {
+			t.Errorf("TruncateWidth(%q, %d) is %d cells wide", tt.in, tt.width, w)
+		}
+	}
+}
+
+// TestWrapWidth verifies wrapped rows fit and carry their style.
+func TestWrapWidth(t *testing.T) {
+	tests := []struct {
+		in    string
+		width int
+		want  []string
+	}{
+		{"", 4, []string{""}},
+		{"abcdef", 4, []string{"abcd", "ef"}},
+		{"\x1b[32mabcdef", 4, []string{"\x1b[32mabcd\x1b[0m", "\x1b[32mef\x1b[0m"}},
+		{"\x1b[1m\x1b[31mab\x1b[0mcdef", 3, []string{"\x1b[1m\x1b[31mab\x1b[0mc", "def"}},
+		{"日本語", 5, []string{"日本", "語"}},
+		{"日本語", 1, []string{"日", "本", "語"}},
+		{"ab\tc", 4, []string{"ab", "    ", "c"}},
+	}
+	for _, tt := range tests {
+		got := renderer.WrapWidth(tt.in, tt.width)
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("WrapWidth(%q, %d) = %q, want %q", tt.in, tt.width, got, tt.want)
+		}
+	}
+}
+
+// TestScreenRendererSanitizesOutput verifies process output cannot move the
+// cursor or clear the screen, and that wide characters are measured in cells.
+func TestScreenRendererSanitizesOutput(t *testing.T) {
+	states := []renderer.ProcessState{{
+		Name: "app",
+		Lines: []string{
+			"\x1b[2J\x1b[Hcleared",
+			"\x1b[12;3Hmoved",
+			"\x1b[32m日本語のテキストがとても長い\x1b[0m",
+		},
+		Done:  true,
+		Dirty: true,
+	}}
+
+	var raw strings.Builder
+	screen := newVirtualScreen(8)
+	r := renderer.NewScreenRenderer(io.MultiWriter(screen, &raw))
+	r.Width, r.Height = 20, 8
+	r.Tick(states)
+
+	rows := screen.lines()
+	for _, row := range rows {
+		if w := renderer.DisplayWidth(row); w > r.Width {
+			t.Errorf("Row is %d cells wide, over %d: %q", w, r.Width, row)
+		}
+	}
+	want := []string{"✓ app [ok]", "    cleared", "    moved", "    日本語のテキス…"}
+	if got := rows[:len(want)]; !slices.Equal(got, want) {
+		t.Errorf("Unexpected frame %q, want %q", got, want)
+	}
+	if strings.Contains(raw.String(), "\x1b[12;3H") {
+		t.Errorf("Expected cursor movement from the output to be dropped, got %q", raw.String())
+	}
+	if !strings.Contains(raw.String(), "\x1b[32m日本語のテキス\x1b[0m…") {
+		t.Errorf("Expected the color kept and reset before the ellipsis, got %q", raw.String())
+	}
+}
+
+// TestIncrementalRendererOutputColors verifies colors in process output are
+// kept with Color and stripped without it.
+func TestIncrementalRendererOutputColors(t *testing.T) {
+	specs := []engine.ProcessSpec{{Name: "app"}}
+	states := make([]renderer.ProcessState, len(specs))
+	ev := renderer.LineEvent{Index: 0, Line: "\x1b[2K\x1b[31mfailed\ttwice", Stream: engine.StreamStdout}
+
+	var out strings.Builder
+	r := renderer.NewIncrementalRenderer(&out, false, "[%s]")
+	r.Start(specs, states)
+	out.Reset()
+	r.Event(ev, states)
+	if want := "[app] failed  twice\n"; out.String() != want {
+		t.Errorf("Expected colors stripped %q, got %q", want, out.String())
+	}
+
+	out.Reset()
+	r.Color = true
+	r.Event(ev, states)
+	if !strings.HasSuffix(out.String(), " \x1b[31mfailed  twice\x1b[0m\n") {
+		t.Errorf("Expected colors kept and reset, got %q", out.String())
+	}
+}
@@ -10,7 +10,8 @@
 //   - ProcessState: Maintains renderable state for each process
 //   - Event system: Adapts engine events to renderer events
 //   - ApplyEvent: Pure function for state updates
-//   - Renderers: Format and display state
+//   - Renderer: Interface implemented by every output backend
+//   - ScreenRenderer, IncrementalRenderer: Built-in Renderer implementations
 //
 // Basic usage with engine events:
 //
@@ -25,6 +26,9 @@
 package renderer
 
 import (
+	"slices"
+	"time"
+
 	"github.com/a2y-d5l/multiproc/engine"
 )
 
@@ -88,53 +92,208 @@ type ProcessState struct {
 	// Opposite of Done, provided for convenience.
 	Running bool
 
+	// StartedAt is when the process was started.
+	// Set by the runner when it initializes the state.
+	StartedAt time.Time
+
+	// FinishedAt is when the process exited. Zero until Done is true.
+	// Set by ApplyEvent from the completion event.
+	FinishedAt time.Time
+
+	// Canceled is true when the process was shut down, or never started,
+	// because the run was cancelled. Set by ApplyEvent from the DoneEvent.
+	Canceled bool
+
+	// LineCount is the number of lines received, including evicted ones.
+	// Updated by ApplyEvent for every LineEvent.
+	LineCount int
+
+	// Evicted is the number of lines evicted from Lines to honor MaxLines
+	// and MaxBytes, or cleared by a RestartEvent with Clear set.
+	// LineCount - Evicted == len(Lines).
+	Evicted int
+
+	// Restarts counts how many times the process was restarted.
+	// Incremented by ApplyEvent for every RestartEvent.
+	Restarts int
+
+	// Runs holds the results of the last runs of the process, oldest
+	// first: at most RunHistory of them. Appended by ApplyEvent for every
+	// DoneEvent.
+	Runs []RunResult
+
+	// RunHistory is the number of run results kept in Runs. If zero,
+	// DefaultRunHistory is used. Typically copied from
+	// ProcessSpec.RunHistory.
+	RunHistory int
+
+	// NextRun is when a scheduled process (ProcessSpec.Schedule) runs
+	// next; zero for other processes. Set by ApplyEvent from the
+	// ScheduleEvent.
+	NextRun time.Time
+
+	// SkippedRuns counts the scheduled runs skipped because the process
+	// was still running. Updated by ApplyEvent from the ScheduleEvent.
+	SkippedRuns int
+
+	// Removed is true once the process was removed from the run, e.g., by
+	// a configuration reload. Set by ApplyEvent from the RemoveEvent.
+	// Removed processes do not count as failed.
+	Removed bool
+
 	// Dirty indicates whether this process state has changed since last render.
 	// Set to true by ApplyEvent, cleared by renderer after displaying.
 	// Used for performance optimization in full-screen rendering.
 	Dirty bool
 }
 
+// DefaultRunHistory is the number of run results kept in
+// ProcessState.Runs when RunHistory is zero.
+const DefaultRunHistory = 10
+
+// RunResult is the result of one run of a process.
+type RunResult struct {
+	// StartedAt and FinishedAt are when the run started and ended.
+	StartedAt  time.Time
+	FinishedAt time.Time
+
+	// Err is the exit error of the run, nil on success.
+	Err error
+
+	// Canceled reports that the run was shut down by a cancellation.
+	Canceled bool
+}
+
 // Event is a marker interface for renderer events.
 // All renderer event types implement this interface.
 //
 // Event types:
-//   - lineEvent: Output line from a process
-//   - doneEvent: Process completion/exit
+//   - LineEvent: Output line from a process
+//   - DoneEvent: Process completion/exit
+//   - RestartEvent: Process restarted (see engine.Engine.Restart)
+//   - SpecEvent: Process added or its spec changed (see engine.Engine.Add)
+//   - RemoveEvent: Process removed from the run (see engine.Engine.Remove)
+//   - ScheduleEvent: Scheduled process waiting for its next run
 //
 // Events are created by ConvertProcessLineToEvent() from engine.ProcessLine
-// and consumed by ApplyEvent() to update ProcessState.
+// and consumed by ApplyEvent() to update ProcessState. Renderer
+// implementations receive them through Renderer.Event and switch on the
+// concrete type.
 type Event interface{ isEvent() }
 
-// lineEvent represents a single line of output for one process.
-// This is an internal event type used by the renderer.
-type lineEvent struct {
+// LineEvent represents a single line of output for one process.
+type LineEvent struct {
+	// Time is when the engine observed the line.
+	Time time.Time
+
 	// Line contains the output text (already normalized for line endings).
 	Line string
 
+	// Stream identifies the source of the line (stdout, stderr or system).
+	Stream engine.Stream
+
 	// Index identifies which process emitted this line.
 	Index int
 }
 
-func (lineEvent) isEvent() {}
+func (LineEvent) isEvent() {}
+
+// DoneEvent signals that a process has exited.
+type DoneEvent struct {
+	// Time is when the engine observed the exit.
+	Time time.Time
 
-// doneEvent signals that a process has exited.
-// This is an internal event type used by the renderer.
-type doneEvent struct {
 	// Err contains the exit error, if any (nil for successful exit).
 	Err error
 
 	// Index identifies which process has exited.
 	Index int
+
+	// Canceled reports that the process was shut down, or never started,
+	// because the run was cancelled (see engine.ProcessLine.Canceled).
+	Canceled bool
+}
+
+func (DoneEvent) isEvent() {}
+
+// RestartEvent signals that a process is being started again. It follows
+// the DoneEvent of the previous instance; line events of the new instance
+// follow it.
+type RestartEvent struct {
+	// Time is when the new instance was started.
+	Time time.Time
+
+	// Index identifies which process was restarted.
+	Index int
+
+	// Reason is why the process was restarted, e.g., "change: src/app.go"
+	// for a watched file; empty for a restart on request.
+	Reason string
+
+	// Clear drops the output of the previous runs (ProcessSpec.WatchClear).
+	Clear bool
+}
+
+func (RestartEvent) isEvent() {}
+
+// SpecEvent signals that a process was added to the run, or that its spec
+// changed, e.g., on a configuration reload. The process starts with Spec
+// right after: line events follow for an added process (whose Index is the
+// next one), a RestartEvent for a changed one.
+type SpecEvent struct {
+	// Time is when the change was applied.
+	Time time.Time
+
+	// Spec is the new spec of the process.
+	Spec engine.ProcessSpec
+
+	// Index identifies which process was added or changed.
+	Index int
+}
+
+func (SpecEvent) isEvent() {}
+
+// RemoveEvent signals that a process was removed from the run. If it was
+// running, it is stopped and its DoneEvent follows.
+type RemoveEvent struct {
+	// Time is when the process was removed.
+	Time time.Time
+
+	// Index identifies which process was removed.
+	Index int
+}
+
+func (RemoveEvent) isEvent() {}
+
+// ScheduleEvent signals that a scheduled process (ProcessSpec.Schedule)
+// waits for its next run.
+type ScheduleEvent struct {
+	// Time is when the next run was scheduled.
+	Time time.Time
+
+	// Next is when the process runs next.
+	Next time.Time
+
+	// Index identifies which process is scheduled.
+	Index int
+
+	// Skipped reports that the previous scheduled run was skipped because
+	// the process was still running.
+	Skipped bool
 }
 
-func (doneEvent) isEvent() {}
+func (ScheduleEvent) isEvent() {}
 
 // ConvertProcessLineToEvent converts a ProcessLine from the engine to an Event for the renderer.
 // This adapter function bridges the engine and renderer layers.
 //
 // Conversion logic:
-//   - ProcessLine with IsComplete=true → doneEvent
-//   - ProcessLine with IsComplete=false → lineEvent
+//   - ProcessLine with IsComplete=true → DoneEvent
+//   - ProcessLine with IsRestart=true → RestartEvent
+//   - ProcessLine with IsUpdate=true → SpecEvent
+//   - ProcessLine with IsRemoved=true → RemoveEvent
+//   - ProcessLine with IsSchedule=true → ScheduleEvent
+//   - Any other ProcessLine → LineEvent
 //
 // Parameters:
 //   - pl: ProcessLine from engine
@@ -150,23 +309,45 @@ func (doneEvent) isEvent() {}
 //	}
 func ConvertProcessLineToEvent(pl engine.ProcessLine) Event {
 	if pl.IsComplete {
-		return doneEvent{Index: pl.Index, Err: pl.Err}
+		return DoneEvent{Index: pl.Index, Err: pl.Err, Time: pl.Time, Canceled: pl.Canceled}
+	}
+	if pl.IsRestart {
+		return RestartEvent{Index: pl.Index, Time: pl.Time, Reason: pl.Reason, Clear: pl.ClearOutput}
+	}
+	if pl.IsUpdate {
+		return SpecEvent{Index: pl.Index, Spec: pl.Spec, Time: pl.Time}
+	}
+	if pl.IsRemoved {
+		return RemoveEvent{Index: pl.Index, Time: pl.Time}
+	}
+	if pl.IsSchedule {
+		return ScheduleEvent{Index: pl.Index, Next: pl.NextRun, Skipped: pl.Skipped, Time: pl.Time}
 	}
-	return lineEvent{Index: pl.Index, Line: pl.Line}
+	return LineEvent{Index: pl.Index, Line: pl.Line, Stream: pl.Stream, Time: pl.Time}
 }
 
 // ApplyEvent updates process state based on a renderer event.
 // This is a pure function that mutates the states slice in-place.
 //
 // Behavior:
-//   - lineEvent: Appends line to state, enforces memory limits, marks dirty
-//   - doneEvent: Sets Done=true, Running=false, stores exit error, marks dirty
+//   - LineEvent: Appends line to state, enforces memory limits, marks dirty
+//   - DoneEvent: Sets Done=true, Running=false, stores exit error,
+//     Canceled and FinishedAt, records the run in Runs, marks dirty
+//   - RestartEvent: Sets Running=true, Done=false, clears Err, Canceled and
+//     FinishedAt, resets StartedAt, counts the restart, marks dirty.
+//     Lines are kept, so the output of earlier instances stays visible,
+//     unless Clear is set: then they are dropped and counted in Evicted
+//   - SpecEvent: Updates Name, MaxLines, MaxBytes and RunHistory from the
+//     new spec, marks dirty (see GrowStates for added processes)
+//   - RemoveEvent: Sets Removed, marks dirty; the DoneEvent of a removed
+//     process sets Canceled
+//   - ScheduleEvent: Sets NextRun, counts a skipped run, marks dirty
 //
-// Memory limit enforcement (lineEvent only):
-//  1. Append new line to Lines slice
+// Memory limit enforcement (LineEvent only):
+//  1. Append new line to Lines slice and count it in LineCount
 //  2. Add line byte count to ByteSize
 //  3. While (lines > MaxLines OR bytes > MaxBytes):
-//     - Remove oldest line from Lines
+//     - Remove oldest line from Lines and count it in Evicted
 //     - Subtract its byte count from ByteSize
 //  4. Mark state as Dirty
 //
@@ -176,7 +357,7 @@ func ConvertProcessLineToEvent(pl engine.ProcessLine) Event {
 //
 // Parameters:
 //   - states: Slice of ProcessState to update (mutated in-place)
-//   - ev: Event to apply (lineEvent or doneEvent)
+//   - ev: Event to apply (LineEvent or DoneEvent)
 //
 // Example:
 //
@@ -189,7 +370,7 @@ func ConvertProcessLineToEvent(pl engine.ProcessLine) Event {
 //	}
 func ApplyEvent(states []ProcessState, ev Event) {
 	switch e := ev.(type) {
-	case lineEvent:
+	case LineEvent:
 		if e.Index < 0 || e.Index >= len(states) {
 			return
 		}
@@ -199,6 +380,7 @@ func ApplyEvent(states []ProcessState, ev Event) {
 		lineBytes := len(e.Line)
 		ps.Lines = append(ps.Lines, e.Line)
 		ps.ByteSize += lineBytes
+		ps.LineCount++
 
 		// Enforce limits: evict oldest lines if either limit is exceeded.
 		// We need to keep removing lines until both constraints are satisfied.
@@ -218,11 +400,12 @@ func ApplyEvent(states []ProcessState, ev Event) {
 			oldestLine := ps.Lines[0]
 			ps.Lines = ps.Lines[1:]
 			ps.ByteSize -= len(oldestLine)
+			ps.Evicted++
 		}
 
 		ps.Dirty = true
 
-	case doneEvent:
+	case DoneEvent:
 		if e.Index < 0 || e.Index >= len(states) {
 			return
 		}
@@ -230,15 +413,130 @@ func ApplyEvent(states []ProcessState, ev Event) {
 		ps.Done = true
 		ps.Running = false
 		ps.Err = e.Err
+		ps.Canceled = e.Canceled || ps.Removed
+		ps.FinishedAt = e.Time
+		if ps.FinishedAt.IsZero() {
+			ps.FinishedAt = time.Now()
+		}
+		ps.recordRun()
 		ps.Dirty = true
+
+	case RestartEvent:
+		if e.Index < 0 || e.Index >= len(states) {
+			return
+		}
+		ps := &states[e.Index]
+		ps.Done = false
+		ps.Running = true
+		ps.Err = nil
+		ps.Canceled = false
+		ps.StartedAt = e.Time
+		if ps.StartedAt.IsZero() {
+			ps.StartedAt = time.Now()
+		}
+		ps.FinishedAt = time.Time{}
+		ps.Restarts++
+		if e.Clear {
+			ps.Evicted += len(ps.Lines)
+			ps.Lines = nil
+			ps.ByteSize = 0
+		}
+		ps.Dirty = true
+
+	case SpecEvent:
+		if e.Index < 0 || e.Index >= len(states) {
+			return
+		}
+		ps := &states[e.Index]
+		ps.Name = e.Spec.Name
+		ps.MaxLines = e.Spec.MaxLines
+		ps.MaxBytes = e.Spec.MaxBytes
+		ps.RunHistory = e.Spec.RunHistory
+		ps.Dirty = true
+
+	case RemoveEvent:
+		if e.Index < 0 || e.Index >= len(states) {
+			return
+		}
+		states[e.Index].Removed = true
+		states[e.Index].Dirty = true
+
+	case ScheduleEvent:
+		if e.Index < 0 || e.Index >= len(states) {
+			return
+		}
+		ps := &states[e.Index]
+		ps.NextRun = e.Next
+		if e.Skipped {
+			ps.SkippedRuns++
+		}
+		ps.Dirty = true
+	}
+}
+
+// recordRun appends the run that just finished to Runs, dropping the
+// oldest results beyond RunHistory.
+func (ps *ProcessState) recordRun() {
+	history := ps.RunHistory
+	if history <= 0 {
+		history = DefaultRunHistory
+	}
+	ps.Runs = append(ps.Runs, RunResult{
+		StartedAt:  ps.StartedAt,
+		FinishedAt: ps.FinishedAt,
+		Err:        ps.Err,
+		Canceled:   ps.Canceled,
+	})
+	if extra := len(ps.Runs) - history; extra > 0 {
+		ps.Runs = slices.Delete(ps.Runs, 0, extra)
+	}
+}
+
+// GrowStates returns states with a new running state appended if ev is
+// the SpecEvent of a process added to the run (Index == len(states)), and
+// states unchanged otherwise. Call it before ApplyEvent, keeping the
+// returned slice:
+//
+//	states = renderer.GrowStates(states, ev)
+//	renderer.ApplyEvent(states, ev)
+func GrowStates(states []ProcessState, ev Event) []ProcessState {
+	e, ok := ev.(SpecEvent)
+	if !ok || e.Index != len(states) {
+		return states
+	}
+	startedAt := e.Time
+	if startedAt.IsZero() {
+		startedAt = time.Now()
+	}
+	return append(states, ProcessState{
+		Name:       e.Spec.Name,
+		Running:    true,
+		MaxLines:   e.Spec.MaxLines,
+		MaxBytes:   e.Spec.MaxBytes,
+		RunHistory: e.Spec.RunHistory,
+		StartedAt:  startedAt,
+		Dirty:      true,
+	})
+}
+
+// Duration returns how long the process ran.
+// For running processes it is the time elapsed so far; it is zero if the
+// process has no StartedAt.
+func (ps *ProcessState) Duration() time.Duration {
+	if ps.StartedAt.IsZero() {
+		return 0
+	}
+	if ps.Done {
+		return ps.FinishedAt.Sub(ps.StartedAt)
 	}
+	return time.Since(ps.StartedAt)
 }
 
 // ExitCodeFromStates determines the appropriate exit code based on process states.
 // This function is used to compute the final exit code for the overall execution.
 //
 // Logic:
-//   - If any process has a non-nil Err, return 1 (failure)
+//   - If any process that was not removed has a non-nil Err, return 1 (failure)
 //   - If all processes succeeded (Err == nil), return 0 (success)
 //
 // This follows standard Unix conventions where:
@@ -257,7 +555,7 @@ func ApplyEvent(states []ProcessState, ev Event) {
 //	os.Exit(exitCode)
 func ExitCodeFromStates(states []ProcessState) int {
 	for _, ps := range states {
-		if ps.Err != nil {
+		if ps.Err != nil && !ps.Removed {
 			return 1
 		}
 	}
@@ -0,0 +1,356 @@
+package renderer
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// SummarySort selects the order of processes in the final summary.
+type SummarySort int
+
+const (
+	// SummaryByOrder lists processes in spec order.
+	SummaryByOrder SummarySort = iota
+
+	// SummaryByDuration lists the longest-running processes first.
+	SummaryByDuration
+
+	// SummaryByStatus lists failed processes first, then cancelled and
+	// incomplete ones, then successful ones; spec order within each group.
+	SummaryByStatus
+)
+
+// SummaryLayout selects how the final summary is laid out.
+type SummaryLayout int
+
+const (
+	// SummaryAuto uses SummaryFull, or SummaryCompact when there are more
+	// than DefaultSummaryCompactAbove processes.
+	SummaryAuto SummaryLayout = iota
+
+	// SummaryFull writes one line per process with its status, duration and
+	// line counts, followed by the output tail of failed processes.
+	SummaryFull
+
+	// SummaryCompact writes an aligned table with one row per process,
+	// followed by the output tails of failed processes.
+	SummaryCompact
+)
+
+const (
+	// DefaultSummaryTailLines is the number of retained output lines the
+	// summary shows for each failed process when TailLines is zero.
+	DefaultSummaryTailLines = 10
+
+	// DefaultSummaryCompactAbove is the number of processes above which
+	// SummaryAuto switches to the compact table.
+	DefaultSummaryCompactAbove = 12
+)
+
+const (
+	// summaryTailIndent prefixes the output tail lines of the full summary.
+	summaryTailIndent = "    | "
+
+	// summaryColumnGap is the space between the columns of the compact table.
+	summaryColumnGap = 2
+)
+
+// Ranks of processes for SummaryByStatus.
+const (
+	summaryRankFailed = iota
+	summaryRankPending
+	summaryRankOK
+)
+
+// String returns the flag spelling of the sort order ("order", "duration",
+// "status").
+func (s SummarySort) String() string {
+	switch s {
+	case SummaryByOrder:
+		return "order"
+	case SummaryByDuration:
+		return "duration"
+	case SummaryByStatus:
+		return "status"
+	default:
+		return fmt.Sprintf("SummarySort(%d)", int(s))
+	}
+}
+
+// ParseSummarySort parses "order", "duration" or "status"
+// (case-insensitive).
+//
+// Example:
+//
+//	sort, err := renderer.ParseSummarySort(*summarySortFlag)
+func ParseSummarySort(s string) (SummarySort, error) {
+	switch strings.ToLower(s) {
+	case "", "order":
+		return SummaryByOrder, nil
+	case "duration":
+		return SummaryByDuration, nil
+	case "status":
+		return SummaryByStatus, nil
+	default:
+		return SummaryByOrder, fmt.Errorf("invalid summary sort %q (want order, duration or status)", s)
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler with the flag spelling.
+func (s SummarySort) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler with ParseSummarySort, so the
+// sort order can be read from configuration files.
+func (s *SummarySort) UnmarshalText(text []byte) error {
+	v, err := ParseSummarySort(string(text))
+	if err != nil {
+		return err
+	}
+	*s = v
+	return nil
+}
+
+// String returns the flag spelling of the layout ("auto", "full",
+// "compact").
+func (l SummaryLayout) String() string {
+	switch l {
+	case SummaryAuto:
+		return "auto"
+	case SummaryFull:
+		return "full"
+	case SummaryCompact:
+		return "compact"
+	default:
+		return fmt.Sprintf("SummaryLayout(%d)", int(l))
+	}
+}
+
+// ParseSummaryLayout parses "auto", "full" or "compact" (case-insensitive).
+//
+// Example:
+//
+//	layout, err := renderer.ParseSummaryLayout(*summaryLayoutFlag)
+func ParseSummaryLayout(s string) (SummaryLayout, error) {
+	switch strings.ToLower(s) {
+	case "", "auto":
+		return SummaryAuto, nil
+	case "full":
+		return SummaryFull, nil
+	case "compact":
+		return SummaryCompact, nil
+	default:
+		return SummaryAuto, fmt.Errorf("invalid summary layout %q (want auto, full or compact)", s)
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler with the flag spelling.
+func (l SummaryLayout) MarshalText() ([]byte, error) {
+	return []byte(l.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler with ParseSummaryLayout, so the
+// layout can be read from configuration files.
+func (l *SummaryLayout) UnmarshalText(text []byte) error {
+	v, err := ParseSummaryLayout(string(text))
+	if err != nil {
+		return err
+	}
+	*l = v
+	return nil
+}
+
+// SummaryOptions configures the final summary written by WriteSummaryTo.
+// The zero value lists processes in spec order, picks the layout by the
+// number of processes and shows DefaultSummaryTailLines lines per failure.
+type SummaryOptions struct {
+	// Sort is the order of the processes.
+	Sort SummarySort
+
+	// Layout selects the full listing or the compact table.
+	Layout SummaryLayout
+
+	// TailLines is the number of retained output lines shown for each
+	// failed process. If zero, DefaultSummaryTailLines is used; if
+	// negative, no output is shown.
+	TailLines int
+}
+
+// WriteSummaryTo writes the final summary of a run to w.
+//
+// Full layout:
+//
+//	Summary:
+//	  - build: ok in 3.2s, 120 lines
+//	  - test: exit code 1 after 12s, 4210 lines (3210 evicted)
+//	    | --- FAIL: TestParse
+//	    | FAIL
+//	  - lint: incomplete, 0 lines
+//	  - check: ok in 0.3s, 40 lines, last 10 runs: 9 ok, 1 failed
+//
+// Scheduled processes report the results of their last runs
+// (ProcessState.Runs) in the full layout.
+//
+// Compact layout:
+//
+//	Summary:
+//	  PROCESS  STATUS       DURATION  LINES  EVICTED
+//	  build    ok           3.2s      120    0
+//	  test     exit code 1  12s       4210   3210
+//	  lint     incomplete   -         0      0
+//
+//	test (last 2 lines):
+//	  --- FAIL: TestParse
+//	  FAIL
+//
+// The tail is taken from the retained ProcessState.Lines, so it is bounded
+// by MaxLines and MaxBytes.
+func WriteSummaryTo(w io.Writer, states []ProcessState, opts SummaryOptions) {
+	order := summaryOrder(states, opts.Sort)
+	tail := opts.TailLines
+	if tail == 0 {
+		tail = DefaultSummaryTailLines
+	}
+	layout := opts.Layout
+	if layout == SummaryAuto {
+		layout = SummaryFull
+		if len(states) > DefaultSummaryCompactAbove {
+			layout = SummaryCompact
+		}
+	}
+
+	var b strings.Builder
+	b.WriteString("\nSummary:\n")
+	if layout == SummaryCompact {
+		writeSummaryTable(&b, states, order, tail)
+	} else {
+		for _, i := range order {
+			ps := &states[i]
+			fmt.Fprintf(&b, "  - %s: %s, %s%s\n", ps.Name, finalStatusOrIncomplete(ps), summaryLineCounts(ps), summaryRuns(ps))
+			for _, line := range summaryTail(ps, tail) {
+				b.WriteString(summaryTailIndent + SanitizeLine(line) + "\n")
+			}
+		}
+	}
+	_, _ = io.WriteString(w, b.String())
+}
+
+// writeSummaryTable writes the compact table, then the failure tails.
+func writeSummaryTable(b *strings.Builder, states []ProcessState, order []int, tail int) {
+	tw := tabwriter.NewWriter(b, 0, 0, summaryColumnGap, ' ', 0)
+	fmt.Fprintln(tw, "  PROCESS\tSTATUS\tDURATION\tLINES\tEVICTED")
+	for _, i := range order {
+		ps := &states[i]
+		status, duration := "incomplete", "-"
+		if ps.Done {
+			status = FormatExitError(ps.Err)
+			if !ps.StartedAt.IsZero() && !ps.FinishedAt.IsZero() {
+				duration = formatDuration(ps.Duration())
+			}
+		}
+		if ps.Removed {
+			status = statusRemoved
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%d\t%d\n", ps.Name, status, duration, ps.LineCount, ps.Evicted)
+	}
+	_ = tw.Flush()
+
+	for _, i := range order {
+		ps := &states[i]
+		lines := summaryTail(ps, tail)
+		if len(lines) == 0 {
+			continue
+		}
+		fmt.Fprintf(b, "\n%s (last %d lines):\n", ps.Name, len(lines))
+		for _, line := range lines {
+			b.WriteString("  " + SanitizeLine(line) + "\n")
+		}
+	}
+}
+
+// summaryLineCounts describes how many lines a process produced, e.g.,
+// "4210 lines (3210 evicted)".
+func summaryLineCounts(ps *ProcessState) string {
+	s := strconv.Itoa(ps.LineCount) + " lines"
+	if ps.LineCount == 1 {
+		s = "1 line"
+	}
+	if ps.Evicted > 0 {
+		s += " (" + strconv.Itoa(ps.Evicted) + " evicted)"
+	}
+	return s
+}
+
+// summaryRuns describes the last runs of a scheduled process, e.g.,
+// ", last 10 runs: 9 ok, 1 failed", or returns "" for other processes and
+// before the second run.
+func summaryRuns(ps *ProcessState) string {
+	if ps.NextRun.IsZero() || len(ps.Runs) < 2 {
+		return ""
+	}
+	var ok, failed, canceled int
+	for _, run := range ps.Runs {
+		switch {
+		case run.Canceled:
+			canceled++
+		case run.Err != nil:
+			failed++
+		default:
+			ok++
+		}
+	}
+	s := fmt.Sprintf(", last %d runs: %d ok, %d failed", len(ps.Runs), ok, failed)
+	if canceled > 0 {
+		s += ", " + strconv.Itoa(canceled) + " cancelled"
+	}
+	return s
+}
+
+// summaryTail returns the last n retained lines of a failed process, or
+// nil for processes that did not fail (or were cancelled or removed) and
+// for n < 0.
+func summaryTail(ps *ProcessState, n int) []string {
+	if n < 0 || !ps.Done || ps.Err == nil || ps.Canceled || ps.Removed {
+		return nil
+	}
+	return ps.Lines[max(len(ps.Lines)-n, 0):]
+}
+
+// summaryOrder returns the indexes of states in the given order.
+func summaryOrder(states []ProcessState, by SummarySort) []int {
+	order := make([]int, len(states))
+	for i := range order {
+		order[i] = i
+	}
+	switch by {
+	case SummaryByDuration:
+		slices.SortStableFunc(order, func(a, b int) int {
+			return cmp.Compare(states[b].Duration(), states[a].Duration())
+		})
+	case SummaryByStatus:
+		slices.SortStableFunc(order, func(a, b int) int {
+			return cmp.Compare(summaryRank(&states[a]), summaryRank(&states[b]))
+		})
+	case SummaryByOrder:
+	}
+	return order
+}
+
+// summaryRank ranks a process for SummaryByStatus: failed, then cancelled,
+// removed or incomplete, then successful.
+func summaryRank(ps *ProcessState) int {
+	switch {
+	case ps.Done && ps.Err != nil && !ps.Canceled && !ps.Removed:
+		return summaryRankFailed
+	case !ps.Done || ps.Canceled || ps.Removed:
+		return summaryRankPending
+	default:
+		return summaryRankOK
+	}
+}
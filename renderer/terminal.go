@@ -3,10 +3,16 @@ package renderer
 import (
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"os/signal"
 	"strings"
+	"sync"
 	"syscall"
+	"time"
+
+	"github.com/a2y-d5l/multiproc/engine"
 )
 
 // clearScreen clears the terminal screen and moves the cursor to the top-left.
@@ -21,9 +27,9 @@ import (
 //   - Works on Windows 10+ with VT100 emulation
 //   - When piped to file, escape codes are preserved (still readable)
 //
-// This function is called by RenderScreen() before each re-render in TTY mode.
-func clearScreen() {
-	fmt.Print("\x1b[H\x1b[2J")
+// This function is called by ScreenRenderer before each re-render in TTY mode.
+func clearScreen(w io.Writer) {
+	fmt.Fprint(w, "\x1b[H\x1b[2J")
 }
 
 // RenderScreen performs a full-screen re-render of all process states.
@@ -33,10 +39,11 @@ func clearScreen() {
 //  1. Check if any state is dirty (optimization)
 //  2. Clear the entire screen with ANSI codes
 //  3. Render each process in order:
-//     - Header: "Running <Name>... [<status>]"
+//     - Header while running: "<spinner> Running <Name>… [running <elapsed>]"
+//     - Header once done: "✓ <Name> [ok in <duration>]" or "✗ <Name> [<status> after <duration>]"
 //     - Output lines (indented)
 //     - Blank line separator
-//  4. Display footer with instructions
+//  4. Display the status bar and instructions
 //  5. Clear dirty flags on all states
 //
 // Status values:
@@ -52,22 +59,285 @@ func clearScreen() {
 //
 // Output format example:
 //
-//	Running build... [running]
+//	⠹ Running build… [running 12s]
 //	    Starting build process
 //	    Compiling...
 //
-//	Running test... [ok]
+//	✓ test [ok in 3.2s]
 //	    Running tests
 //	    All tests passed
 //
-//	Press Ctrl+C to cancel. Output updates in real time.
+//	queued 0 · running 1 · ok 1 · failed 0 · 12s  Press Ctrl+C to cancel.
 //
 // Parameters:
 //   - states: Slice of ProcessState to render
 //
 // This function writes directly to stdout and is intended for TTY environments.
+// It is a convenience wrapper around ScreenRenderer.
 func RenderScreen(states []ProcessState) {
-	// Fast path: if nothing is dirty, skip the render entirely.
+	NewScreenRenderer(os.Stdout).render(states)
+}
+
+// Terminal control sequences used by ScreenRenderer.
+const (
+	// enterAltScreen switches to the alternate screen buffer and hides the cursor.
+	enterAltScreen = "\x1b[?1049h\x1b[?25l"
+
+	// leaveAltScreen shows the cursor and switches back to the main screen buffer.
+	leaveAltScreen = "\x1b[?25h\x1b[?1049l"
+
+	// eraseLine erases the entire line the cursor is on.
+	eraseLine = "\x1b[2K"
+)
+
+// ScreenRenderer is the Renderer implementation behind RenderScreen.
+// It redraws the view on Start, on every Tick, and once more on Finish.
+//
+// Each process header shows a spinner and the elapsed time while the
+// process runs, and its final duration once it finishes. The bottom row is
+// a status bar with the number of queued, running, ok and failed processes
+// and the overall elapsed time.
+//
+// Layout:
+//   - With a known terminal size, each frame fits the screen: output rows
+//     are shared among processes (running ones get more), each block shows
+//     the tail of its output, and long lines are truncated (or wrapped)
+//   - With an unknown size, every retained line is printed (legacy layout)
+//
+// Drawing:
+//   - With a known size, each frame is diffed line by line against the
+//     previous one and only the rows that changed are rewritten, using
+//     cursor addressing. This avoids the flicker of clearing the screen,
+//     which is most visible over slow links such as SSH
+//   - With an unknown size, the screen is cleared and redrawn in full
+//
+// If Width and Height are zero and Out is a terminal, Start detects the
+// size with TerminalSize, tracks resizes (SIGWINCH) until Finish, and
+// enables AltScreen.
+//
+// Interactive mode:
+//
+// If Input is set and the terminal size is known, the view accepts key
+// presses (press ? for the list): select a process and expand it to full
+// height, scroll back through its retained lines, search them, restart or
+// stop it, collapse finished processes. Restart, stop and cancel need a
+// Controller, which runner.Run provides through SetController. The
+// terminal mode is restored by Finish, or by Restore if the run panics.
+//
+// Example:
+//
+//	r := renderer.NewScreenRenderer(os.Stdout)
+//	r.SummaryOut = os.Stderr
+//	cfg.Renderers = []renderer.Renderer{r}
+type ScreenRenderer struct {
+	// Out is the terminal the view is drawn on.
+	Out io.Writer
+
+	// SummaryOut receives the final summary. If nil, no summary is written.
+	SummaryOut io.Writer
+
+	// Summary configures the summary written to SummaryOut.
+	Summary SummaryOptions
+
+	// resize receives SIGWINCH while the renderer tracks the terminal size.
+	resize chan os.Signal
+
+	// prev holds the rows of the last frame drawn, for diffing.
+	// nil forces the next frame to be drawn in full.
+	prev []string
+
+	// Width is the terminal width in columns. 0 means unknown (no truncation).
+	Width int
+
+	// Height is the terminal height in rows. 0 means unknown (unbounded layout).
+	Height int
+
+	// Wrap wraps long lines onto several rows instead of truncating them.
+	Wrap bool
+
+	// Input is the keyboard, usually os.Stdin. If it is a terminal, it is
+	// put in raw mode (without cgo) while the view is shown; Ctrl+C still
+	// raises SIGINT. Any other reader is read as-is. nil disables
+	// interactive mode.
+	Input io.Reader
+
+	// ctl acts on processes in interactive mode. nil if not provided.
+	ctl Controller
+
+	// view is the interactive view state, nil when display-only.
+	view *screenView
+
+	// keys queues decoded key presses until the next Tick; guarded by keyMu.
+	keys  []key
+	keyMu sync.Mutex
+
+	// stopInput and inputDone stop and track the input reader.
+	stopInput chan struct{}
+	inputDone chan struct{}
+
+	// redraw forces the next render even if no state is dirty.
+	redraw bool
+
+	// start is when the run started, for the elapsed time in the status bar.
+	start time.Time
+
+	// AltScreen draws the view on the terminal's alternate screen buffer
+	// with the cursor hidden, so frames do not pile up in the user's
+	// scrollback. Finish switches back to the main screen and prints the
+	// final frame there once, so the outcome of the run remains visible.
+	AltScreen bool
+
+	// mu guards active and unraw, since Restore may be called from
+	// another goroutine.
+	mu sync.Mutex
+
+	// active is true while the alternate screen is in use.
+	active bool
+
+	// unraw restores the terminal mode of Input; nil if it is not raw.
+	unraw func() error
+}
+
+// NewScreenRenderer creates a ScreenRenderer drawing to out.
+// The final summary is disabled until SummaryOut is set.
+func NewScreenRenderer(out io.Writer) *ScreenRenderer {
+	return &ScreenRenderer{Out: out}
+}
+
+// Start implements Renderer. It detects the terminal size, switches to the
+// alternate screen if enabled, and draws the initial view.
+func (r *ScreenRenderer) Start(_ []engine.ProcessSpec, states []ProcessState) {
+	if f, ok := r.Out.(*os.File); ok && r.Width == 0 && r.Height == 0 {
+		if w, h, err := TerminalSize(f); err == nil {
+			r.Width, r.Height = w, h
+			r.AltScreen = true
+			r.resize = make(chan os.Signal, 1)
+			notifyResize(r.resize)
+		}
+	}
+	if r.AltScreen {
+		r.mu.Lock()
+		r.active = true
+		_, _ = io.WriteString(r.Out, enterAltScreen)
+		r.mu.Unlock()
+	}
+	if r.Input != nil && r.Height > 0 {
+		r.startInput(states)
+	}
+	r.start = earliestStart(states)
+	if r.start.IsZero() {
+		r.start = time.Now()
+	}
+	r.prev = nil
+	r.render(states)
+}
+
+// SetController implements Interactive. It must be called before Start.
+func (r *ScreenRenderer) SetController(c Controller) {
+	r.ctl = c
+}
+
+// Event implements Renderer. Events only mark states dirty; drawing is
+// deferred to Tick so that bursts of output are coalesced.
+func (r *ScreenRenderer) Event(ev Event, _ []ProcessState) {
+	if r.view != nil {
+		r.view.observe(ev, r.layout())
+	}
+}
+
+// Tick implements Renderer. It applies pending key presses and redraws the
+// view. With a known size, Tick is also what advances spinners and elapsed
+// times, so the runner calls it periodically (Config.TickInterval); only the
+// rows that changed are rewritten.
+func (r *ScreenRenderer) Tick(states []ProcessState) {
+	r.checkResize(states)
+	r.handleKeys(states)
+	r.render(states)
+}
+
+// Finish implements Renderer. It stops reading keys, draws the final frame
+// (always the display-only view), stops tracking terminal resizes and
+// restores the terminal. If the alternate screen was in use, the final
+// frame is printed once more on the main screen.
+func (r *ScreenRenderer) Finish(states []ProcessState) {
+	r.stopReading()
+	if r.view != nil {
+		r.view = nil
+		r.redraw = true
+	}
+	r.checkResize(states)
+	r.render(states)
+	if r.resize != nil {
+		signal.Stop(r.resize)
+		r.resize = nil
+	}
+
+	r.mu.Lock()
+	wasActive := r.active
+	r.mu.Unlock()
+	r.Restore()
+	if wasActive && len(r.prev) > 0 {
+		_, _ = io.WriteString(r.Out, strings.Join(r.prev, "\n")+"\n")
+	}
+}
+
+// Restore implements Restorer. It restores the terminal mode of Input,
+// shows the cursor and leaves the alternate screen, as far as the renderer
+// changed them; otherwise it does nothing.
+// It is safe to call concurrently with the other hooks and more than once.
+func (r *ScreenRenderer) Restore() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.unraw != nil {
+		_ = r.unraw()
+		r.unraw = nil
+	}
+	if !r.active {
+		return
+	}
+	r.active = false
+	_, _ = io.WriteString(r.Out, leaveAltScreen)
+}
+
+// FinalState implements Renderer. It writes the summary to SummaryOut, if set.
+func (r *ScreenRenderer) FinalState(states []ProcessState) error {
+	if r.SummaryOut != nil {
+		WriteSummaryTo(r.SummaryOut, states, r.Summary)
+	}
+	return nil
+}
+
+// checkResize re-reads the terminal size after a SIGWINCH and marks every
+// state dirty so that the next render redraws the whole view.
+func (r *ScreenRenderer) checkResize(states []ProcessState) {
+	if r.resize == nil {
+		return
+	}
+	select {
+	case <-r.resize:
+	default:
+		return
+	}
+	f, ok := r.Out.(*os.File)
+	if !ok {
+		return
+	}
+	if w, h, err := TerminalSize(f); err == nil {
+		r.Width, r.Height = w, h
+	}
+	// The terminal may have reflowed the old frame; diffing against it is
+	// meaningless, so draw the next frame in full.
+	r.prev = nil
+	for i := range states {
+		states[i].Dirty = true
+	}
+}
+
+// render draws states if any of them is dirty or a redraw was requested.
+func (r *ScreenRenderer) render(states []ProcessState) {
+	// Fast path: if nothing is dirty, skip the render entirely. A sized
+	// frame is always laid out, since spinners and elapsed times change on
+	// their own; diffing keeps an unchanged frame free.
 	hasDirty := false
 	for _, ps := range states {
 		if ps.Dirty {
@@ -75,36 +345,68 @@ func RenderScreen(states []ProcessState) {
 			break
 		}
 	}
-	if !hasDirty {
+	if !hasDirty && !r.redraw && r.Height <= 0 {
 		return
 	}
 
-	clearScreen()
+	frame := r.layout().frame(states)
 
-	for i := range states {
-		ps := &states[i]
-		status := "running"
-		if ps.Done {
-			status = FormatExitError(ps.Err)
+	var buf strings.Builder
+	if r.Height <= 0 {
+		// The unbounded layout scrolls, so rows cannot be addressed;
+		// clear and redraw everything, ending with a newline as before.
+		clearScreen(&buf)
+		buf.WriteString(strings.Join(frame, "\n"))
+		buf.WriteString("\n")
+	} else {
+		if r.prev == nil {
+			clearScreen(&buf)
 		}
+		diffFrame(&buf, r.prev, frame)
+	}
 
-		// Header: "Running Subprocess A… [running]"
-		fmt.Printf("Running %s… [%s]\n", ps.Name, status)
+	if buf.Len() > 0 {
+		r.mu.Lock()
+		_, _ = io.WriteString(r.Out, buf.String())
+		r.mu.Unlock()
+	}
 
-		for _, line := range ps.Lines {
-			trimmed := strings.TrimSpace(line)
-			if trimmed == "" {
-				fmt.Println()
-				continue
-			}
-			fmt.Printf("    %s\n", line)
-		}
+	r.prev = frame
+	r.redraw = false
+	for i := range states {
+		states[i].Dirty = false
+	}
+}
 
-		fmt.Println()
-		ps.Dirty = false
+// layout returns the layout for the current terminal size, view and time.
+func (r *ScreenRenderer) layout() screenLayout {
+	return screenLayout{
+		width:  r.Width,
+		height: r.Height,
+		wrap:   r.Wrap,
+		view:   r.view,
+		now:    time.Now(),
+		start:  r.start,
 	}
+}
 
-	fmt.Println("Press Ctrl+C to cancel. Output updates in real time.")
+// diffFrame writes the escape sequences that turn a screen showing prev into
+// one showing next: every row that differs is addressed with a cursor move,
+// erased and rewritten; rows of prev beyond the end of next are erased.
+// Unchanged rows produce no output.
+func diffFrame(w *strings.Builder, prev, next []string) {
+	for i := range max(len(prev), len(next)) {
+		var row string
+		if i < len(next) {
+			row = next[i]
+		}
+		if i < len(prev) && i < len(next) && prev[i] == row {
+			continue
+		}
+		// Erase before writing: erasing after a full-width row would also
+		// erase its last cell, since the cursor stays on the last column.
+		fmt.Fprintf(w, "\x1b[%d;1H%s%s", i+1, eraseLine, row)
+	}
 }
 
 // FormatExitError formats a process exit error into a human-readable string.
@@ -164,7 +466,28 @@ func FormatExitError(err error) string {
 	return fmt.Sprintf("exit code %d", exitCode)
 }
 
-// WriteFinalSummary prints a concise summary of all process results to stderr.
+// ExitCode extracts the numeric exit code from a process exit error.
+//
+// Return values:
+//   - 0: err is nil (success)
+//   - N: err is an exec.ExitError with exit code N (-1 if killed by a signal)
+//   - -1: any other error (e.g., the process could not be started)
+//
+// Example:
+//
+//	code := renderer.ExitCode(ps.Err)
+func ExitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// WriteFinalSummary prints a summary of all process results to stderr.
 // This is useful after the real-time view completes, especially when:
 //   - Scrollback history is long
 //   - Output was redirected to a file
@@ -173,16 +496,21 @@ func FormatExitError(err error) string {
 // Format:
 //
 //	Summary:
-//	  - <ProcessName>: <status>
-//	  - <ProcessName>: <status>
+//	  - <ProcessName>: <status>, <line counts>
+//	    | <output tail of failed processes>
 //	  ...
 //
 // Example output:
 //
 //	Summary:
-//	  - build: ok
-//	  - test: exit code 1
-//	  - lint: ok
+//	  - build: ok in 3.2s, 120 lines
+//	  - test: exit code 1 after 12s, 4210 lines (3210 evicted)
+//	    | --- FAIL: TestParse
+//	    | FAIL
+//	  - lint: ok in 1.1s, 3 lines
+//
+// It uses the default SummaryOptions; see WriteSummaryTo for sorting and
+// the compact table layout.
 //
 // Parameters:
 //   - states: Slice of ProcessState to summarize
@@ -190,11 +518,12 @@ func FormatExitError(err error) string {
 // This function writes to stderr to keep it separate from process output
 // and ensure visibility even when stdout is redirected.
 func WriteFinalSummary(states []ProcessState) {
-	fmt.Fprintln(os.Stderr, "\nSummary:")
-	for _, ps := range states {
-		status := FormatExitError(ps.Err)
-		fmt.Fprintf(os.Stderr, "  - %s: %s\n", ps.Name, status)
-	}
+	WriteFinalSummaryTo(os.Stderr, states)
+}
+
+// WriteFinalSummaryTo writes the summary produced by WriteFinalSummary to w.
+func WriteFinalSummaryTo(w io.Writer, states []ProcessState) {
+	WriteSummaryTo(w, states, SummaryOptions{})
 }
 
 // IsTTY reports whether the current stdout is a TTY (interactive terminal).
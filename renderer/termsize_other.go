@@ -0,0 +1,18 @@
+//go:build !(darwin || dragonfly || freebsd || linux || netbsd || openbsd)
+
+package renderer
+
+import (
+	"errors"
+	"os"
+)
+
+// TerminalSize returns the width (columns) and height (rows) of the
+// terminal attached to f. It is not supported on this platform and always
+// returns an error, so the full-screen view falls back to the unbounded layout.
+func TerminalSize(*os.File) (int, int, error) {
+	return 0, 0, errors.New("terminal size: not supported on this platform")
+}
+
+// notifyResize is a no-op: resize notifications are not supported on this platform.
+func notifyResize(chan<- os.Signal) {}
@@ -0,0 +1,44 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package renderer
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"unsafe"
+)
+
+// winsize mirrors struct winsize from <sys/ioctl.h>.
+type winsize struct {
+	Row    uint16
+	Col    uint16
+	Xpixel uint16
+	Ypixel uint16
+}
+
+// TerminalSize returns the width (columns) and height (rows) of the
+// terminal attached to f, using the TIOCGWINSZ ioctl.
+// It returns an error if f is not a terminal.
+//
+// Example:
+//
+//	width, height, err := renderer.TerminalSize(os.Stdout)
+func TerminalSize(f *os.File) (int, int, error) {
+	var ws winsize
+	_, _, errno := syscall.Syscall(
+		syscall.SYS_IOCTL,
+		f.Fd(),
+		uintptr(syscall.TIOCGWINSZ),
+		uintptr(unsafe.Pointer(&ws)), //nolint:gosec // ioctl requires a raw pointer to winsize
+	)
+	if errno != 0 {
+		return 0, 0, errno
+	}
+	return int(ws.Col), int(ws.Row), nil
+}
+
+// notifyResize relays SIGWINCH (terminal resized) to ch.
+func notifyResize(ch chan<- os.Signal) {
+	signal.Notify(ch, syscall.SIGWINCH)
+}
@@ -0,0 +1,481 @@
+package renderer
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Terminal text handling.
+//
+// Process output is written by programs that expect a terminal of their own:
+// it may contain colors, but also cursor movements, line erasures, window
+// titles and other escape sequences that would corrupt a shared view, and
+// characters that take two cells (CJK, emoji) or none (combining marks).
+// The functions below treat a line as a sequence of tokens:
+//   - Grapheme clusters: a base character with its combining marks,
+//     variation selectors, emoji modifiers and zero-width-joined sequences,
+//     measured in terminal cells
+//   - SGR sequences ("\x1b[...m", colors and text attributes), which take
+//     no space and are kept
+//   - Every other escape sequence (CSI, OSC, DCS, ...) and control
+//     character, which are dropped
+//
+// Tabs are expanded to spaces up to the next multiple of tabWidth cells.
+// Cluster segmentation and widths follow the common cases of Unicode
+// (UAX #29 and #11) without the full tables: good enough for a terminal
+// view, which is all they are used for.
+
+// tabWidth is the distance between tab stops, in cells.
+const tabWidth = 8
+
+// tokenKind classifies the tokens of a line (see nextToken).
+type tokenKind int
+
+const (
+	// tokenText is a grapheme cluster.
+	tokenText tokenKind = iota
+
+	// tokenSGR is an SGR escape sequence.
+	tokenSGR
+
+	// tokenTab is a horizontal tab.
+	tokenTab
+
+	// tokenControl is any other escape sequence or control character.
+	tokenControl
+)
+
+// Code points with a role in grapheme clusters.
+const (
+	zeroWidthJoiner     = '\u200d'
+	variationSelector16 = '\ufe0f' // emoji presentation
+	regionalIndicatorA  = '\U0001F1E6'
+	regionalIndicatorZ  = '\U0001F1FF'
+	emojiModifierFirst  = '\U0001F3FB'
+	emojiModifierLast   = '\U0001F3FF'
+	tagFirst            = '\U000E0020'
+	tagLast             = '\U000E007F'
+	c1First             = '\u0080'
+	c1Last              = '\u009f'
+	del                 = 0x7f
+)
+
+// wideCells is the width of East Asian wide characters and emoji.
+const wideCells = 2
+
+// StripANSI removes every escape sequence (colors included) and control
+// character from s, and expands tabs. Use it for output that does not go to
+// a terminal.
+//
+// Example:
+//
+//	renderer.StripANSI("\x1b[31mFAIL\x1b[0m\tTestParse") // "FAIL    TestParse"
+func StripANSI(s string) string {
+	return cleanText(s, false)
+}
+
+// SanitizeLine makes a line of process output safe to show in a shared
+// terminal view: SGR sequences (colors and text attributes) are kept, every
+// other escape sequence (cursor movement, erasing, window titles, ...) and
+// control character is removed, and tabs are expanded. If the line leaves
+// attributes set, a reset is appended so that they do not bleed into what
+// follows.
+//
+// Example:
+//
+//	renderer.SanitizeLine("\x1b[2K\r\x1b[32mok") // "\x1b[32mok\x1b[0m"
+func SanitizeLine(s string) string {
+	return cleanText(s, true)
+}
+
+// cleanText implements StripANSI (keepSGR false) and SanitizeLine.
+func cleanText(s string, keepSGR bool) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	width, styled := 0, false
+	for len(s) > 0 {
+		kind, n, w := nextToken(s)
+		switch kind {
+		case tokenText:
+			b.WriteString(s[:n])
+			width += w
+		case tokenTab:
+			pad := tabWidth - width%tabWidth
+			b.WriteString(strings.Repeat(" ", pad))
+			width += pad
+		case tokenSGR:
+			if keepSGR {
+				b.WriteString(s[:n])
+				styled = !isSGRReset(s[:n])
+			}
+		case tokenControl:
+		}
+		s = s[n:]
+	}
+	if styled {
+		b.WriteString(sgrReset)
+	}
+	return b.String()
+}
+
+// DisplayWidth returns the number of terminal cells s takes: escape
+// sequences and control characters take none, wide characters (CJK, most
+// emoji) take two, combining marks are part of the preceding character, and
+// tabs extend to the next tab stop.
+//
+// Example:
+//
+//	renderer.DisplayWidth("\x1b[1m日本\x1b[0m") // 4
+func DisplayWidth(s string) int {
+	width := 0
+	for len(s) > 0 {
+		kind, n, w := nextToken(s)
+		switch kind {
+		case tokenText:
+			width += w
+		case tokenTab:
+			width += tabWidth - width%tabWidth
+		case tokenSGR, tokenControl:
+		}
+		s = s[n:]
+	}
+	return width
+}
+
+// TruncateWidth cuts s to at most width cells, ending with tail (e.g., "…")
+// if anything was cut. It never splits an escape sequence or a grapheme
+// cluster: a wide character that does not fit is left out whole. If
+// attributes are set at the cut, a reset is inserted before tail.
+//
+// Like SanitizeLine, it drops escape sequences other than SGR and control
+// characters, and expands tabs.
+//
+// Example:
+//
+//	renderer.TruncateWidth("\x1b[31m日本語\x1b[0m", 5, "…") // "\x1b[31m日本\x1b[0m…"
+func TruncateWidth(s string, width int, tail string) string {
+	if DisplayWidth(s) <= width {
+		return SanitizeLine(s)
+	}
+	avail := max(width-DisplayWidth(tail), 0)
+
+	var b strings.Builder
+	used, styled := 0, false
+	for len(s) > 0 {
+		kind, n, w := nextToken(s)
+		if kind == tokenTab {
+			w = tabWidth - used%tabWidth
+		}
+		if (kind == tokenText || kind == tokenTab) && used+w > avail {
+			break
+		}
+		switch kind {
+		case tokenText:
+			b.WriteString(s[:n])
+		case tokenTab:
+			b.WriteString(strings.Repeat(" ", w))
+		case tokenSGR:
+			b.WriteString(s[:n])
+			styled = !isSGRReset(s[:n])
+		case tokenControl:
+		}
+		used += w
+		s = s[n:]
+	}
+	if styled {
+		b.WriteString(sgrReset)
+	}
+	return b.String() + tail
+}
+
+// WrapWidth splits s into rows of at most width cells, without splitting
+// escape sequences or grapheme clusters. Every row is self-contained:
+// attributes still set at the end of a row are reset there and set again
+// at the start of the next one. A cluster wider than width gets a row of
+// its own.
+//
+// Like SanitizeLine, it drops escape sequences other than SGR and control
+// characters, and expands tabs.
+//
+// Example:
+//
+//	renderer.WrapWidth("\x1b[32mabcdef", 4) // ["\x1b[32mabcd\x1b[0m", "\x1b[32mef\x1b[0m"]
+func WrapWidth(s string, width int) []string {
+	if width <= 0 {
+		return []string{SanitizeLine(s)}
+	}
+
+	var rows []string
+	var b strings.Builder
+	// style holds the SGR sequences in effect, to restore them on the next row.
+	style := ""
+	used, column := 0, 0
+	for len(s) > 0 {
+		kind, n, w := nextToken(s)
+		if kind == tokenTab {
+			// Expand against the whole line, so tabs align as without wrapping.
+			w = min(tabWidth-column%tabWidth, width)
+		}
+		if (kind == tokenText || kind == tokenTab) && used+w > width && used > 0 {
+			if style != "" {
+				b.WriteString(sgrReset)
+			}
+			rows = append(rows, b.String())
+			b.Reset()
+			b.WriteString(style)
+			used = 0
+		}
+		switch kind {
+		case tokenText:
+			b.WriteString(s[:n])
+		case tokenTab:
+			b.WriteString(strings.Repeat(" ", w))
+		case tokenSGR:
+			b.WriteString(s[:n])
+			if isSGRReset(s[:n]) {
+				style = ""
+			} else {
+				style += s[:n]
+			}
+		case tokenControl:
+		}
+		used += w
+		column += w
+		s = s[n:]
+	}
+	if style != "" {
+		b.WriteString(sgrReset)
+	}
+	return append(rows, b.String())
+}
+
+// nextToken returns the kind and byte length of the token at the start of
+// s (which must not be empty) and, for text, its width in cells.
+func nextToken(s string) (tokenKind, int, int) {
+	c := s[0]
+	switch {
+	case c == '\t':
+		return tokenTab, 1, 0
+	case c == '\x1b':
+		n, sgr := escapeSeqLen(s)
+		if sgr {
+			return tokenSGR, n, 0
+		}
+		return tokenControl, n, 0
+	case c < ' ' || c == del:
+		return tokenControl, 1, 0
+	case c < utf8.RuneSelf && (len(s) == 1 || s[1] < utf8.RuneSelf):
+		// Fast path: printable ASCII not followed by a combining mark.
+		return tokenText, 1, 1
+	}
+
+	r, n := utf8.DecodeRuneInString(s)
+	if r >= c1First && r <= c1Last {
+		return tokenControl, n, 0
+	}
+	width := runeWidth(r)
+	regional := isRegionalIndicator(r)
+	for n < len(s) {
+		next, size := utf8.DecodeRuneInString(s[n:])
+		switch {
+		case next == zeroWidthJoiner:
+			// The joiner and the character it joins belong to the cluster.
+			n += size
+			if n < len(s) && s[n] >= ' ' {
+				_, size = utf8.DecodeRuneInString(s[n:])
+				n += size
+			}
+			continue
+		case isGraphemeExtend(next):
+			if next == variationSelector16 {
+				width = wideCells
+			}
+			n += size
+			continue
+		case regional && isRegionalIndicator(next):
+			// A pair of regional indicators is a flag.
+			regional = false
+			width = wideCells
+			n += size
+			continue
+		}
+		break
+	}
+	return tokenText, n, width
+}
+
+// escapeSeqLen returns the length of the escape sequence at the start of s
+// (which starts with ESC), and whether it is an SGR sequence. A sequence
+// cut short by the end of s extends to the end.
+func escapeSeqLen(s string) (int, bool) {
+	const (
+		introLen      = 2 // ESC and the byte selecting the sequence type
+		stLen         = 2 // ESC \
+		csiFinalFirst = 0x40
+		csiFinalLast  = 0x7e
+		intermFirst   = 0x20
+		intermLast    = 0x2f
+	)
+	if len(s) < introLen {
+		return len(s), false
+	}
+	switch s[1] {
+	case '[':
+		// CSI: parameter and intermediate bytes, then a final byte.
+		for i := introLen; i < len(s); i++ {
+			c := s[i]
+			switch {
+			case c >= csiFinalFirst && c <= csiFinalLast:
+				return i + 1, c == 'm' && isSGRParams(s[introLen:i])
+			case c < intermFirst:
+				// Malformed: end the sequence before the control character.
+				return i, false
+			}
+		}
+		return len(s), false
+
+	case ']', 'P', 'X', '^', '_':
+		// OSC, DCS, SOS, PM and APC: a string ended by BEL or ST (ESC \).
+		for i := introLen; i < len(s); i++ {
+			switch {
+			case s[i] == '\a':
+				return i + 1, false
+			case s[i] == '\x1b' && i+1 < len(s) && s[i+1] == '\\':
+				return i + stLen, false
+			case s[i] == '\x1b':
+				return i, false
+			}
+		}
+		return len(s), false
+
+	default:
+		// Two-character sequence, possibly with intermediate bytes.
+		i := 1
+		for i < len(s) && s[i] >= intermFirst && s[i] <= intermLast {
+			i++
+		}
+		return min(i+1, len(s)), false
+	}
+}
+
+// isSGRParams reports whether p is a valid SGR parameter string: digits
+// separated by ";" (or ":" for extended colors).
+func isSGRParams(p string) bool {
+	for i := range len(p) {
+		if c := p[i]; (c < '0' || c > '9') && c != ';' && c != ':' {
+			return false
+		}
+	}
+	return true
+}
+
+// sgrParams parses the parameters of an SGR sequence. Empty parameters are
+// zero, so "\x1b[m" yields [0].
+func sgrParams(seq string) []int {
+	p := strings.ReplaceAll(seq[len("\x1b["):len(seq)-1], ":", ";")
+	fields := strings.Split(p, ";")
+	params := make([]int, len(fields))
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			n = 0
+		}
+		params[i] = n
+	}
+	return params
+}
+
+// isSGRReset reports whether the SGR sequence seq resets every attribute.
+func isSGRReset(seq string) bool {
+	return strings.Trim(seq[len("\x1b["):len(seq)-1], "0;") == ""
+}
+
+// isRegionalIndicator reports whether r is a regional indicator symbol
+// (two of which make a flag).
+func isRegionalIndicator(r rune) bool {
+	return r >= regionalIndicatorA && r <= regionalIndicatorZ
+}
+
+// isGraphemeExtend reports whether r continues the preceding grapheme
+// cluster: combining marks, variation selectors, emoji modifiers and tags.
+func isGraphemeExtend(r rune) bool {
+	if r < unicode.MaxLatin1 {
+		return false
+	}
+	return unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc) ||
+		(r >= emojiModifierFirst && r <= emojiModifierLast) ||
+		(r >= tagFirst && r <= tagLast)
+}
+
+// runeWidth returns the cells taken by r at the start of a cluster: 0 for
+// combining marks and format characters, 2 for East Asian wide and
+// fullwidth characters and emoji, 1 otherwise.
+func runeWidth(r rune) int {
+	const firstWide = 0x1100
+	switch {
+	case r < firstWide:
+		if r >= unicode.MaxLatin1 && unicode.In(r, unicode.Mn, unicode.Me) {
+			return 0
+		}
+		return 1
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case isWide(r):
+		return wideCells
+	default:
+		return 1
+	}
+}
+
+// isWide reports whether r is an East Asian wide or fullwidth character or
+// an emoji shown in emoji presentation by default.
+func isWide(r rune) bool {
+	wide := [...][2]rune{
+		{0x1100, 0x115F}, // Hangul Jamo initial consonants
+		{0x231A, 0x231B}, {0x2329, 0x232A}, {0x23E9, 0x23EC}, {0x23F0, 0x23F0}, {0x23F3, 0x23F3},
+		{0x25FD, 0x25FE}, {0x2614, 0x2615}, {0x2648, 0x2653}, {0x267F, 0x267F}, {0x2693, 0x2693},
+		{0x26A1, 0x26A1}, {0x26AA, 0x26AB}, {0x26BD, 0x26BE}, {0x26C4, 0x26C5}, {0x26CE, 0x26CE},
+		{0x26D4, 0x26D4}, {0x26EA, 0x26EA}, {0x26F2, 0x26F3}, {0x26F5, 0x26F5}, {0x26FA, 0x26FA},
+		{0x26FD, 0x26FD}, {0x2705, 0x2705}, {0x270A, 0x270B}, {0x2728, 0x2728}, {0x274C, 0x274C},
+		{0x274E, 0x274E}, {0x2753, 0x2755}, {0x2757, 0x2757}, {0x2795, 0x2797}, {0x27B0, 0x27B0},
+		{0x27BF, 0x27BF}, {0x2B1B, 0x2B1C}, {0x2B50, 0x2B50}, {0x2B55, 0x2B55},
+		{0x2E80, 0x303E},   // CJK radicals, symbols and punctuation
+		{0x3041, 0x33FF},   // Kana, Bopomofo, Hangul compatibility, CJK compatibility
+		{0x3400, 0x4DBF},   // CJK extension A
+		{0x4E00, 0x9FFF},   // CJK unified ideographs
+		{0xA000, 0xA4CF},   // Yi
+		{0xA960, 0xA97F},   // Hangul Jamo extended A
+		{0xAC00, 0xD7A3},   // Hangul syllables
+		{0xF900, 0xFAFF},   // CJK compatibility ideographs
+		{0xFE10, 0xFE19},   // Vertical forms
+		{0xFE30, 0xFE6F},   // CJK compatibility forms, small forms
+		{0xFF00, 0xFF60},   // Fullwidth forms
+		{0xFFE0, 0xFFE6},   // Fullwidth signs
+		{0x16FE0, 0x18CFF}, // Tangut, Khitan
+		{0x1B000, 0x1B2FF}, // Kana supplement and extensions, Nushu
+		{0x1F004, 0x1F004}, {0x1F0CF, 0x1F0CF}, {0x1F18E, 0x1F18E}, {0x1F191, 0x1F19A},
+		{0x1F200, 0x1F2FF}, // Enclosed ideographic supplement
+		{0x1F300, 0x1F320}, {0x1F32D, 0x1F335}, {0x1F337, 0x1F37C}, {0x1F37E, 0x1F393},
+		{0x1F3A0, 0x1F3CA}, {0x1F3CF, 0x1F3D3}, {0x1F3E0, 0x1F3F0}, {0x1F3F4, 0x1F3F4},
+		{0x1F3F8, 0x1F43E}, {0x1F440, 0x1F440}, {0x1F442, 0x1F4FC}, {0x1F4FF, 0x1F53D},
+		{0x1F54B, 0x1F54E}, {0x1F550, 0x1F567}, {0x1F57A, 0x1F57A}, {0x1F595, 0x1F596},
+		{0x1F5A4, 0x1F5A4}, {0x1F5FB, 0x1F64F}, {0x1F680, 0x1F6C5}, {0x1F6CC, 0x1F6CC},
+		{0x1F6D0, 0x1F6D2}, {0x1F6D5, 0x1F6D7}, {0x1F6DC, 0x1F6DF}, {0x1F6EB, 0x1F6EC},
+		{0x1F6F4, 0x1F6FC}, {0x1F7E0, 0x1F7EB}, {0x1F7F0, 0x1F7F0}, {0x1F90C, 0x1F93A},
+		{0x1F93C, 0x1F945}, {0x1F947, 0x1F9FF}, {0x1FA70, 0x1FAFF},
+		{0x20000, 0x3FFFD}, // CJK extensions B and later
+	}
+	// Binary search for the last range starting at or before r.
+	lo, hi := 0, len(wide)
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		if wide[mid][0] <= r {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo > 0 && r <= wide[lo-1][1]
+}
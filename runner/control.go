@@ -0,0 +1,39 @@
+package runner
+
+import (
+	"context"
+	"errors"
+
+	"github.com/a2y-d5l/multiproc/engine"
+)
+
+// errUserCancel is the cancellation cause when an interactive renderer
+// cancels the run (e.g., the user pressed q).
+var errUserCancel = errors.New("cancelled from the terminal")
+
+// controller implements renderer.Controller for the renderer of one sink.
+type controller struct {
+	eng    *engine.Engine
+	cancel context.CancelCauseFunc
+	sink   *sink
+}
+
+// Restart implements renderer.Controller.
+func (c *controller) Restart(index int) error {
+	return c.eng.Restart(index)
+}
+
+// Stop implements renderer.Controller.
+func (c *controller) Stop(index int) error {
+	return c.eng.Stop(index)
+}
+
+// Cancel implements renderer.Controller.
+func (c *controller) Cancel() {
+	c.cancel(errUserCancel)
+}
+
+// Redraw implements renderer.Controller. It schedules a Tick on the sink.
+func (c *controller) Redraw() {
+	c.sink.wake()
+}
@@ -0,0 +1,354 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/a2y-d5l/multiproc/engine"
+	"github.com/a2y-d5l/multiproc/renderer"
+)
+
+// Restart policies of a planned process.
+const (
+	// RestartNever means the process runs once.
+	RestartNever = "never"
+
+	// RestartManual means the process runs once but can be restarted from
+	// the interactive full-screen view.
+	RestartManual = "manual"
+
+	// RestartOnChange means the process is started again whenever its
+	// watched files change (engine.ProcessSpec.Watch).
+	RestartOnChange = "on-change"
+)
+
+// Plan describes what Run would do with a configuration, without starting
+// anything: the processes, in order, with their effective settings. It is
+// what the CLI prints with -dry-run.
+//
+// Example:
+//
+//	plan, err := runner.NewPlan(cfg)
+//	if err != nil {
+//	    return err
+//	}
+//	return plan.WriteText(os.Stdout) // or json.NewEncoder(os.Stdout).Encode(plan)
+type Plan struct {
+	// Dir is the working directory of multiproc, which processes without a
+	// ProcessSpec.Dir run in.
+	Dir string `json:"dir"`
+
+	// Waves lists the names of the processes started together, in the
+	// order the waves start (see engine.Waves): the first wave starts right
+	// away, and each process of a later wave once the processes it depends
+	// on have exited successfully.
+	Waves [][]string `json:"waves"`
+
+	// Processes are the processes to run, in spec order.
+	Processes []PlannedProcess `json:"processes"`
+}
+
+// PlannedProcess is a process of a Plan.
+type PlannedProcess struct {
+	// Index is the position of the process in Config.Specs.
+	Index int `json:"index"`
+
+	// Name is the process name.
+	Name string `json:"name"`
+
+	// CommandLine is the command and arguments quoted for a POSIX shell
+	// (see engine.ProcessSpec.CommandLine).
+	CommandLine string `json:"command_line"`
+
+	// Argv is the command followed by its arguments, exactly as they are
+	// passed to the process.
+	Argv []string `json:"argv"`
+
+	// Dir is the absolute working directory of the process.
+	Dir string `json:"dir"`
+
+	// Env lists the environment variables ProcessSpec.Env adds or changes
+	// compared to the environment of the current process, sorted by name.
+	Env []EnvChange `json:"env"`
+
+	// MaxLines is the effective line limit of the output history:
+	// ProcessSpec.MaxLines, or Config.MaxLinesPerProc.
+	MaxLines int `json:"max_lines"`
+
+	// MaxBytes is the byte limit of the output history; 0 means none.
+	MaxBytes int `json:"max_bytes"`
+
+	// ShutdownTimeout is how long the process has to exit after SIGTERM
+	// on shutdown before it is killed, as a duration string ("5s").
+	ShutdownTimeout string `json:"shutdown_timeout"`
+
+	// Restart is the restart policy: RestartNever, RestartManual or
+	// RestartOnChange.
+	Restart string `json:"restart"`
+
+	// Watch and WatchIgnore are the glob patterns of the watched and the
+	// ignored files, and WatchDebounce the effective debounce window as a
+	// duration string ("200ms"), for a process restarted on change.
+	Watch         []string `json:"watch,omitempty"`
+	WatchIgnore   []string `json:"watch_ignore,omitempty"`
+	WatchDebounce string   `json:"watch_debounce,omitempty"`
+
+	// Schedule is the schedule of a scheduled process, and
+	// ScheduleOverlap what happens to runs it overlaps ("skip", "queue").
+	Schedule        string `json:"schedule,omitempty"`
+	ScheduleOverlap string `json:"schedule_overlap,omitempty"`
+
+	// Wave is the index of the wave the process starts in, in Plan.Waves.
+	Wave int `json:"wave"`
+
+	// DependsOn names the processes that must exit successfully before
+	// this one starts (engine.ProcessSpec.DependsOn).
+	DependsOn []string `json:"depends_on,omitempty"`
+
+	// Group and Tags are the group and tags of the process, if any.
+	Group string   `json:"group,omitempty"`
+	Tags  []string `json:"tags,omitempty"`
+}
+
+// EnvChange is an environment variable set for a planned process.
+type EnvChange struct {
+	// Name and Value are the variable and the value the process gets.
+	Name  string `json:"name"`
+	Value string `json:"value"`
+
+	// Overrides reports whether the variable is set, to Previous, in the
+	// environment of the current process.
+	Overrides bool   `json:"overrides"`
+	Previous  string `json:"previous,omitempty"`
+}
+
+// NewPlan returns the plan of a run of cfg, with the defaults Run applies.
+// It fails if the working directory cannot be determined or the
+// dependencies of the processes form a cycle.
+func NewPlan(cfg Config) (*Plan, error) {
+	cfg = withDefaults(cfg)
+	dir, err := os.Getwd()
+	if err != nil {
+		return nil, fmt.Errorf("plan: %w", err)
+	}
+	waves, err := engine.Waves(cfg.Specs)
+	if err != nil {
+		return nil, fmt.Errorf("plan: %w", err)
+	}
+
+	restart := RestartNever
+	if interactiveView(cfg) {
+		restart = RestartManual
+	}
+
+	plan := &Plan{Dir: dir, Waves: make([][]string, len(waves)), Processes: make([]PlannedProcess, len(cfg.Specs))}
+	wave := make([]int, len(cfg.Specs))
+	for w, indexes := range waves {
+		for _, i := range indexes {
+			wave[i] = w
+			plan.Waves[w] = append(plan.Waves[w], cfg.Specs[i].Name)
+		}
+	}
+	for i, spec := range cfg.Specs {
+		maxLines := spec.MaxLines
+		if maxLines <= 0 {
+			maxLines = cfg.MaxLinesPerProc
+		}
+		procDir := dir
+		if spec.Dir != "" {
+			procDir = spec.Dir
+			if !filepath.IsAbs(procDir) {
+				procDir = filepath.Join(dir, procDir)
+			}
+		}
+		plan.Processes[i] = PlannedProcess{
+			Index:           i,
+			Name:            spec.Name,
+			CommandLine:     spec.CommandLine(),
+			Argv:            append([]string{spec.Command}, spec.Args...),
+			Dir:             procDir,
+			Env:             envChanges(spec.Env),
+			MaxLines:        maxLines,
+			MaxBytes:        spec.MaxBytes,
+			ShutdownTimeout: cfg.ShutdownTimeout.String(),
+			Restart:         restart,
+			Wave:            wave[i],
+			DependsOn:       spec.DependsOn,
+			Group:           spec.Group,
+			Tags:            spec.Tags,
+		}
+		if len(spec.Watch) > 0 {
+			debounce := spec.WatchDebounce
+			if debounce <= 0 {
+				debounce = engine.DefaultWatchDebounce
+			}
+			proc := &plan.Processes[i]
+			proc.Restart = RestartOnChange
+			proc.Watch, proc.WatchIgnore, proc.WatchDebounce = spec.Watch, spec.WatchIgnore, debounce.String()
+		}
+		if spec.Schedule != "" {
+			plan.Processes[i].Schedule = spec.Schedule
+			plan.Processes[i].ScheduleOverlap = spec.ScheduleOverlap.String()
+		}
+	}
+	return plan, nil
+}
+
+// WriteText writes the plan in a human-readable form. The env lines list
+// the variables that differ from the current environment:
+//
+//	Plan: 3 processes in 2 waves, nothing started
+//	Working directory: /src/app
+//
+//	Wave 1: api, web
+//	Wave 2: e2e (after api, web)
+//
+//	api
+//	  command:  go run ./cmd/api
+//	  argv:     "go" "run" "./cmd/api"
+//	  dir:      /src/app
+//	  env:      PORT=5000
+//	            DEBUG=1 (was 0)
+//	  limits:   1000 lines, no byte limit
+//	  shutdown: SIGTERM, then SIGKILL after 5s
+//	  restart:  on-change
+//	  after:    db
+//	  watch:    **/*.go (ignoring *_test.go), debounce 200ms
+//	  schedule: */5 * * * *, skip overlapping runs
+//	  group:    backend
+func (p *Plan) WriteText(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Plan: %s in %s, nothing started\n", plural(len(p.Processes), "process", "processes"),
+		plural(len(p.Waves), "wave", "waves"))
+	fmt.Fprintf(&b, "Working directory: %s\n", p.Dir)
+	b.WriteString("\n")
+	for i, wave := range p.Waves {
+		fmt.Fprintf(&b, "Wave %d: %s", i+1, strings.Join(wave, ", "))
+		if i > 0 {
+			fmt.Fprintf(&b, " (after %s)", strings.Join(p.dependencies(wave), ", "))
+		}
+		b.WriteString("\n")
+	}
+
+	for _, proc := range p.Processes {
+		argv := make([]string, len(proc.Argv))
+		for i, arg := range proc.Argv {
+			argv[i] = strconv.Quote(arg)
+		}
+		limits := strconv.Itoa(proc.MaxLines) + " lines, no byte limit"
+		if proc.MaxBytes > 0 {
+			limits = fmt.Sprintf("%d lines, %d bytes", proc.MaxLines, proc.MaxBytes)
+		}
+
+		fmt.Fprintf(&b, "\n%s\n", proc.Name)
+		fmt.Fprintf(&b, "  command:  %s\n", proc.CommandLine)
+		fmt.Fprintf(&b, "  argv:     %s\n", strings.Join(argv, " "))
+		fmt.Fprintf(&b, "  dir:      %s\n", proc.Dir)
+		if len(proc.Env) == 0 {
+			b.WriteString("  env:      (inherited)\n")
+		}
+		for i, e := range proc.Env {
+			label := "  env:      "
+			if i > 0 {
+				label = "            "
+			}
+			fmt.Fprintf(&b, "%s%s=%s", label, e.Name, e.Value)
+			if e.Overrides {
+				fmt.Fprintf(&b, " (was %s)", e.Previous)
+			}
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "  limits:   %s\n", limits)
+		fmt.Fprintf(&b, "  shutdown: SIGTERM, then SIGKILL after %s\n", proc.ShutdownTimeout)
+		fmt.Fprintf(&b, "  restart:  %s\n", proc.Restart)
+		if len(proc.DependsOn) > 0 {
+			fmt.Fprintf(&b, "  after:    %s\n", strings.Join(proc.DependsOn, ", "))
+		}
+		if len(proc.Watch) > 0 {
+			fmt.Fprintf(&b, "  watch:    %s", strings.Join(proc.Watch, ", "))
+			if len(proc.WatchIgnore) > 0 {
+				fmt.Fprintf(&b, " (ignoring %s)", strings.Join(proc.WatchIgnore, ", "))
+			}
+			fmt.Fprintf(&b, ", debounce %s\n", proc.WatchDebounce)
+		}
+		if proc.Schedule != "" {
+			fmt.Fprintf(&b, "  schedule: %s, %s overlapping runs\n", proc.Schedule, proc.ScheduleOverlap)
+		}
+		if proc.Group != "" {
+			fmt.Fprintf(&b, "  group:    %s\n", proc.Group)
+		}
+		if len(proc.Tags) > 0 {
+			fmt.Fprintf(&b, "  tags:     %s\n", strings.Join(proc.Tags, ", "))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// dependencies returns the names of the processes that the processes
+// called names depend on, without duplicates, in the order of the waves.
+func (p *Plan) dependencies(names []string) []string {
+	var deps []string
+	for _, wave := range p.Waves {
+		for _, name := range wave {
+			needed := slices.ContainsFunc(p.Processes, func(proc PlannedProcess) bool {
+				return slices.Contains(names, proc.Name) && slices.Contains(proc.DependsOn, name)
+			})
+			if needed {
+				deps = append(deps, name)
+			}
+		}
+	}
+	return deps
+}
+
+// envChanges returns the variables of env, "KEY=value" entries where the
+// last of a key wins, that differ from the environment of the current
+// process.
+func envChanges(env []string) []EnvChange {
+	values := map[string]string{}
+	for _, e := range env {
+		if k, v, ok := strings.Cut(e, "="); ok {
+			values[k] = v
+		}
+	}
+
+	changes := []EnvChange{}
+	for k, v := range values {
+		prev, set := os.LookupEnv(k)
+		if set && prev == v {
+			continue
+		}
+		changes = append(changes, EnvChange{Name: k, Value: v, Overrides: set, Previous: prev})
+	}
+	slices.SortFunc(changes, func(a, b EnvChange) int { return strings.Compare(a.Name, b.Name) })
+	return changes
+}
+
+// interactiveView reports whether Run would show the interactive
+// full-screen view for cfg, as DefaultRenderer picks it.
+func interactiveView(cfg Config) bool {
+	if !cfg.Interactive || !cfg.FullScreen || len(cfg.Renderers) > 0 {
+		return false
+	}
+	if cfg.Output == renderer.OutputGrouped || renderer.ResolveCIPlatform(cfg.CI) != renderer.CINone {
+		return false
+	}
+	if cfg.IsTTY != nil {
+		return *cfg.IsTTY
+	}
+	return renderer.IsTTY()
+}
+
+// plural returns n followed by the singular or plural noun.
+func plural(n int, singular, pluralForm string) string {
+	if n == 1 {
+		return "1 " + singular
+	}
+	return strconv.Itoa(n) + " " + pluralForm
+}
@@ -0,0 +1,140 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/a2y-d5l/multiproc/engine"
+)
+
+// reloader applies the specs received on Config.Reload to the engine of a
+// run in progress.
+type reloader struct {
+	eng *engine.Engine
+	cfg Config
+
+	// specs are the current specs of the run, by engine index.
+	specs []engine.ProcessSpec
+
+	// removed marks the indexes of removed processes, which are never
+	// reused.
+	removed []bool
+}
+
+// newReloader creates a reloader for a run of cfg on eng.
+func newReloader(eng *engine.Engine, cfg Config) *reloader {
+	return &reloader{
+		eng:     eng,
+		cfg:     cfg,
+		specs:   append([]engine.ProcessSpec(nil), cfg.Specs...),
+		removed: make([]bool, len(cfg.Specs)),
+	}
+}
+
+// run applies every reload until ctx is done, printing errors to errOut.
+// Reloads are received once the engine has started its run (see
+// engine.Engine.Started), so that an early one is not lost.
+func (r *reloader) run(ctx context.Context, reload <-chan []engine.ProcessSpec, errOut io.Writer) {
+	select {
+	case <-ctx.Done():
+		return
+	case <-r.eng.Started():
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case next := <-reload:
+			if err := r.apply(next); err != nil {
+				for _, line := range strings.Split(err.Error(), "\n") {
+					fmt.Fprintf(errOut, "multiproc: reload: %s\n", line)
+				}
+			}
+		}
+	}
+}
+
+// apply changes the run to next: processes are matched by name, new ones
+// are added, changed ones replaced, and missing ones removed. Invalid specs
+// change nothing. Additions and replacements come first, so that the run
+// does not end when every old process is removed. Once every process has
+// exited, the run is over and the reload is ignored.
+func (r *reloader) apply(next []engine.ProcessSpec) error {
+	if len(next) == 0 {
+		return errors.New("no processes")
+	}
+	check := r.cfg
+	check.Specs = next
+	if err := check.Validate(); err != nil {
+		return err
+	}
+
+	live := map[string]int{}
+	for i, spec := range r.specs {
+		if !r.removed[i] {
+			live[spec.Name] = i
+		}
+	}
+
+	var errs []error
+	keep := map[string]bool{}
+	for _, spec := range next {
+		keep[spec.Name] = true
+		i, ok := live[spec.Name]
+		switch {
+		case !ok:
+			idx, err := r.eng.Add(spec)
+			if errors.Is(err, engine.ErrNoRun) {
+				return nil
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("add %q: %w", spec.Name, err))
+				continue
+			}
+			r.track(idx, spec)
+		case !reflect.DeepEqual(r.specs[i], spec):
+			err := r.eng.Replace(i, spec)
+			if errors.Is(err, engine.ErrNoRun) {
+				return nil
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("restart %q: %w", spec.Name, err))
+				continue
+			}
+			r.specs[i] = spec
+		}
+	}
+
+	for i, spec := range r.specs {
+		if r.removed[i] || keep[spec.Name] {
+			continue
+		}
+		err := r.eng.Remove(i)
+		if errors.Is(err, engine.ErrNoRun) {
+			return nil
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("remove %q: %w", spec.Name, err))
+			continue
+		}
+		r.removed[i] = true
+	}
+	return errors.Join(errs...)
+}
+
+// track records spec as the process the engine added at idx. Indexes below
+// idx that the reloader did not add itself belong to another caller of
+// engine.Engine.Add; they are marked removed so that reloads leave them
+// alone.
+func (r *reloader) track(idx int, spec engine.ProcessSpec) {
+	for len(r.specs) < idx {
+		r.specs = append(r.specs, engine.ProcessSpec{})
+		r.removed = append(r.removed, true)
+	}
+	r.specs = append(r.specs, spec)
+	r.removed = append(r.removed, false)
+}
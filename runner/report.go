@@ -0,0 +1,70 @@
+package runner
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/a2y-d5l/multiproc/renderer"
+)
+
+// reportFile is a file written by a report renderer (JUnit XML, HTML).
+type reportFile struct {
+	f    *os.File
+	w    *bufio.Writer
+	path string
+}
+
+// createReport creates (or truncates) the report file at path.
+func createReport(path string) (*reportFile, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("report: %w", err)
+	}
+	return &reportFile{f: f, w: bufio.NewWriter(f), path: path}, nil
+}
+
+// close flushes and closes the report file.
+func (rf *reportFile) close() error {
+	if err := errors.Join(rf.w.Flush(), rf.f.Close()); err != nil {
+		return fmt.Errorf("report %s: %w", rf.path, err)
+	}
+	return nil
+}
+
+// openReports creates the report files configured in cfg and returns the
+// renderers writing them. On error, the files created so far are closed.
+func openReports(cfg Config) ([]renderer.Renderer, []*reportFile, error) {
+	var renderers []renderer.Renderer
+	var files []*reportFile
+	if cfg.JUnitFile != "" {
+		rf, err := createReport(cfg.JUnitFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		files = append(files, rf)
+		renderers = append(renderers, renderer.NewJUnitRenderer(rf.w))
+	}
+	if cfg.HTMLFile != "" {
+		rf, err := createReport(cfg.HTMLFile)
+		if err != nil {
+			_ = closeReports(files)
+			return nil, nil, err
+		}
+		files = append(files, rf)
+		renderers = append(renderers, renderer.NewHTMLRenderer(rf.w))
+	}
+	return slices.Clip(renderers), files, nil
+}
+
+// closeReports closes every report file and returns the errors of those
+// that could not be written completely.
+func closeReports(files []*reportFile) error {
+	var errs []error
+	for _, rf := range files {
+		errs = append(errs, rf.close())
+	}
+	return errors.Join(errs...)
+}
@@ -9,6 +9,7 @@
 //   - Config: High-level configuration with sensible defaults
 //   - Run(): Main entry point that coordinates everything
 //   - Automatic TTY detection and renderer selection
+//   - Pluggable renderers via renderer.Renderer
 //   - Render debouncing for performance
 //   - Event conversion between layers
 //
@@ -27,7 +28,12 @@ package runner //nolint:cyclop // Package complexity is expected for high-level
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -42,6 +48,12 @@ const (
 	// defaultShutdownTimeout is the default time to wait for graceful shutdown.
 	defaultShutdownTimeout = 5 * time.Second
 
+	// defaultTickInterval is how often renderers are ticked without events.
+	defaultTickInterval = 100 * time.Millisecond
+
+	// defaultMaxFrameRate is the default cap on renderer ticks per second.
+	defaultMaxFrameRate = 30
+
 	// eventChannelBuffer is the buffer size for event channels.
 	eventChannelBuffer = 128
 )
@@ -147,10 +159,12 @@ type Config struct {
 
 	// ShowSummary enables printing a summary to stderr after execution completes.
 	//
-	// The summary shows the final status of each process:
+	// The summary shows the final status, duration and line counts of each
+	// process, and the output tail of failed processes:
 	//   Summary:
-	//     - build: ok
-	//     - test: exit code 1
+	//     - build: ok in 3.2s, 120 lines
+	//     - test: exit code 1 after 12s, 4210 lines (3210 evicted)
+	//       | FAIL
 	//
 	// Useful when:
 	//   - Output is long and you need quick overview
@@ -158,6 +172,20 @@ type Config struct {
 	//   - Debugging failures across multiple processes
 	ShowSummary bool
 
+	// SummarySort orders the processes in the summary: spec order (the
+	// default), longest first, or failures first.
+	SummarySort renderer.SummarySort
+
+	// SummaryLayout selects the full summary or a compact table. The
+	// default, renderer.SummaryAuto, switches to the table when there are
+	// more than renderer.DefaultSummaryCompactAbove processes.
+	SummaryLayout renderer.SummaryLayout
+
+	// SummaryTailLines is the number of retained output lines the summary
+	// shows for each failed process. If zero,
+	// renderer.DefaultSummaryTailLines is used; if negative, none are shown.
+	SummaryTailLines int
+
 	// ShowTimestamps prefixes each output line with an RFC3339 timestamp.
 	// Only applies to incremental (non-TTY) rendering mode.
 	//
@@ -171,6 +199,145 @@ type Config struct {
 	//
 	// Timestamps are in UTC for consistency across time zones.
 	ShowTimestamps bool
+
+	// Color controls ANSI colors in the built-in incremental renderer:
+	// each process prefix gets a stable color (ProcessSpec.Color, or one
+	// from a palette).
+	//
+	// Values:
+	//   - renderer.ColorAuto (default): colors on a TTY, honoring NO_COLOR
+	//     and FORCE_COLOR
+	//   - renderer.ColorAlways: always emit colors (e.g., for `less -R`)
+	//   - renderer.ColorNever: never emit colors
+	Color renderer.ColorMode
+
+	// Output arranges line-oriented output (when the full-screen view is
+	// not used):
+	//   - renderer.OutputInterleaved (default): lines are printed as they
+	//     arrive, prefixed with the process name
+	//   - renderer.OutputGrouped: the output of each process is buffered and
+	//     printed as one block when it exits, which keeps CI logs readable
+	//
+	// OutputGrouped also replaces the full-screen view on a TTY.
+	Output renderer.OutputMode
+
+	// FailedLast prints the blocks of failed processes after all others.
+	// Only applies to renderer.OutputGrouped.
+	FailedLast bool
+
+	// CI selects native log folding for a CI system:
+	//   - renderer.CINone (default): no CI markup
+	//   - renderer.CIAuto: detect GitHub Actions or GitLab CI from the
+	//     environment (GITHUB_ACTIONS, GITLAB_CI); cmd/multiproc uses it
+	//     unless -ci is given
+	//   - renderer.CIGitHub: ::group:: folding and ::error:: annotations,
+	//     plus a Markdown results table in $GITHUB_STEP_SUMMARY if set
+	//   - renderer.CIGitLab: collapsible sections
+	//
+	// Folding needs one block per process, so a CI platform implies
+	// renderer.OutputGrouped.
+	CI renderer.CIPlatform
+
+	// WrapLines wraps long output lines in full-screen mode instead of
+	// truncating them at the terminal width.
+	WrapLines bool
+
+	// Interactive enables keyboard input in full-screen mode: select,
+	// expand, scroll, search, restart and stop processes (press ? for help).
+	// Stdin must be a terminal; otherwise the view stays display-only.
+	Interactive bool
+
+	// TickInterval is how often every renderer is ticked, in addition to
+	// the tick after each batch of events. The periodic tick is what keeps
+	// the spinners and elapsed times of the full-screen view moving while
+	// processes are silent.
+	//
+	// If zero, uses a default of 100ms. If negative, renderers are only
+	// ticked after events.
+	TickInterval time.Duration
+
+	// MaxFrameRate caps how many times per second each renderer is ticked,
+	// i.e., how often the full-screen view is redrawn. Events arriving
+	// between frames are applied to the states right away and drawn
+	// together on the next frame; the final frame is always drawn.
+	//
+	// Without a cap, heavy output redraws the screen after nearly every
+	// batch of lines, which burns CPU in the terminal emulator.
+	//
+	// If zero, uses a default of 30 frames per second. If negative, ticks
+	// are not rate-limited.
+	MaxFrameRate int
+
+	// Renderers receive every event of the run. Each renderer runs on its own
+	// goroutine with its own unbounded event queue and its own copy of the
+	// process states, so a slow renderer (e.g., writing to a network file
+	// system) never stalls the interactive view.
+	//
+	// The queues trade memory for completeness: no event is ever dropped, so
+	// a renderer that falls behind holds every event it has not rendered
+	// yet, and a renderer that stops returning grows its queue for the rest
+	// of the run. Renderers must keep up with the output on average; for
+	// processes that write faster than a renderer's destination accepts,
+	// buffer the writer (as below) or reduce the output at the source.
+	//
+	// When empty, Run selects a single built-in renderer from Output, IsTTY
+	// and FullScreen:
+	//   - OutputGrouped: renderer.GroupedRenderer on stdout
+	//   - TTY + FullScreen: renderer.ScreenRenderer on stdout
+	//   - Otherwise: renderer.IncrementalRenderer on stdout
+	//
+	// ShowSummary, the Summary* fields, ShowTimestamps and LogPrefix only
+	// configure the built-in renderers; custom renderers carry their own
+	// options.
+	//
+	// Example (full-screen view plus a JSON log file):
+	//   cfg.Renderers = []renderer.Renderer{
+	//       renderer.NewScreenRenderer(os.Stdout),
+	//       renderer.NewJSONRenderer(bufio.NewWriter(logFile)),
+	//   }
+	Renderers []renderer.Renderer
+
+	// JUnitFile is the path of a JUnit XML report written at the end of the
+	// run (renderer.JUnitRenderer): one testcase per process, with its
+	// duration, exit status and output tail; cancelled processes are
+	// skipped. The file is created before any process starts. If empty, no
+	// report is written.
+	//
+	// The report is added to Renderers, or to the built-in renderer.
+	JUnitFile string
+
+	// HTMLFile is the path of a self-contained HTML report written at the
+	// end of the run (renderer.HTMLRenderer): a table of the processes and
+	// their full, searchable logs with ANSI colors converted. The file is
+	// created before any process starts. If empty, no report is written.
+	//
+	// The report is added to Renderers, or to the built-in renderer.
+	HTMLFile string
+
+	// Reload receives new specs during the run, e.g., from a reloaded
+	// configuration file. They are compared with the running ones by name:
+	// new processes are started, processes whose spec changed are restarted
+	// with it, and processes no longer listed are stopped and removed.
+	// Unchanged processes keep running, with their output history.
+	//
+	// The new specs are validated first (see Config.Validate); if they are
+	// invalid, nothing changes and the problems are printed to stderr.
+	// Only specs are reloaded; the other fields keep their values. Reloads
+	// sent before the run has started are applied once it has, and reloads
+	// received after every process has exited are ignored. If nil, specs
+	// never change.
+	//
+	// Example:
+	//   reload := make(chan []engine.ProcessSpec)
+	//   cfg.Reload = reload
+	//   go func() { reload <- newSpecs }()
+	Reload <-chan []engine.ProcessSpec
+
+	// CommandFactory creates the commands for Specs.
+	// If nil, engine.DefaultCommandFactory is used.
+	//
+	// Override this to run processes remotely or to inject mocks in tests.
+	CommandFactory engine.CommandFactory
 }
 
 // DefaultConfig returns sensible defaults for Config.
@@ -184,6 +351,12 @@ type Config struct {
 //   - ShowSummary: true
 //   - ShowTimestamps: false
 //   - LogPrefix: "[%s]"
+//   - Color: renderer.ColorAuto
+//   - Output: renderer.OutputInterleaved
+//   - CI: renderer.CINone
+//   - Interactive: true
+//   - TickInterval: 100ms
+//   - MaxFrameRate: 30
 //
 // Example:
 //
@@ -201,24 +374,55 @@ func DefaultConfig() Config {
 		ShutdownTimeout: defaultShutdownTimeout,
 		ShowTimestamps:  false,
 		LogPrefix:       "[%s]",
+		Color:           renderer.ColorAuto,
+		Output:          renderer.OutputInterleaved,
+		CI:              renderer.CINone,
+		Interactive:     true,
+		TickInterval:    defaultTickInterval,
+		MaxFrameRate:    defaultMaxFrameRate,
 	}
 }
 
+// Validate checks cfg before anything is started: the specs (see
+// engine.Engine.Validate, with cfg.CommandFactory) and LogPrefix, which must
+// contain one "%s" for the process name and no other verb. Every problem
+// found is returned, joined with errors.Join; nil means cfg is valid.
+//
+// Run calls Validate and starts nothing if it fails.
+//
+// Example:
+//
+//	if err := cfg.Validate(); err != nil {
+//	    fmt.Fprintln(os.Stderr, err) // one line per problem
+//	    os.Exit(2)
+//	}
+func (c Config) Validate() error {
+	errs := []error{engine.New(c.Specs, c.ShutdownTimeout).WithCommandFactory(c.CommandFactory).Validate()}
+	if c.LogPrefix != "" {
+		// A bad verb, or a missing or extra one, shows as "%!".
+		if out := fmt.Sprintf(c.LogPrefix, "name"); strings.Contains(out, "%!") {
+			errs = append(errs, fmt.Errorf("invalid log prefix %q: want one %%s for the process name", c.LogPrefix))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // Run executes the configured processes and manages rendering.
 // This is the main entry point for the runner package.
 //
 // Orchestration:
-//  1. Apply configuration defaults
+//  1. Apply configuration defaults and validate them (see Config.Validate)
 //  2. Initialize process states
 //  3. Create and start engine
-//  4. Set up appropriate renderer (TTY or non-TTY)
-//  5. Process events and update state
-//  6. Render updates in real-time
-//  7. Print summary (if enabled)
+//  4. Set up renderers (Config.Renderers, or a built-in one)
+//  5. Process events and update state, applying reloaded specs
+//     (Config.Reload)
+//  6. Fan out events to every renderer through its own sink
+//  7. Call Finish, then FinalState (summary) on every renderer
 //  8. Return aggregate exit code
 //
-// Rendering modes:
-//   - TTY + FullScreen: Full-screen with debouncing
+// Rendering modes (when Config.Renderers is empty):
+//   - TTY + FullScreen: Full-screen, redrawn once per drained burst of events
 //   - TTY + !FullScreen: Incremental line-by-line
 //   - Non-TTY: Always incremental
 //
@@ -229,7 +433,8 @@ func DefaultConfig() Config {
 //
 // Exit codes:
 //   - 0: All processes succeeded
-//   - 1: One or more processes failed
+//   - 1: One or more processes failed, a report file could not be created,
+//     or a renderer or report file could not write its output
 //
 // Parameters:
 //   - ctx: Context for cancellation (typically from signal handling)
@@ -275,22 +480,20 @@ func DefaultConfig() Config {
 //nolint:gocognit,funlen // High-level orchestration requires conditional logic and length
 func Run(ctx context.Context, cfg Config) int {
 	// Derive effective configuration, falling back to defaults.
-	base := DefaultConfig()
-	if cfg.MaxLinesPerProc <= 0 {
-		cfg.MaxLinesPerProc = base.MaxLinesPerProc
-	}
-	if cfg.Specs == nil {
-		cfg.Specs = base.Specs
+	cfg = withDefaults(cfg)
+	if err := cfg.Validate(); err != nil {
+		for _, line := range strings.Split(err.Error(), "\n") {
+			fmt.Fprintf(os.Stderr, "multiproc: %s\n", line)
+		}
+		return 1
 	}
 	if cfg.IsTTY == nil {
 		val := renderer.IsTTY()
 		cfg.IsTTY = &val
 	}
-	if cfg.ShutdownTimeout <= 0 {
-		cfg.ShutdownTimeout = base.ShutdownTimeout
-	}
-	if cfg.LogPrefix == "" {
-		cfg.LogPrefix = base.LogPrefix
+	var frame time.Duration
+	if cfg.MaxFrameRate > 0 {
+		frame = time.Second / time.Duration(cfg.MaxFrameRate)
 	}
 
 	// In non-TTY environments, full-screen rendering is not useful, so
@@ -299,9 +502,18 @@ func Run(ctx context.Context, cfg Config) int {
 		cfg.FullScreen = false
 	}
 
+	// Create report files before starting anything, so that a bad path
+	// fails fast.
+	reporters, reports, reportErr := openReports(cfg)
+	if reportErr != nil {
+		fmt.Fprintf(os.Stderr, "multiproc: %v\n", reportErr)
+		return 1
+	}
+
 	specs := cfg.Specs
 
 	// Build initial render state.
+	startedAt := time.Now()
 	states := make([]renderer.ProcessState, len(specs))
 	for i, spec := range specs {
 		// Determine effective limits for this process.
@@ -313,22 +525,29 @@ func Run(ctx context.Context, cfg Config) int {
 		// Note: if maxBytes is 0, no byte limit is enforced.
 
 		states[i] = renderer.ProcessState{
-			Name:     spec.Name,
-			Lines:    nil,
-			Done:     false,
-			Err:      nil,
-			Running:  true,
-			Dirty:    true, // initial state should be rendered
-			ByteSize: 0,
-			MaxLines: maxLines,
-			MaxBytes: maxBytes,
+			Name:      spec.Name,
+			Lines:     nil,
+			Done:      false,
+			Err:       nil,
+			Running:   true,
+			Dirty:     true, // initial state should be rendered
+			ByteSize:  0,
+			MaxLines:  maxLines,
+			MaxBytes:  maxBytes,
+			StartedAt: startedAt,
+
+			RunHistory: spec.RunHistory,
 		}
 	}
 
 	events := make(chan renderer.Event, eventChannelBuffer)
 
+	// Interactive renderers may cancel the run on their own.
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
 	// Use the Engine to run processes.
-	eng := engine.New(specs, cfg.ShutdownTimeout)
+	eng := engine.New(specs, cfg.ShutdownTimeout).WithCommandFactory(cfg.CommandFactory)
 
 	// Convert ProcessLine events from engine to Event for rendering.
 	processLines := make(chan engine.ProcessLine, eventChannelBuffer)
@@ -340,67 +559,177 @@ func Run(ctx context.Context, cfg Config) int {
 	// Convert engine events to renderer events.
 	go func() {
 		for pl := range processLines {
-			events <- renderer.ConvertProcessLineToEvent(pl)
+			ev := renderer.ConvertProcessLineToEvent(pl)
+			if s, ok := ev.(renderer.SpecEvent); ok && s.Spec.MaxLines <= 0 {
+				// Reloaded specs get the same default limit as the others.
+				s.Spec.MaxLines = cfg.MaxLinesPerProc
+				ev = s
+			}
+			events <- ev
 		}
 		close(events)
 	}()
 
-	var renderCh chan renderer.RenderRequest
-	if cfg.FullScreen && cfg.IsTTY != nil && *cfg.IsTTY {
-		renderCh = make(chan renderer.RenderRequest, 1)
-		// Dedicated render loop with debouncing.
-		go func() {
-			for range renderCh {
-				renderer.RenderScreen(states)
-			}
-		}()
-
-		// Queue initial render to show "starting" status for all processes.
-		renderCh <- renderer.RenderRequest{}
-	} else if cfg.IsTTY != nil && !*cfg.IsTTY {
-		// In non-TTY mode, print initial status for all processes
-		for i, spec := range specs {
-			name := spec.Name
-			if name == "" {
-				name = fmt.Sprintf("proc-%d", i)
-			}
-			prefix := fmt.Sprintf(cfg.LogPrefix, name)
-			if cfg.ShowTimestamps {
-				timestamp := time.Now().UTC().Format(time.RFC3339)
-				fmt.Printf("[%s] %s starting...\n", timestamp, prefix)
-			} else {
-				fmt.Printf("%s starting...\n", prefix)
-			}
+	if cfg.Reload != nil {
+		go newReloader(eng, cfg).run(ctx, cfg.Reload, os.Stderr)
+	}
+
+	renderers := cfg.Renderers
+	if len(renderers) == 0 {
+		renderers = []renderer.Renderer{DefaultRenderer(cfg)}
+	}
+	renderers = append(slices.Clip(renderers), reporters...)
+
+	// A panic must not leave the terminal on the alternate screen.
+	restore := func() { restoreTerminal(renderers) }
+	defer restoreOnPanic(restore)
+
+	// Every renderer gets its own sink (goroutine, queue and state copy),
+	// so a slow renderer never stalls the others or the event loop.
+	sinks := make([]*sink, len(renderers))
+	for i, r := range renderers {
+		sinks[i] = newSink(r, specs, states, restore, cfg.TickInterval, frame)
+		if ir, ok := r.(renderer.Interactive); ok {
+			ir.SetController(&controller{eng: eng, cancel: cancel, sink: sinks[i]})
 		}
+		go sinks[i].run()
 	}
 
-	// Main event loop: update state and re-render in real time.
+	// Main event loop: update the runner's own state and fan out to sinks.
 	for ev := range events {
+		states = renderer.GrowStates(states, ev)
 		renderer.ApplyEvent(states, ev)
-		if cfg.IsTTY != nil && *cfg.IsTTY && cfg.FullScreen {
-			// Non-blocking send to debounce renders.
-			select {
-			case renderCh <- renderer.RenderRequest{}:
-			default:
-			}
-		} else {
-			// Non-TTY incremental renderer.
-			renderer.RenderIncremental(ev, specs, states, cfg.ShowTimestamps, cfg.LogPrefix)
+		for _, s := range sinks {
+			s.push(ev)
+		}
+	}
+
+	// Let every renderer draw its final frame and release the terminal
+	// before anyone writes end-of-run output.
+	for _, s := range sinks {
+		s.close()
+	}
+	for _, s := range sinks {
+		s.wait()
+	}
+	outputFailed := false
+	for _, s := range sinks {
+		if err := s.r.FinalState(s.states); err != nil {
+			fmt.Fprintf(os.Stderr, "multiproc: renderer: %v\n", err)
+			outputFailed = true
+		}
+	}
+	if err := closeReports(reports); err != nil {
+		fmt.Fprintf(os.Stderr, "multiproc: %v\n", err)
+		outputFailed = true
+	}
+
+	// Return exit code for caller to handle. A report or log that could
+	// not be written fails the run even if every process succeeded.
+	code := renderer.ExitCodeFromStates(states)
+	if outputFailed && code == 0 {
+		code = 1
+	}
+	return code
+}
+
+// withDefaults returns cfg with its unset limits, timeouts and intervals
+// set to the DefaultConfig values, as Run uses them.
+func withDefaults(cfg Config) Config {
+	base := DefaultConfig()
+	if cfg.MaxLinesPerProc <= 0 {
+		cfg.MaxLinesPerProc = base.MaxLinesPerProc
+	}
+	if cfg.Specs == nil {
+		cfg.Specs = base.Specs
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		cfg.ShutdownTimeout = base.ShutdownTimeout
+	}
+	if cfg.LogPrefix == "" {
+		cfg.LogPrefix = base.LogPrefix
+	}
+	if cfg.TickInterval == 0 {
+		cfg.TickInterval = base.TickInterval
+	}
+	if cfg.MaxFrameRate == 0 {
+		cfg.MaxFrameRate = base.MaxFrameRate
+	}
+	return cfg
+}
+
+// restoreTerminal calls Restore on every renderer implementing renderer.Restorer.
+func restoreTerminal(renderers []renderer.Renderer) {
+	for _, r := range renderers {
+		if rr, ok := r.(renderer.Restorer); ok {
+			rr.Restore()
 		}
 	}
+}
 
-	// Final render (in case we exited without drawing the last frame).
-	if renderCh != nil {
-		// Ensure the last state is rendered, then close the loop.
-		renderCh <- renderer.RenderRequest{}
-		close(renderCh)
+// restoreOnPanic must be deferred. If the calling goroutine is panicking,
+// it calls restore and then re-panics with the original value.
+func restoreOnPanic(restore func()) {
+	if p := recover(); p != nil {
+		restore()
+		panic(p)
 	}
+}
 
-	// Print a short summary to stderr.
+// DefaultRenderer returns the built-in renderer Run uses when
+// Config.Renderers is empty: a GroupedRenderer for renderer.OutputGrouped
+// or when Config.CI selects a CI platform, a ScreenRenderer for TTY + FullScreen,
+// otherwise an IncrementalRenderer, all on stdout with the summary on
+// stderr when ShowSummary is set.
+//
+// Use it to keep the default terminal output while adding more renderers:
+//
+//	cfg.Renderers = []renderer.Renderer{runner.DefaultRenderer(cfg), myRenderer}
+//
+// If cfg.IsTTY is nil, the TTY is auto-detected.
+func DefaultRenderer(cfg Config) renderer.Renderer {
+	if cfg.IsTTY == nil {
+		val := renderer.IsTTY()
+		cfg.IsTTY = &val
+	}
+
+	var summaryOut io.Writer
 	if cfg.ShowSummary {
-		renderer.WriteFinalSummary(states)
+		summaryOut = os.Stderr
+	}
+	summary := renderer.SummaryOptions{Sort: cfg.SummarySort, Layout: cfg.SummaryLayout, TailLines: cfg.SummaryTailLines}
+
+	ci := renderer.ResolveCIPlatform(cfg.CI)
+	if cfg.Output == renderer.OutputGrouped || ci != renderer.CINone {
+		r := renderer.NewGroupedRenderer(os.Stdout)
+		r.SummaryOut = summaryOut
+		r.Summary = summary
+		r.ShowTimestamps = cfg.ShowTimestamps
+		r.Color = renderer.ColorEnabled(cfg.Color, *cfg.IsTTY)
+		r.FailedLast = cfg.FailedLast
+		r.CI = ci
+		if ci == renderer.CIGitHub {
+			r.StepSummary = os.Getenv("GITHUB_STEP_SUMMARY")
+		}
+		return r
+	}
+
+	if cfg.FullScreen && cfg.IsTTY != nil && *cfg.IsTTY {
+		r := renderer.NewScreenRenderer(os.Stdout)
+		r.SummaryOut = summaryOut
+		r.Summary = summary
+		r.Wrap = cfg.WrapLines
+		if cfg.Interactive {
+			r.Input = os.Stdin
+		}
+		return r
 	}
 
-	// Return exit code for caller to handle.
-	return renderer.ExitCodeFromStates(states)
+	r := renderer.NewIncrementalRenderer(os.Stdout, cfg.ShowTimestamps, cfg.LogPrefix)
+	r.SummaryOut = summaryOut
+	r.Summary = summary
+	r.Color = renderer.ColorEnabled(cfg.Color, *cfg.IsTTY)
+	r.Align = true
+	r.AnnounceStart = !*cfg.IsTTY
+	return r
 }
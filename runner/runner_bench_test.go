@@ -0,0 +1,83 @@
+package runner_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/a2y-d5l/multiproc/engine"
+	"github.com/a2y-d5l/multiproc/renderer"
+	"github.com/a2y-d5l/multiproc/runner"
+)
+
+// timedRenderer measures the time a renderer spends drawing frames.
+type timedRenderer struct {
+	renderer.Renderer
+	spent  time.Duration
+	frames int
+}
+
+func (r *timedRenderer) Tick(states []renderer.ProcessState) {
+	start := time.Now()
+	r.Renderer.Tick(states)
+	r.spent += time.Since(start)
+	r.frames++
+}
+
+func (r *timedRenderer) Finish(states []renderer.ProcessState) {
+	start := time.Now()
+	r.Renderer.Finish(states)
+	r.spent += time.Since(start)
+	r.frames++
+}
+
+// BenchmarkRenderFlood measures the rendering cost of the full-screen view
+// under a flood of output, with and without a frame rate cap. render-ms/op
+// is the time spent drawing frames, which is mostly CPU.
+func BenchmarkRenderFlood(b *testing.B) {
+	const (
+		numProcs     = 4
+		linesPerProc = 5000
+	)
+	lines := make([]string, linesPerProc)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("flood output line %d with some padding to make it realistic", i)
+	}
+	factory := func(_ context.Context, spec engine.ProcessSpec) (engine.Command, error) {
+		return NewMockCommand(spec).WithStdout(lines...), nil
+	}
+	specs := make([]engine.ProcessSpec, numProcs)
+	for i := range specs {
+		specs[i] = engine.ProcessSpec{Name: fmt.Sprintf("proc-%d", i), Command: "mock"}
+	}
+
+	for _, fps := range []int{-1, 30} {
+		name := fmt.Sprintf("%dfps", fps)
+		if fps < 0 {
+			name = "uncapped"
+		}
+		b.Run(name, func(b *testing.B) {
+			var spent time.Duration
+			var frames int
+			for b.Loop() {
+				screen := renderer.NewScreenRenderer(io.Discard)
+				screen.Width, screen.Height = 120, 40
+				r := &timedRenderer{Renderer: screen}
+
+				cfg := runner.DefaultConfig()
+				cfg.Specs = specs
+				cfg.CommandFactory = factory
+				cfg.MaxFrameRate = fps
+				cfg.Renderers = []renderer.Renderer{r}
+				runner.Run(context.Background(), cfg)
+
+				spent += r.spent
+				frames += r.frames
+			}
+			b.ReportMetric(spent.Seconds()*1000/float64(b.N), "render-ms/op")
+			b.ReportMetric(float64(frames)/float64(b.N), "frames/op")
+		})
+	}
+}
@@ -1,13 +1,21 @@
 package runner_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"sync"
 	"syscall"
 	"testing"
 	"time"
 
 	"github.com/a2y-d5l/multiproc/engine"
+	"github.com/a2y-d5l/multiproc/renderer"
 	"github.com/a2y-d5l/multiproc/runner"
 )
 
@@ -16,6 +24,7 @@ type MockCommand struct {
 	exitErr     error
 	stdoutLines []string
 	stderrLines []string
+	lineDelay   time.Duration
 	mu          sync.Mutex
 	started     bool
 	waited      bool
@@ -33,8 +42,14 @@ func (m *MockCommand) WithStdout(lines ...string) *MockCommand {
 	return m
 }
 
+// WithLineDelay makes the command write one stdout line every d.
+func (m *MockCommand) WithLineDelay(d time.Duration) *MockCommand {
+	m.lineDelay = d
+	return m
+}
+
 func (m *MockCommand) StdoutPipe() (io.ReadCloser, error) {
-	return &mockReadCloser{lines: m.stdoutLines}, nil
+	return &mockReadCloser{lines: m.stdoutLines, delay: m.lineDelay}, nil
 }
 
 func (m *MockCommand) StderrPipe() (io.ReadCloser, error) {
@@ -75,6 +90,7 @@ type mockReadCloser struct {
 	lines []string
 	buf   []byte
 	pos   int
+	delay time.Duration
 }
 
 func (m *mockReadCloser) Read(p []byte) (int, error) {
@@ -88,6 +104,9 @@ func (m *mockReadCloser) Read(p []byte) (int, error) {
 		return n, nil
 	}
 
+	if m.delay > 0 {
+		time.Sleep(m.delay)
+	}
 	line := m.lines[m.pos] + "\n"
 	m.pos++
 
@@ -123,6 +142,14 @@ func TestDefaultConfig(t *testing.T) {
 		t.Errorf("Expected ShutdownTimeout=5s, got %v", cfg.ShutdownTimeout)
 	}
 
+	if cfg.TickInterval != 100*time.Millisecond {
+		t.Errorf("Expected TickInterval=100ms, got %v", cfg.TickInterval)
+	}
+
+	if cfg.MaxFrameRate != 30 {
+		t.Errorf("Expected MaxFrameRate=30, got %d", cfg.MaxFrameRate)
+	}
+
 	if cfg.ShowTimestamps != false {
 		t.Error("Expected ShowTimestamps=false by default")
 	}
@@ -221,4 +248,677 @@ func TestNonTTYMode(t *testing.T) {
 	if cfg.IsTTY != nil && !*cfg.IsTTY {
 		t.Log("Non-TTY mode detected, full-screen should be disabled by Run()")
 	}
+
+	// Only non-TTY output announces every process at the start.
+	r, ok := runner.DefaultRenderer(cfg).(*renderer.IncrementalRenderer)
+	if !ok || !r.AnnounceStart {
+		t.Errorf("Expected an announcing IncrementalRenderer for non-TTY output, got %#v", runner.DefaultRenderer(cfg))
+	}
+	cfg.IsTTY = &isTTYTrue
+	cfg.FullScreen = false
+	if r, ok = runner.DefaultRenderer(cfg).(*renderer.IncrementalRenderer); !ok || r.AnnounceStart {
+		t.Errorf("Expected a quiet IncrementalRenderer for a TTY, got %#v", runner.DefaultRenderer(cfg))
+	}
+}
+
+// recordingRenderer records the hooks called by runner.Run.
+type recordingRenderer struct {
+	renderer.NopRenderer
+	calls []string
+	lines []string
+}
+
+func (r *recordingRenderer) Start(specs []engine.ProcessSpec, _ []renderer.ProcessState) {
+	r.calls = append(r.calls, fmt.Sprintf("start:%d", len(specs)))
+}
+
+func (r *recordingRenderer) Event(ev renderer.Event, _ []renderer.ProcessState) {
+	if le, ok := ev.(renderer.LineEvent); ok {
+		r.lines = append(r.lines, le.Line)
+	}
+}
+
+func (r *recordingRenderer) Finish([]renderer.ProcessState) {
+	r.calls = append(r.calls, "finish")
+}
+
+func (r *recordingRenderer) FinalState(states []renderer.ProcessState) error {
+	done := 0
+	for _, ps := range states {
+		if ps.Done {
+			done++
+		}
+	}
+	r.calls = append(r.calls, fmt.Sprintf("final:%d", done))
+	return nil
+}
+
+// TestRunWithCustomRenderers verifies that every configured renderer receives all hooks.
+func TestRunWithCustomRenderers(t *testing.T) {
+	cfg := runner.DefaultConfig()
+	cfg.Specs = []engine.ProcessSpec{
+		{Name: "a", Command: "mock"},
+		{Name: "b", Command: "mock"},
+	}
+	cfg.CommandFactory = func(_ context.Context, spec engine.ProcessSpec) (engine.Command, error) {
+		return NewMockCommand(spec).WithStdout(spec.Name + "-1"), nil
+	}
+
+	first, second := &recordingRenderer{}, &recordingRenderer{}
+	cfg.Renderers = []renderer.Renderer{first, second}
+
+	if code := runner.Run(context.Background(), cfg); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d", code)
+	}
+
+	for _, r := range []*recordingRenderer{first, second} {
+		want := []string{"start:2", "finish", "final:2"}
+		if strings.Join(r.calls, ",") != strings.Join(want, ",") {
+			t.Errorf("Expected calls %v, got %v", want, r.calls)
+		}
+		if len(r.lines) != 2 {
+			t.Errorf("Expected 2 lines, got %v", r.lines)
+		}
+	}
+}
+
+// blockingRenderer blocks on its first event until gate is closed.
+type blockingRenderer struct {
+	renderer.NopRenderer
+	gate    chan struct{}
+	blocked bool
+}
+
+func (r *blockingRenderer) Event(renderer.Event, []renderer.ProcessState) {
+	if !r.blocked {
+		r.blocked = true
+		<-r.gate
+	}
+}
+
+// gateOpener closes gate once it has seen every event.
+type gateOpener struct {
+	renderer.NopRenderer
+	gate  chan struct{}
+	lines int
+}
+
+func (r *gateOpener) Event(ev renderer.Event, _ []renderer.ProcessState) {
+	if _, ok := ev.(renderer.LineEvent); ok {
+		r.lines++
+	}
+}
+
+func (r *gateOpener) Finish([]renderer.ProcessState) {
+	close(r.gate)
+}
+
+// TestRunSlowRendererDoesNotStallOthers verifies that renderers are fed independently.
+func TestRunSlowRendererDoesNotStallOthers(t *testing.T) {
+	cfg := runner.DefaultConfig()
+	cfg.Specs = []engine.ProcessSpec{{Name: "chatty", Command: "mock"}}
+
+	lines := make([]string, 1000)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i)
+	}
+	cfg.CommandFactory = func(_ context.Context, spec engine.ProcessSpec) (engine.Command, error) {
+		return NewMockCommand(spec).WithStdout(lines...), nil
+	}
+
+	// The slow renderer only proceeds once the fast one has seen everything,
+	// which deadlocks if the fast one is fed through the slow one.
+	gate := make(chan struct{})
+	fast := &gateOpener{gate: gate}
+	cfg.Renderers = []renderer.Renderer{&blockingRenderer{gate: gate}, fast}
+
+	done := make(chan int)
+	go func() { done <- runner.Run(context.Background(), cfg) }()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run stalled behind a slow renderer")
+	}
+
+	if fast.lines != len(lines) {
+		t.Errorf("Expected fast renderer to see %d lines, got %d", len(lines), fast.lines)
+	}
+}
+
+// restartingRenderer restarts process 0 after its first line, then
+// cancels the run after the first line of the new instance.
+type restartingRenderer struct {
+	renderer.NopRenderer
+	ctl      renderer.Controller
+	restarts int
+	lines    int
+}
+
+func (r *restartingRenderer) SetController(c renderer.Controller) {
+	r.ctl = c
+}
+
+func (r *restartingRenderer) Event(ev renderer.Event, _ []renderer.ProcessState) {
+	switch e := ev.(type) {
+	case renderer.LineEvent:
+		if e.Stream != engine.StreamStdout {
+			return
+		}
+		r.lines++
+		if r.lines == 1 {
+			_ = r.ctl.Restart(0)
+		} else {
+			r.ctl.Cancel()
+		}
+	case renderer.RestartEvent:
+		r.restarts++
+	}
+}
+
+func (r *restartingRenderer) FinalState(states []renderer.ProcessState) error {
+	if states[0].Restarts != r.restarts {
+		return fmt.Errorf("states report %d restarts, saw %d", states[0].Restarts, r.restarts)
+	}
+	return nil
+}
+
+// TestRunInteractiveController verifies interactive renderers can restart
+// processes and cancel the run.
+func TestRunInteractiveController(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping real process test in short mode")
+	}
+
+	cfg := runner.DefaultConfig()
+	cfg.Specs = []engine.ProcessSpec{
+		{Name: "server", Command: "sh", Args: []string{"-c", "echo ready; exec sleep 10"}},
+	}
+	cfg.ShutdownTimeout = time.Second
+	r := &restartingRenderer{}
+	cfg.Renderers = []renderer.Renderer{r}
+
+	done := make(chan int)
+	go func() { done <- runner.Run(context.Background(), cfg) }()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run was not cancelled by the renderer")
+	}
+	if r.restarts != 1 || r.lines != 2 {
+		t.Errorf("Expected 1 restart and 2 lines, got %d restarts and %d lines", r.restarts, r.lines)
+	}
+}
+
+// reloadingRenderer reloads the specs once both processes have written
+// their first line, then cancels the run once the added process exits.
+type reloadingRenderer struct {
+	renderer.NopRenderer
+	ctl    renderer.Controller
+	reload chan<- []engine.ProcessSpec
+	next   []engine.ProcessSpec
+	seen   map[string]bool
+	final  []renderer.ProcessState
+}
+
+func (r *reloadingRenderer) SetController(c renderer.Controller) {
+	r.ctl = c
+}
+
+func (r *reloadingRenderer) Event(ev renderer.Event, states []renderer.ProcessState) {
+	switch e := ev.(type) {
+	case renderer.LineEvent:
+		r.seen[e.Line] = true
+		if e.Line == "keep" || e.Line == "old" {
+			if r.seen["keep"] && r.seen["old"] && r.next != nil {
+				r.reload <- r.next
+				r.next = nil
+			}
+		}
+	case renderer.DoneEvent:
+		if states[e.Index].Name == "new" {
+			r.ctl.Cancel()
+		}
+	}
+}
+
+func (r *reloadingRenderer) FinalState(states []renderer.ProcessState) error {
+	r.final = states
+	return nil
+}
+
+// TestRunReload verifies reloaded specs add and remove processes, and
+// leave unchanged ones running.
+func TestRunReload(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping real process test in short mode")
+	}
+
+	sleeper := func(name string) engine.ProcessSpec {
+		return engine.ProcessSpec{Name: name, Command: "sh", Args: []string{"-c", "echo " + name + "; exec sleep 10"}}
+	}
+	cfg := runner.DefaultConfig()
+	cfg.Specs = []engine.ProcessSpec{sleeper("keep"), sleeper("old")}
+	cfg.ShutdownTimeout = time.Second
+	reload := make(chan []engine.ProcessSpec, 1)
+	cfg.Reload = reload
+	r := &reloadingRenderer{
+		reload: reload,
+		next:   []engine.ProcessSpec{sleeper("keep"), {Name: "new", Command: "echo", Args: []string{"new"}}},
+		seen:   map[string]bool{},
+	}
+	cfg.Renderers = []renderer.Renderer{r}
+
+	done := make(chan int)
+	go func() { done <- runner.Run(context.Background(), cfg) }()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not end after the reload")
+	}
+
+	if len(r.final) != 3 {
+		t.Fatalf("Expected 3 process states, got %d", len(r.final))
+	}
+	// The shutdown adds status lines, but the process must not have run twice.
+	if keep := r.final[0]; keep.Removed || keep.Restarts != 0 || strings.Count(strings.Join(keep.Lines, "\n"), "keep") != 1 {
+		t.Errorf("Expected the unchanged process to keep running, got %+v", keep)
+	}
+	if old := r.final[1]; !old.Removed || !old.Done {
+		t.Errorf("Expected the old process to be removed and stopped, got %+v", old)
+	}
+	if added := r.final[2]; added.Name != "new" || !added.Done || added.Err != nil || added.MaxLines != cfg.MaxLinesPerProc {
+		t.Errorf("Expected the new process to run with the default limit, got %+v", added)
+	}
+}
+
+// tickCounter cancels the run after a few ticks.
+type tickCounter struct {
+	renderer.NopRenderer
+	ctl   renderer.Controller
+	ticks int
+}
+
+func (r *tickCounter) SetController(c renderer.Controller) {
+	r.ctl = c
+}
+
+func (r *tickCounter) Tick([]renderer.ProcessState) {
+	r.ticks++
+	if r.ticks == 3 {
+		r.ctl.Cancel()
+	}
+}
+
+// TestRunTicksPeriodically verifies renderers are ticked while processes are silent.
+func TestRunTicksPeriodically(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping real process test in short mode")
+	}
+
+	cfg := runner.DefaultConfig()
+	cfg.Specs = []engine.ProcessSpec{
+		{Name: "silent", Command: "sh", Args: []string{"-c", "exec sleep 10"}},
+	}
+	cfg.ShutdownTimeout = time.Second
+	cfg.TickInterval = 10 * time.Millisecond
+	r := &tickCounter{}
+	cfg.Renderers = []renderer.Renderer{r}
+
+	done := make(chan int)
+	go func() { done <- runner.Run(context.Background(), cfg) }()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run was not cancelled: the renderer was not ticked")
+	}
+}
+
+// frameCounter counts ticks and records the time span of the run.
+type frameCounter struct {
+	renderer.NopRenderer
+	start, end time.Time
+	ticks      int
+	lines      int
+}
+
+func (r *frameCounter) Start([]engine.ProcessSpec, []renderer.ProcessState) {
+	r.start = time.Now()
+}
+
+func (r *frameCounter) Tick([]renderer.ProcessState) {
+	r.ticks++
+}
+
+func (r *frameCounter) Finish(states []renderer.ProcessState) {
+	r.end = time.Now()
+	for _, ps := range states {
+		r.lines += ps.LineCount
+	}
+}
+
+// TestRunCapsFrameRate verifies ticks are rate-limited under a flood of
+// output, and that the final state still holds every line.
+func TestRunCapsFrameRate(t *testing.T) {
+	const numLines = 200
+	lines := make([]string, numLines)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i)
+	}
+
+	cfg := runner.DefaultConfig()
+	cfg.Specs = []engine.ProcessSpec{{Name: "flood", Command: "mock"}}
+	cfg.CommandFactory = func(_ context.Context, spec engine.ProcessSpec) (engine.Command, error) {
+		return NewMockCommand(spec).WithStdout(lines...).WithLineDelay(time.Millisecond), nil
+	}
+	cfg.TickInterval = -1
+	cfg.MaxFrameRate = 20
+	r := &frameCounter{}
+	cfg.Renderers = []renderer.Renderer{r}
+
+	if code := runner.Run(context.Background(), cfg); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d", code)
+	}
+	if r.lines != numLines {
+		t.Errorf("Expected the final state to hold %d lines, got %d", numLines, r.lines)
+	}
+	// One tick per 50ms frame, plus the first one.
+	if limit := int(r.end.Sub(r.start)/(50*time.Millisecond)) + 2; r.ticks > limit {
+		t.Errorf("Expected at most %d ticks in %v, got %d", limit, r.end.Sub(r.start), r.ticks)
+	}
+}
+
+// TestDefaultRendererGrouped verifies OutputGrouped selects the grouped renderer.
+func TestDefaultRendererGrouped(t *testing.T) {
+	isTTY := true
+	cfg := runner.DefaultConfig()
+	cfg.IsTTY = &isTTY
+	cfg.Output = renderer.OutputGrouped
+	cfg.FailedLast = true
+	cfg.SummarySort = renderer.SummaryByStatus
+	cfg.SummaryTailLines = 5
+
+	r, ok := runner.DefaultRenderer(cfg).(*renderer.GroupedRenderer)
+	if !ok {
+		t.Fatalf("Expected a GroupedRenderer, got %T", runner.DefaultRenderer(cfg))
+	}
+	if !r.FailedLast {
+		t.Error("Expected FailedLast to be passed to the renderer")
+	}
+	if want := (renderer.SummaryOptions{Sort: renderer.SummaryByStatus, TailLines: 5}); r.Summary != want {
+		t.Errorf("Expected summary options %+v, got %+v", want, r.Summary)
+	}
+}
+
+// TestDefaultRendererCI verifies CI detection is opt-in, and the GitHub
+// step summary.
+func TestDefaultRendererCI(t *testing.T) {
+	t.Setenv("GITHUB_ACTIONS", "true")
+	t.Setenv("GITHUB_STEP_SUMMARY", "/tmp/step-summary.md")
+
+	isTTY := false
+	cfg := runner.DefaultConfig()
+	cfg.IsTTY = &isTTY
+
+	// The library does not detect CI unless asked to.
+	if _, ok := runner.DefaultRenderer(cfg).(*renderer.IncrementalRenderer); !ok {
+		t.Errorf("Expected an IncrementalRenderer by default, got %T", runner.DefaultRenderer(cfg))
+	}
+
+	cfg.CI = renderer.CIAuto
+	r, ok := runner.DefaultRenderer(cfg).(*renderer.GroupedRenderer)
+	if !ok {
+		t.Fatalf("Expected a GroupedRenderer in CI, got %T", runner.DefaultRenderer(cfg))
+	}
+	if r.CI != renderer.CIGitHub || r.StepSummary != "/tmp/step-summary.md" {
+		t.Errorf("Expected GitHub markup and step summary, got %v and %q", r.CI, r.StepSummary)
+	}
+
+	cfg.CI = renderer.CINone
+	if _, ok = runner.DefaultRenderer(cfg).(*renderer.IncrementalRenderer); !ok {
+		t.Errorf("Expected an IncrementalRenderer with CINone, got %T", runner.DefaultRenderer(cfg))
+	}
+}
+
+// TestRunReports verifies report files are written next to the other renderers.
+func TestRunReports(t *testing.T) {
+	cfg := runner.DefaultConfig()
+	cfg.Specs = []engine.ProcessSpec{{Name: "a", Command: "mock"}, {Name: "b", Command: "mock"}}
+	cfg.CommandFactory = func(_ context.Context, spec engine.ProcessSpec) (engine.Command, error) {
+		return NewMockCommand(spec).WithStdout(spec.Name + "-out"), nil
+	}
+	rec := &recordingRenderer{}
+	cfg.Renderers = []renderer.Renderer{rec}
+	cfg.JUnitFile = filepath.Join(t.TempDir(), "report.xml")
+	cfg.HTMLFile = filepath.Join(t.TempDir(), "report.html")
+
+	if code := runner.Run(context.Background(), cfg); code != 0 {
+		t.Fatalf("Expected exit code 0, got %d", code)
+	}
+	if len(rec.lines) != 2 {
+		t.Errorf("Expected the configured renderer to run too, got lines %v", rec.lines)
+	}
+
+	data, err := os.ReadFile(cfg.JUnitFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`tests="2" failures="0"`, `<testcase name="a"`, "a-out", `<testcase name="b"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Expected %q in report:\n%s", want, data)
+		}
+	}
+	data, err = os.ReadFile(cfg.HTMLFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`<a href="#p0">a</a>`, `<div class="line">a-out</div>`, `<a href="#p1">b</a>`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Expected %q in HTML report:\n%s", want, data)
+		}
+	}
+
+	// A report that cannot be created fails the run before anything starts.
+	cfg.JUnitFile = filepath.Join(t.TempDir(), "missing", "report.xml")
+	cfg.CommandFactory = func(_ context.Context, _ engine.ProcessSpec) (engine.Command, error) {
+		t.Error("Process started despite the report error")
+		return nil, errors.New("unexpected")
+	}
+	if code := runner.Run(context.Background(), cfg); code != 1 {
+		t.Errorf("Expected exit code 1, got %d", code)
+	}
+}
+
+// failingRenderer fails to write its output.
+type failingRenderer struct {
+	renderer.NopRenderer
+}
+
+func (failingRenderer) FinalState([]renderer.ProcessState) error {
+	return errors.New("disk full")
+}
+
+// TestRunOutputErrors verifies that a renderer or report file that cannot
+// write its output fails a run whose processes all succeeded.
+func TestRunOutputErrors(t *testing.T) {
+	newConfig := func() runner.Config {
+		cfg := runner.DefaultConfig()
+		cfg.Specs = []engine.ProcessSpec{{Name: "a", Command: "mock"}}
+		cfg.CommandFactory = func(_ context.Context, spec engine.ProcessSpec) (engine.Command, error) {
+			return NewMockCommand(spec).WithStdout("out"), nil
+		}
+		cfg.Renderers = []renderer.Renderer{&recordingRenderer{}}
+		return cfg
+	}
+
+	t.Run("renderer", func(t *testing.T) {
+		cfg := newConfig()
+		cfg.Renderers = append(cfg.Renderers, failingRenderer{})
+		if code := runner.Run(context.Background(), cfg); code != 1 {
+			t.Errorf("Expected exit code 1, got %d", code)
+		}
+	})
+
+	t.Run("report", func(t *testing.T) {
+		if _, err := os.Stat("/dev/full"); err != nil {
+			t.Skip("no /dev/full")
+		}
+		cfg := newConfig()
+		cfg.JUnitFile = "/dev/full"
+		if code := runner.Run(context.Background(), cfg); code != 1 {
+			t.Errorf("Expected exit code 1, got %d", code)
+		}
+	})
+}
+
+// TestNewPlan verifies the plan reports effective limits, the environment
+// diff and the restart policy without starting anything.
+func TestNewPlan(t *testing.T) {
+	t.Setenv("PLAN_KEPT", "same")
+	t.Setenv("PLAN_CHANGED", "old")
+
+	isTTY := false
+	cfg := runner.Config{IsTTY: &isTTY, ShutdownTimeout: 3 * time.Second}
+	cfg.Specs = []engine.ProcessSpec{
+		{Name: "api", Command: "go", Args: []string{"run", "./cmd/api"}, MaxBytes: 4096, Group: "backend",
+			Env: []string{"PLAN_NEW=1", "PLAN_KEPT=same", "PLAN_CHANGED=first", "PLAN_CHANGED=new"}},
+		{Name: "web", Command: "sh", Args: []string{"-c", "npm run dev"}, MaxLines: 50,
+			Watch: []string{"src/**"}, WatchIgnore: []string{"*.tmp"}, Schedule: "@hourly", ScheduleOverlap: engine.OverlapQueue},
+	}
+	cfg.CommandFactory = func(_ context.Context, _ engine.ProcessSpec) (engine.Command, error) {
+		t.Error("Process started by NewPlan")
+		return nil, errors.New("unexpected")
+	}
+
+	plan, err := runner.NewPlan(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Waves) != 1 || strings.Join(plan.Waves[0], ",") != "api,web" {
+		t.Errorf("Expected one wave with api and web, got %v", plan.Waves)
+	}
+	api, web := plan.Processes[0], plan.Processes[1]
+	if api.MaxLines != 1000 || api.MaxBytes != 4096 || web.MaxLines != 50 {
+		t.Errorf("Expected limits 1000/4096 and 50, got %d/%d and %d", api.MaxLines, api.MaxBytes, web.MaxLines)
+	}
+	if api.ShutdownTimeout != "3s" || api.Restart != runner.RestartNever {
+		t.Errorf("Expected 3s and %q, got %q and %q", runner.RestartNever, api.ShutdownTimeout, api.Restart)
+	}
+	if web.Restart != runner.RestartOnChange || web.WatchDebounce != "200ms" {
+		t.Errorf("Expected %q with the default debounce, got %q and %q", runner.RestartOnChange, web.Restart, web.WatchDebounce)
+	}
+	want := []runner.EnvChange{
+		{Name: "PLAN_CHANGED", Value: "new", Overrides: true, Previous: "old"},
+		{Name: "PLAN_NEW", Value: "1"},
+	}
+	if fmt.Sprint(api.Env) != fmt.Sprint(want) {
+		t.Errorf("Expected env %v, got %v", want, api.Env)
+	}
+
+	var b strings.Builder
+	if err = plan.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"Plan: 2 processes in 1 wave, nothing started",
+		"Wave 1: api, web",
+		`  command:  sh -c 'npm run dev'`,
+		`  argv:     "sh" "-c" "npm run dev"`,
+		"  env:      PLAN_CHANGED=new (was old)",
+		"            PLAN_NEW=1",
+		"  env:      (inherited)",
+		"  limits:   1000 lines, 4096 bytes",
+		"  shutdown: SIGTERM, then SIGKILL after 3s",
+		"  group:    backend",
+		"  watch:    src/** (ignoring *.tmp), debounce 200ms",
+		"  schedule: @hourly, queue overlapping runs",
+	} {
+		if !strings.Contains(b.String(), line+"\n") {
+			t.Errorf("Expected line %q in plan:\n%s", line, b.String())
+		}
+	}
+}
+
+// TestNewPlanWaves verifies processes are layered into waves by their
+// dependencies, and that a dependency cycle is an error.
+func TestNewPlanWaves(t *testing.T) {
+	cfg := runner.Config{}
+	cfg.Specs = []engine.ProcessSpec{
+		{Name: "deploy", Command: "sh", DependsOn: []string{"test", "lint"}},
+		{Name: "test", Command: "sh", DependsOn: []string{"build"}},
+		{Name: "build", Command: "sh", DependsOn: []string{"gen", "skipped"}},
+		{Name: "lint", Command: "sh"},
+		{Name: "gen", Command: "sh"},
+		{Name: "docs", Command: "sh", DependsOn: []string{"gen"}},
+	}
+
+	plan, err := runner.NewPlan(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"lint", "gen"}, {"build", "docs"}, {"test"}, {"deploy"}}
+	if fmt.Sprint(plan.Waves) != fmt.Sprint(want) {
+		t.Errorf("Expected waves %v, got %v", want, plan.Waves)
+	}
+	for _, proc := range plan.Processes {
+		if !slices.Contains(plan.Waves[proc.Wave], proc.Name) {
+			t.Errorf("%s: Wave %d does not list it (%v)", proc.Name, proc.Wave, plan.Waves)
+		}
+	}
+
+	var b strings.Builder
+	if err = plan.WriteText(&b); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"Plan: 6 processes in 4 waves, nothing started",
+		"Wave 1: lint, gen",
+		"Wave 2: build, docs (after gen)",
+		"Wave 4: deploy (after lint, test)",
+		"  after:    test, lint",
+	} {
+		if !strings.Contains(b.String(), line+"\n") {
+			t.Errorf("Expected line %q in plan:\n%s", line, b.String())
+		}
+	}
+
+	cfg.Specs[4].DependsOn = []string{"deploy"}
+	if _, err = runner.NewPlan(cfg); err == nil || !strings.Contains(err.Error(), "dependency cycle among deploy, test, build, gen, docs") {
+		t.Errorf("Expected a dependency cycle error, got %v", err)
+	}
+}
+
+// TestConfigValidate verifies spec and prefix problems are reported, and
+// that Run starts nothing when there are any.
+func TestConfigValidate(t *testing.T) {
+	cfg := runner.DefaultConfig()
+	cfg.Specs = []engine.ProcessSpec{{Name: "ok", Command: "sh"}}
+	for _, prefix := range []string{"", "[%s]", "%s:", "%% %s"} {
+		cfg.LogPrefix = prefix
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("Prefix %q: unexpected error %v", prefix, err)
+		}
+	}
+	for _, prefix := range []string{"[%d]", "[name]", "%s %s"} {
+		cfg.LogPrefix = prefix
+		if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "invalid log prefix") {
+			t.Errorf("Prefix %q: expected an invalid prefix error, got %v", prefix, err)
+		}
+	}
+
+	isTTY := false
+	cfg = runner.Config{IsTTY: &isTTY, ShowSummary: false}
+	cfg.Specs = []engine.ProcessSpec{{Name: "a", Command: "mock"}, {Name: "a", Command: "mock"}}
+	cfg.CommandFactory = func(_ context.Context, _ engine.ProcessSpec) (engine.Command, error) {
+		t.Error("Process started despite an invalid configuration")
+		return nil, errors.New("unexpected")
+	}
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), `process "a": duplicate name`) {
+		t.Errorf("Expected a duplicate name error, got %v", err)
+	}
+	if code := runner.Run(context.Background(), cfg); code != 1 {
+		t.Errorf("Expected exit code 1, got %d", code)
+	}
 }
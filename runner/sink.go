@@ -0,0 +1,169 @@
+package runner
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/a2y-d5l/multiproc/engine"
+	"github.com/a2y-d5l/multiproc/renderer"
+)
+
+// sink delivers events to a single renderer on its own goroutine.
+//
+// Each sink owns:
+//   - An unbounded event queue, so pushing never blocks the event loop
+//   - A private copy of the process states, so renderers never share state
+//     (e.g., one renderer clearing Dirty flags does not hide updates from another)
+//
+// If the renderer panics, the sink calls restore (which restores the
+// terminal for every renderer) before the panic crashes the program.
+//
+// This isolates renderers from each other: a slow sink (a log file on NFS)
+// accumulates a backlog in its queue while the interactive view keeps up.
+// The queue is deliberately not capped: dropping events would silently
+// truncate log files and reports, so a renderer that never catches up
+// costs memory instead (see Config.Renderers).
+//
+// Lifecycle:
+//  1. newSink copies the initial states
+//  2. run calls Start, then delivers queued events in batches, ticking after
+//     each batch and every tick interval (so clocks advance without events)
+//  3. close marks the queue closed; run drains it and calls Finish
+//  4. wait blocks until run has returned
+//
+// Ticks are rate-limited to one per frame interval. Batches that arrive
+// sooner are applied right away, but their Tick is deferred to the end of
+// the frame, so a flood of output redraws the screen at most once per
+// frame. Finish always follows the last batch, so the final frame is drawn
+// regardless of the limit.
+type sink struct {
+	r        renderer.Renderer
+	restore  func()
+	specs    []engine.ProcessSpec
+	states   []renderer.ProcessState
+	queue    []renderer.Event
+	notify   chan struct{}
+	done     chan struct{}
+	mu       sync.Mutex
+	interval time.Duration
+	frame    time.Duration
+	closed   bool
+}
+
+// newSink creates a sink for r with a private copy of states.
+// restore is called if r panics. If interval is positive, r is also
+// ticked periodically. If frame is positive, r is ticked at most once per
+// frame.
+func newSink(r renderer.Renderer, specs []engine.ProcessSpec, states []renderer.ProcessState, restore func(), interval, frame time.Duration) *sink {
+	own := make([]renderer.ProcessState, len(states))
+	for i, ps := range states {
+		own[i] = ps
+		own[i].Lines = slices.Clone(ps.Lines)
+	}
+	return &sink{
+		r:        r,
+		restore:  restore,
+		specs:    specs,
+		states:   own,
+		notify:   make(chan struct{}, 1),
+		done:     make(chan struct{}),
+		interval: interval,
+		frame:    frame,
+	}
+}
+
+// push queues ev for delivery. It never blocks.
+func (s *sink) push(ev renderer.Event) {
+	s.mu.Lock()
+	s.queue = append(s.queue, ev)
+	s.mu.Unlock()
+	s.wake()
+}
+
+// close marks the end of the event stream.
+func (s *sink) close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+	s.wake()
+}
+
+// wait blocks until the sink has delivered every event and called Finish.
+func (s *sink) wait() {
+	<-s.done
+}
+
+// wake signals run that the queue changed, without blocking.
+func (s *sink) wake() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// run is the sink's delivery loop. It must be started exactly once.
+func (s *sink) run() {
+	defer close(s.done)
+	defer restoreOnPanic(s.restore)
+
+	s.r.Start(s.specs, s.states)
+
+	var ticks <-chan time.Time
+	if s.interval > 0 {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	// deferred fires when the Tick of a batch that arrived within the
+	// frame of the previous Tick is due.
+	deferred := time.NewTimer(time.Hour)
+	deferred.Stop()
+	defer deferred.Stop()
+	pending := false
+	var last time.Time
+
+	var batch []renderer.Event
+	for {
+		select {
+		case <-s.notify:
+		case <-ticks:
+		case <-deferred.C:
+			pending = false
+		}
+
+		s.mu.Lock()
+		batch, s.queue = s.queue, batch[:0]
+		closed := s.closed
+		s.mu.Unlock()
+
+		for _, ev := range batch {
+			s.states = renderer.GrowStates(s.states, ev)
+			renderer.ApplyEvent(s.states, ev)
+			s.r.Event(ev, s.states)
+		}
+		clear(batch)
+
+		if closed {
+			break
+		}
+		// Tick once per batch, so bursts of events are coalesced, and at
+		// most once per frame.
+		if wait := s.frame - time.Since(last); s.frame > 0 && wait > 0 {
+			if !pending {
+				deferred.Reset(wait)
+				pending = true
+			}
+			continue
+		}
+		if pending {
+			deferred.Stop()
+			pending = false
+		}
+		s.r.Tick(s.states)
+		last = time.Now()
+	}
+
+	s.r.Finish(s.states)
+}